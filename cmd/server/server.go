@@ -2,10 +2,17 @@ package main
 
 import (
 	"context"
+	"crypto/rsa"
 	"flag"
 	"fmt"
+	"graphql_project/internal/auth"
+	"graphql_project/internal/broker"
 	"graphql_project/internal/config"
 	"graphql_project/internal/graph"
+	"graphql_project/internal/graph/loaders"
+	"graphql_project/internal/graph/model"
+	"graphql_project/internal/pubsub"
+	"graphql_project/internal/ratelimit"
 	"graphql_project/internal/service"
 	"graphql_project/internal/storage"
 	"graphql_project/migrations"
@@ -42,17 +49,21 @@ func main() {
 	flag.Parse()
 
 	// Инициализация хранилища
+	opts := []storage.Option{
+		storage.WithAutoLock(time.Duration(cfg.CommentsAutoLockDays) * 24 * time.Hour),
+	}
+
+	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName)
+
 	var store storage.Storage
 	switch storageType {
 	case "inmem":
-		store = storage.NewInMemStorage()
+		store = storage.NewInMemStorage(opts...)
 		log.Println("Using in-memory storage")
 
 	case "postgres":
-		dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-			cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName)
-
-		store, err = storage.NewPostgresStorage(dsn)
+		store, err = storage.NewPostgresStorage(dsn, opts...)
 		if err != nil {
 			log.Fatalf("Failed to connect to PostgreSQL: %v", err)
 		}
@@ -66,29 +77,91 @@ func main() {
 		log.Fatalf("Unknown storage type: %s", storageType)
 	}
 
+	// Фильтры содержимого
+	var filters []service.ContentFilter
+	if cfg.ContentBlocklistFile != "" {
+		blocklist, err := service.NewBlocklistFilter(cfg.ContentBlocklistFile, model.FilterAction(cfg.ContentBlocklistAction), cfg.ContentBlocklistReload)
+		if err != nil {
+			log.Fatalf("Failed to load blocklist: %v", err)
+		}
+		filters = append(filters, blocklist)
+	}
+	if cfg.ContentMaxLinks > 0 {
+		filters = append(filters, service.NewLinkLimitFilter(cfg.ContentMaxLinks))
+	}
+	if cfg.ContentRepeatWindow > 0 {
+		filters = append(filters, service.NewRepeatFilter(cfg.ContentRepeatWindow))
+	}
+
+	// Рассылка событий подписок
+	slowConsumer, err := broker.ParsePolicy(cfg.SubscriptionSlowConsumer)
+	if err != nil {
+		log.Fatalf("Invalid SUBSCRIPTION_SLOW_CONSUMER: %v", err)
+	}
+	queue := pubsub.WithQueue(cfg.SubscriptionBuffer, slowConsumer)
+
+	var events pubsub.PubSub
+	switch cfg.PubSubType {
+	case "inmem":
+		events = pubsub.NewInMem(queue)
+
+	case "postgres":
+		events, err = pubsub.NewPostgres(dsn, queue)
+		if err != nil {
+			log.Fatalf("Failed to connect PubSub to PostgreSQL: %v", err)
+		}
+		log.Println("Subscriptions use PostgreSQL LISTEN/NOTIFY")
+
+	default:
+		log.Fatalf("Unknown PubSub type: %s", cfg.PubSubType)
+	}
+
 	// Инициализация сервиса
-	svc := service.NewService(store)
+	svc := service.NewService(store, service.WithContentFilters(filters...), service.WithPubSub(events))
+
+	// Проверка JWT-токенов
+	var publicKey *rsa.PublicKey
+	if len(cfg.JWTPublicKey) > 0 {
+		if publicKey, err = auth.ParseRSAPublicKey(cfg.JWTPublicKey); err != nil {
+			log.Fatalf("Invalid JWT public key: %v", err)
+		}
+	}
+	if cfg.JWTSecret == "" && publicKey == nil {
+		log.Println("JWT keys are not configured: all requests are anonymous")
+	}
+	authenticator := auth.NewAuthenticator([]byte(cfg.JWTSecret), publicKey)
 
 	//Создание GraphQL резольвера
 	resolver := graph.NewResolver(svc)
 
 	// Настройки GraphQL сервера
-	srv := handler.New(graph.NewExecutableSchema(graph.Config{Resolvers: resolver}))
+	srv := handler.New(graph.NewExecutableSchema(graph.Config{
+		Resolvers:  resolver,
+		Directives: graph.Directives(),
+	}))
 
-	srv.AddTransport(&transport.Websocket{})
+	srv.AddTransport(&transport.Websocket{
+		InitFunc: auth.WebsocketInit(authenticator),
+	})
 	srv.AddTransport(transport.Options{})
 	srv.AddTransport(transport.GET{})
 	srv.AddTransport(transport.POST{})
 
 	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+	srv.SetErrorPresenter(graph.ErrorPresenter)
 
 	srv.Use(extension.Introspection{})
 	srv.Use(extension.AutomaticPersistedQuery{
 		Cache: lru.New[string](100),
 	})
+	srv.Use(ratelimit.New(map[string]int{
+		"createPost":    cfg.RateLimitPostsPerMinute,
+		"createComment": cfg.RateLimitCommentsPerMinute,
+		"createUser":    cfg.RateLimitUsersPerMinute,
+	}))
 
 	http.Handle("/", playground.Handler("GraphQL playground", "/query"))
-	http.Handle("/query", srv)
+	http.Handle("/query", ratelimit.Middleware(auth.Middleware(authenticator, loaders.Middleware(svc, srv))))
 
 	server := &http.Server{
 		Addr: ":" + cfg.HTTPPort,
@@ -122,7 +195,12 @@ func main() {
 			}
 		}
 
-		// Закрытие соединения с хранилищем данных
+		// Закрытие соединений с хранилищем данных и PubSub
+		if closer, ok := events.(interface{ Close() error }); ok {
+			if err := closer.Close(); err != nil {
+				log.Printf("PubSub close error: %v", err)
+			}
+		}
 		if closer, ok := store.(interface{ Close() error }); ok {
 			if err := closer.Close(); err != nil {
 				log.Printf("Storage close error: %v", err)
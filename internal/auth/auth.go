@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnauthenticated = errors.New("unauthenticated")
+	ErrInvalidToken    = errors.New("invalid token")
+)
+
+type ctxKey string
+
+const principalKey ctxKey = "principal"
+
+// Role — роль пользователя из claim role токена.
+type Role string
+
+const (
+	RoleUser      Role = "USER"
+	RoleModerator Role = "MODERATOR"
+	RoleAdmin     Role = "ADMIN"
+)
+
+// Principal — пользователь, от имени которого выполняется запрос. UserID берётся из
+// claim sub токена, Role — из claim role (по умолчанию RoleUser).
+type Principal struct {
+	UserID uuid.UUID
+	Role   Role
+}
+
+// roleRank упорядочивает роли: каждая следующая включает права предыдущих.
+var roleRank = map[Role]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// IsModerator сообщает, может ли пользователь изменять чужой контент и модерировать.
+func (p Principal) IsModerator() bool {
+	return p.HasRole(RoleModerator)
+}
+
+// HasRole сообщает, что роль пользователя не ниже role.
+func (p Principal) HasRole(role Role) bool {
+	return roleRank[p.Role] >= roleRank[role]
+}
+
+type claims struct {
+	jwt.RegisteredClaims
+	Role Role `json:"role,omitempty"`
+}
+
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey, p)
+}
+
+// FromContext возвращает пользователя запроса; ok равен false для анонимного запроса.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey).(Principal)
+	return p, ok
+}
+
+// Require возвращает пользователя запроса или ErrUnauthenticated.
+func Require(ctx context.Context) (Principal, error) {
+	p, ok := FromContext(ctx)
+	if !ok {
+		return Principal{}, ErrUnauthenticated
+	}
+	return p, nil
+}
+
+// Authenticator проверяет bearer-токены JWT, подписанные HS256 общим секретом или
+// RS256 закрытым ключом, открытая часть которого известна серверу.
+type Authenticator struct {
+	secret    []byte
+	publicKey *rsa.PublicKey
+}
+
+// NewAuthenticator создаёт проверку токенов; пустой secret или nil publicKey отключают
+// соответствующий алгоритм.
+func NewAuthenticator(secret []byte, publicKey *rsa.PublicKey) *Authenticator {
+	return &Authenticator{secret: secret, publicKey: publicKey}
+}
+
+// ParseRSAPublicKey разбирает открытый ключ RS256 в формате PEM.
+func ParseRSAPublicKey(pem []byte) (*rsa.PublicKey, error) {
+	return jwt.ParseRSAPublicKeyFromPEM(pem)
+}
+
+// Authenticate проверяет подпись и срок действия токена и возвращает его владельца.
+func (a *Authenticator) Authenticate(token string) (Principal, error) {
+	methods := a.methods()
+	if len(methods) == 0 {
+		return Principal{}, ErrInvalidToken
+	}
+
+	var claims claims
+	_, err := jwt.ParseWithClaims(token, &claims, a.key,
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return Principal{}, ErrInvalidToken
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return Principal{}, ErrInvalidToken
+	}
+	if claims.Role == "" {
+		claims.Role = RoleUser
+	}
+	if _, ok := roleRank[claims.Role]; !ok {
+		return Principal{}, ErrInvalidToken
+	}
+	return Principal{UserID: userID, Role: claims.Role}, nil
+}
+
+func (a *Authenticator) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		return a.secret, nil
+	case *jwt.SigningMethodRSA:
+		return a.publicKey, nil
+	}
+	return nil, ErrInvalidToken
+}
+
+func (a *Authenticator) methods() []string {
+	var methods []string
+	if len(a.secret) > 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if a.publicKey != nil {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	return methods
+}
+
+// Middleware кладёт в контекст пользователя из заголовка Authorization. Запрос без
+// заголовка проходит анонимным, с недействительным токеном — отклоняется с 401.
+func Middleware(a *Authenticator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, err := a.withToken(r.Context(), header)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// WebsocketInit аутентифицирует websocket-соединение по полю Authorization из payload
+// сообщения connection_init: браузеры не позволяют передать заголовок при установке
+// соединения. Пользователь сохраняется в контексте всех подписок соединения.
+func WebsocketInit(a *Authenticator) transport.WebsocketInitFunc {
+	return func(ctx context.Context, payload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+		header := payload.Authorization()
+		if header == "" {
+			return ctx, nil, nil
+		}
+
+		ctx, err := a.withToken(ctx, header)
+		if err != nil {
+			return nil, nil, err
+		}
+		return ctx, nil, nil
+	}
+}
+
+func (a *Authenticator) withToken(ctx context.Context, header string) (context.Context, error) {
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	p, err := a.Authenticate(strings.TrimSpace(token))
+	if err != nil {
+		return nil, err
+	}
+	return WithPrincipal(ctx, p), nil
+}
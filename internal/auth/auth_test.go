@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var secret = []byte("test-secret")
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, sub string, ttl time.Duration) string {
+	return signRole(t, method, key, sub, ttl, "")
+}
+
+func signRole(t *testing.T, method jwt.SigningMethod, key interface{}, sub string, ttl time.Duration, role Role) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   sub,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		},
+		Role: role,
+	}).SignedString(key)
+	require.NoError(t, err)
+	return token
+}
+
+func TestAuthenticator(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	userID := uuid.New()
+
+	t.Run("HS256", func(t *testing.T) {
+		a := NewAuthenticator(secret, nil)
+
+		p, err := a.Authenticate(sign(t, jwt.SigningMethodHS256, secret, userID.String(), time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, userID, p.UserID)
+
+		_, err = a.Authenticate(sign(t, jwt.SigningMethodHS256, []byte("other"), userID.String(), time.Minute))
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("RS256", func(t *testing.T) {
+		a := NewAuthenticator(nil, &rsaKey.PublicKey)
+
+		p, err := a.Authenticate(sign(t, jwt.SigningMethodRS256, rsaKey, userID.String(), time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, userID, p.UserID)
+
+		// Алгоритм, не включённый в конфигурации, не принимается.
+		_, err = a.Authenticate(sign(t, jwt.SigningMethodHS256, secret, userID.String(), time.Minute))
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("invalid claims", func(t *testing.T) {
+		a := NewAuthenticator(secret, nil)
+
+		_, err := a.Authenticate(sign(t, jwt.SigningMethodHS256, secret, userID.String(), -time.Minute))
+		assert.ErrorIs(t, err, ErrInvalidToken, "expired")
+		_, err = a.Authenticate(sign(t, jwt.SigningMethodHS256, secret, "alice", time.Minute))
+		assert.ErrorIs(t, err, ErrInvalidToken, "subject is not a user ID")
+	})
+
+	t.Run("role", func(t *testing.T) {
+		a := NewAuthenticator(secret, nil)
+
+		p, err := a.Authenticate(sign(t, jwt.SigningMethodHS256, secret, userID.String(), time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, RoleUser, p.Role)
+		assert.False(t, p.IsModerator())
+
+		p, err = a.Authenticate(signRole(t, jwt.SigningMethodHS256, secret, userID.String(), time.Minute, RoleModerator))
+		require.NoError(t, err)
+		assert.True(t, p.IsModerator())
+		assert.True(t, p.HasRole(RoleUser), "moderator has user rights")
+		assert.False(t, p.HasRole(RoleAdmin))
+
+		p, err = a.Authenticate(signRole(t, jwt.SigningMethodHS256, secret, userID.String(), time.Minute, RoleAdmin))
+		require.NoError(t, err)
+		assert.True(t, p.IsModerator(), "admin has moderator rights")
+
+		_, err = a.Authenticate(signRole(t, jwt.SigningMethodHS256, secret, userID.String(), time.Minute, "ROOT"))
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("no keys", func(t *testing.T) {
+		a := NewAuthenticator(nil, nil)
+
+		_, err := a.Authenticate(sign(t, jwt.SigningMethodHS256, secret, userID.String(), time.Minute))
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	a := NewAuthenticator(secret, nil)
+	userID := uuid.New()
+
+	var got *Principal
+	handler := Middleware(a, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = nil
+		if p, ok := FromContext(r.Context()); ok {
+			got = &p
+		}
+	}))
+	serve := func(header string) int {
+		req := httptest.NewRequest(http.MethodPost, "/query", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve(""))
+	assert.Nil(t, got, "request without token is anonymous")
+
+	assert.Equal(t, http.StatusOK, serve("Bearer "+sign(t, jwt.SigningMethodHS256, secret, userID.String(), time.Minute)))
+	require.NotNil(t, got)
+	assert.Equal(t, userID, got.UserID)
+
+	assert.Equal(t, http.StatusUnauthorized, serve("Bearer garbage"))
+	assert.Equal(t, http.StatusUnauthorized, serve(sign(t, jwt.SigningMethodHS256, secret, userID.String(), time.Minute)))
+}
+
+func TestWebsocketInit(t *testing.T) {
+	initFunc := WebsocketInit(NewAuthenticator(secret, nil))
+	userID := uuid.New()
+
+	ctx, _, err := initFunc(context.Background(), transport.InitPayload{
+		"Authorization": "Bearer " + sign(t, jwt.SigningMethodHS256, secret, userID.String(), time.Minute),
+	})
+	require.NoError(t, err)
+	p, ok := FromContext(ctx)
+	require.True(t, ok)
+	assert.Equal(t, userID, p.UserID)
+
+	ctx, _, err = initFunc(context.Background(), transport.InitPayload{})
+	require.NoError(t, err)
+	_, ok = FromContext(ctx)
+	assert.False(t, ok)
+
+	_, _, err = initFunc(context.Background(), transport.InitPayload{"authorization": "Bearer garbage"})
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
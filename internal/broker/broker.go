@@ -0,0 +1,116 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Policy — что делать, когда очередь подписчика заполнена.
+type Policy string
+
+const (
+	// Drop теряет сообщение, не поместившееся в очередь; подписчик остаётся подключённым.
+	Drop Policy = "drop"
+	// Disconnect отключает подписчика: его канал закрывается, и подписка завершается.
+	Disconnect Policy = "disconnect"
+)
+
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(s); p {
+	case Drop, Disconnect:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown slow consumer policy %q", s)
+	}
+}
+
+// Broker рассылает сообщения подписчикам. У каждого подписчика своя очередь из buffer
+// сообщений, поэтому Publish никогда не ждёт медленного подписчика: при переполнении
+// очереди срабатывает policy.
+type Broker[T any] struct {
+	buffer  int
+	policy  Policy
+	dropped atomic.Uint64
+
+	mu   sync.Mutex
+	subs map[*subscriber[T]]struct{}
+}
+
+type subscriber[T any] struct {
+	ch   chan T
+	done chan struct{}
+}
+
+func New[T any](buffer int, policy Policy) *Broker[T] {
+	return &Broker[T]{
+		buffer: buffer,
+		policy: policy,
+		subs:   make(map[*subscriber[T]]struct{}),
+	}
+}
+
+// Subscribe возвращает канал сообщений. Подписка снимается, а канал закрывается, когда
+// завершается ctx или подписчик отключён за медленное чтение.
+func (b *Broker[T]) Subscribe(ctx context.Context) <-chan T {
+	sub := &subscriber[T]{
+		ch:   make(chan T, b.buffer),
+		done: make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			b.mu.Lock()
+			b.remove(sub)
+			b.mu.Unlock()
+		case <-sub.done:
+		}
+	}()
+	return sub.ch
+}
+
+// Publish кладёт msg в очередь каждого подписчика.
+func (b *Broker[T]) Publish(msg T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		select {
+		case sub.ch <- msg:
+			continue
+		default:
+		}
+		b.dropped.Add(1)
+		if b.policy == Disconnect {
+			b.remove(sub)
+		}
+	}
+}
+
+// Subscribers возвращает число подключённых подписчиков.
+func (b *Broker[T]) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// Dropped возвращает, сколько сообщений не было доставлено из-за переполненных очередей.
+func (b *Broker[T]) Dropped() uint64 {
+	return b.dropped.Load()
+}
+
+// remove отключает подписчика, если он ещё подключён; вызывающий должен удерживать b.mu.
+func (b *Broker[T]) remove(sub *subscriber[T]) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+	delete(b.subs, sub)
+	close(sub.ch)
+	close(sub.done)
+}
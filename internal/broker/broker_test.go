@@ -0,0 +1,125 @@
+package broker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBroker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	t.Run("every subscriber receives messages in order", func(t *testing.T) {
+		b := New[int](4, Drop)
+		first, second := b.Subscribe(ctx), b.Subscribe(ctx)
+		b.Publish(1)
+		b.Publish(2)
+		for _, ch := range []<-chan int{first, second} {
+			assert.Equal(t, 1, <-ch)
+			assert.Equal(t, 2, <-ch)
+		}
+	})
+
+	t.Run("drop keeps slow subscriber", func(t *testing.T) {
+		b := New[int](2, Drop)
+		slow, fast := b.Subscribe(ctx), b.Subscribe(ctx)
+		for i := 1; i <= 3; i++ {
+			b.Publish(i)
+			assert.Equal(t, i, <-fast)
+		}
+		assert.Equal(t, 1, <-slow)
+		assert.Equal(t, 2, <-slow)
+		assert.Equal(t, uint64(1), b.Dropped())
+		assert.Equal(t, 2, b.Subscribers())
+
+		b.Publish(4)
+		assert.Equal(t, 4, <-slow, "subscriber receives messages again once the queue has room")
+	})
+
+	t.Run("disconnect closes slow subscriber", func(t *testing.T) {
+		b := New[int](1, Disconnect)
+		slow, fast := b.Subscribe(ctx), b.Subscribe(ctx)
+		b.Publish(1)
+		assert.Equal(t, 1, <-fast)
+		b.Publish(2)
+		assert.Equal(t, 2, <-fast)
+
+		assert.Equal(t, 1, <-slow, "queued messages are still delivered")
+		_, ok := <-slow
+		assert.False(t, ok)
+		assert.Equal(t, 1, b.Subscribers())
+	})
+
+	t.Run("cancel unsubscribes", func(t *testing.T) {
+		b := New[int](1, Drop)
+		subCtx, subCancel := context.WithCancel(ctx)
+		ch := b.Subscribe(subCtx)
+		subCancel()
+
+		select {
+		case _, ok := <-ch:
+			assert.False(t, ok)
+		case <-time.After(time.Second):
+			t.Fatal("channel is not closed after cancel")
+		}
+		assert.Equal(t, 0, b.Subscribers())
+	})
+}
+
+// TestBrokerConcurrent публикует сообщения, пока подписчики подключаются, читают и
+// отключаются; запускается с -race.
+func TestBrokerConcurrent(t *testing.T) {
+	for _, policy := range []Policy{Drop, Disconnect} {
+		t.Run(string(policy), func(t *testing.T) {
+			b := New[int](4, policy)
+			var wg sync.WaitGroup
+
+			stop := make(chan struct{})
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; ; i++ {
+					select {
+					case <-stop:
+						return
+					default:
+						b.Publish(i)
+					}
+				}
+			}()
+
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+					defer cancel()
+					last := -1
+					for msg := range b.Subscribe(ctx) {
+						assert.Greater(t, msg, last, "messages arrive in publishing order")
+						last = msg
+					}
+				}()
+			}
+
+			time.Sleep(50 * time.Millisecond)
+			close(stop)
+			wg.Wait()
+			require.Eventually(t, func() bool { return b.Subscribers() == 0 }, time.Second, time.Millisecond)
+		})
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy("disconnect")
+	require.NoError(t, err)
+	assert.Equal(t, Disconnect, policy)
+
+	_, err = ParsePolicy("block")
+	assert.Error(t, err)
+}
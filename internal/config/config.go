@@ -1,8 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type Config struct {
@@ -13,9 +16,83 @@ type Config struct {
 	DBUser      string
 	DBPassword  string
 	DBName      string
+	// CommentsAutoLockDays — через сколько дней после публикации пост закрывается
+	// для комментариев; 0 отключает автозакрытие.
+	CommentsAutoLockDays int
+	// JWTSecret — общий секрет для токенов HS256, JWTPublicKey — открытый ключ RS256 в
+	// формате PEM. Пустое значение отключает соответствующий алгоритм.
+	JWTSecret    string
+	JWTPublicKey []byte
+	// ContentBlocklistFile — файл стоп-списка слов (пустое значение отключает фильтр),
+	// ContentBlocklistAction — что делать с найденными словами: FLAG, REWRITE или REJECT.
+	// Файл перечитывается при изменении не чаще раза в ContentBlocklistReload.
+	ContentBlocklistFile   string
+	ContentBlocklistAction string
+	ContentBlocklistReload time.Duration
+	// ContentMaxLinks — сколько ссылок допускается в посте или комментарии; 0 снимает ограничение.
+	ContentMaxLinks int
+	// ContentRepeatWindow — в течение какого срока повтор текста тем же автором отмечается
+	// для модераторов; 0 отключает проверку.
+	ContentRepeatWindow time.Duration
+	// RateLimitPostsPerMinute и RateLimitCommentsPerMinute ограничивают частоту createPost и
+	// createComment для каждого автора и каждого IP-адреса, RateLimitUsersPerMinute —
+	// частоту регистраций createUser с одного IP-адреса; 0 снимает ограничение.
+	RateLimitPostsPerMinute    int
+	RateLimitCommentsPerMinute int
+	RateLimitUsersPerMinute    int
+	// PubSubType — через что рассылаются события подписок: inmem (в пределах процесса) или
+	// postgres (LISTEN/NOTIFY, события видны всем экземплярам сервера).
+	PubSubType string
+	// SubscriptionBuffer — сколько событий ждут каждого подписчика; SubscriptionSlowConsumer —
+	// что делать, когда очередь заполнена: drop (терять события) или disconnect (завершать подписку).
+	SubscriptionBuffer       int
+	SubscriptionSlowConsumer string
 }
 
 func LoadConfig() (*Config, error) {
+	autoLockDays, err := strconv.Atoi(getEnv("COMMENTS_AUTO_LOCK_DAYS", "0"))
+	if err != nil || autoLockDays < 0 {
+		return nil, fmt.Errorf("invalid COMMENTS_AUTO_LOCK_DAYS: %q", os.Getenv("COMMENTS_AUTO_LOCK_DAYS"))
+	}
+
+	var publicKey []byte
+	if path := getEnv("JWT_PUBLIC_KEY_FILE", ""); path != "" {
+		if publicKey, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("reading JWT_PUBLIC_KEY_FILE: %w", err)
+		}
+	}
+
+	maxLinks, err := strconv.Atoi(getEnv("CONTENT_MAX_LINKS", "0"))
+	if err != nil || maxLinks < 0 {
+		return nil, fmt.Errorf("invalid CONTENT_MAX_LINKS: %q", os.Getenv("CONTENT_MAX_LINKS"))
+	}
+	blocklistReload, err := time.ParseDuration(getEnv("CONTENT_BLOCKLIST_RELOAD", "30s"))
+	if err != nil || blocklistReload < 0 {
+		return nil, fmt.Errorf("invalid CONTENT_BLOCKLIST_RELOAD: %q", os.Getenv("CONTENT_BLOCKLIST_RELOAD"))
+	}
+	repeatWindow, err := time.ParseDuration(getEnv("CONTENT_REPEAT_WINDOW", "0"))
+	if err != nil || repeatWindow < 0 {
+		return nil, fmt.Errorf("invalid CONTENT_REPEAT_WINDOW: %q", os.Getenv("CONTENT_REPEAT_WINDOW"))
+	}
+
+	postsPerMinute, err := strconv.Atoi(getEnv("RATE_LIMIT_POSTS_PER_MINUTE", "10"))
+	if err != nil || postsPerMinute < 0 {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_POSTS_PER_MINUTE: %q", os.Getenv("RATE_LIMIT_POSTS_PER_MINUTE"))
+	}
+	commentsPerMinute, err := strconv.Atoi(getEnv("RATE_LIMIT_COMMENTS_PER_MINUTE", "30"))
+	if err != nil || commentsPerMinute < 0 {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_COMMENTS_PER_MINUTE: %q", os.Getenv("RATE_LIMIT_COMMENTS_PER_MINUTE"))
+	}
+	usersPerMinute, err := strconv.Atoi(getEnv("RATE_LIMIT_USERS_PER_MINUTE", "5"))
+	if err != nil || usersPerMinute < 0 {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_USERS_PER_MINUTE: %q", os.Getenv("RATE_LIMIT_USERS_PER_MINUTE"))
+	}
+
+	subscriptionBuffer, err := strconv.Atoi(getEnv("SUBSCRIPTION_BUFFER", "16"))
+	if err != nil || subscriptionBuffer < 1 {
+		return nil, fmt.Errorf("invalid SUBSCRIPTION_BUFFER: %q", os.Getenv("SUBSCRIPTION_BUFFER"))
+	}
+
 	return &Config{
 		HTTPPort:    getEnv("HTTP_PORT", "8080"),
 		StorageType: strings.ToLower(getEnv("STORAGE_TYPE", "inmem")),
@@ -24,6 +101,24 @@ func LoadConfig() (*Config, error) {
 		DBUser:      getEnv("DB_USER", "postgres"),
 		DBPassword:  getEnv("DB_PASSWORD", "postgres"),
 		DBName:      getEnv("DB_NAME", "links"),
+
+		CommentsAutoLockDays: autoLockDays,
+		JWTSecret:            getEnv("JWT_SECRET", ""),
+		JWTPublicKey:         publicKey,
+
+		ContentBlocklistFile:   getEnv("CONTENT_BLOCKLIST_FILE", ""),
+		ContentBlocklistAction: strings.ToUpper(getEnv("CONTENT_BLOCKLIST_ACTION", "REWRITE")),
+		ContentBlocklistReload: blocklistReload,
+		ContentMaxLinks:        maxLinks,
+		ContentRepeatWindow:    repeatWindow,
+
+		RateLimitPostsPerMinute:    postsPerMinute,
+		RateLimitCommentsPerMinute: commentsPerMinute,
+		RateLimitUsersPerMinute:    usersPerMinute,
+
+		PubSubType:               strings.ToLower(getEnv("PUBSUB_TYPE", "inmem")),
+		SubscriptionBuffer:       subscriptionBuffer,
+		SubscriptionSlowConsumer: strings.ToLower(getEnv("SUBSCRIPTION_SLOW_CONSUMER", "drop")),
 	}, nil
 }
 
@@ -0,0 +1,89 @@
+package graph
+
+import (
+	"context"
+	"graphql_project/internal/auth"
+	"graphql_project/internal/graph/model"
+	"graphql_project/internal/service"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// Directives — реализации директив из schema.graphqls. gqlgen вызывает их до
+// резольвера поля, поэтому резольвер выполняется только для разрешённых запросов
+// с корректными входными данными.
+func Directives() DirectiveRoot {
+	return DirectiveRoot{
+		Auth:       Auth,
+		HasRole:    HasRole,
+		Constraint: Constraint,
+	}
+}
+
+// Auth реализует @auth: поле доступно только аутентифицированному пользователю.
+func Auth(ctx context.Context, obj any, next graphql.Resolver) (any, error) {
+	if _, err := auth.Require(ctx); err != nil {
+		return nil, err
+	}
+	return next(ctx)
+}
+
+// HasRole реализует @hasRole: поле доступно пользователю с ролью не ниже role.
+func HasRole(ctx context.Context, obj any, next graphql.Resolver, role model.Role) (any, error) {
+	principal, err := auth.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !principal.HasRole(auth.Role(role)) {
+		return nil, service.ErrForbidden
+	}
+	return next(ctx)
+}
+
+// Constraint реализует @constraint: проверяет строковое поле ввода до вызова резольвера.
+// Нарушение возвращается как *service.ValidationError с именем поля.
+func Constraint(ctx context.Context, obj any, next graphql.Resolver, minLength, maxLength *int32, pattern *string) (any, error) {
+	value, err := next(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case *string:
+		if v == nil {
+			return value, nil
+		}
+		s = *v
+	default:
+		return value, nil
+	}
+
+	c := service.Constraint{}
+	if minLength != nil {
+		c.MinLength = int(*minLength)
+	}
+	if maxLength != nil {
+		c.MaxLength = int(*maxLength)
+	}
+	if pattern != nil {
+		c.Pattern = *pattern
+	}
+	if err := c.Check(fieldName(ctx), s); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// fieldName возвращает имя проверяемого поля ввода или аргумента.
+func fieldName(ctx context.Context) string {
+	if pc := graphql.GetPathContext(ctx); pc != nil && pc.Field != nil {
+		return *pc.Field
+	}
+	if fc := graphql.GetFieldContext(ctx); fc != nil {
+		return fc.Field.Name
+	}
+	return ""
+}
@@ -0,0 +1,74 @@
+package graph
+
+import (
+	"context"
+	"graphql_project/internal/auth"
+	"graphql_project/internal/graph/model"
+	"graphql_project/internal/service"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirectives(t *testing.T) {
+	as := func(role auth.Role) context.Context {
+		return auth.WithPrincipal(context.Background(), auth.Principal{UserID: uuid.New(), Role: role})
+	}
+	calls := 0
+	next := func(ctx context.Context) (any, error) {
+		calls++
+		return "ok", nil
+	}
+
+	t.Run("auth", func(t *testing.T) {
+		calls = 0
+		_, err := Auth(context.Background(), nil, next)
+		assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+
+		res, err := Auth(as(auth.RoleUser), nil, next)
+		require.NoError(t, err)
+		assert.Equal(t, "ok", res)
+		assert.Equal(t, 1, calls, "resolver runs only for authenticated requests")
+	})
+
+	t.Run("hasRole", func(t *testing.T) {
+		calls = 0
+		_, err := HasRole(context.Background(), nil, next, model.RoleModerator)
+		assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+		_, err = HasRole(as(auth.RoleUser), nil, next, model.RoleModerator)
+		assert.ErrorIs(t, err, service.ErrForbidden)
+
+		_, err = HasRole(as(auth.RoleModerator), nil, next, model.RoleModerator)
+		require.NoError(t, err)
+		_, err = HasRole(as(auth.RoleModerator), nil, next, model.RoleUser)
+		require.NoError(t, err)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("constraint", func(t *testing.T) {
+		maxLength := int32(3)
+		value := func(v any) func(ctx context.Context) (any, error) {
+			return func(ctx context.Context) (any, error) { return v, nil }
+		}
+		ctx := graphql.WithPathContext(context.Background(), graphql.NewPathWithField("content"))
+
+		res, err := Constraint(ctx, nil, value("abc"), nil, &maxLength, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "abc", res)
+
+		_, err = Constraint(ctx, nil, value("abcd"), nil, &maxLength, nil)
+		var validationErr *service.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, "content", validationErr.Field)
+
+		long := "abcd"
+		_, err = Constraint(ctx, nil, value(&long), nil, &maxLength, nil)
+		assert.Error(t, err, "optional fields are checked too")
+		res, err = Constraint(ctx, nil, value((*string)(nil)), nil, &maxLength, nil)
+		require.NoError(t, err)
+		assert.Nil(t, res)
+	})
+}
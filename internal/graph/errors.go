@@ -0,0 +1,51 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"graphql_project/internal/auth"
+	"graphql_project/internal/service"
+	"graphql_project/internal/storage"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// errorCodes сопоставляет ошибкам сервиса код, который клиент получает в extensions.code.
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{auth.ErrUnauthenticated, "UNAUTHENTICATED"},
+	{service.ErrForbidden, "FORBIDDEN"},
+	{storage.ErrNotFound, "NOT_FOUND"},
+	{storage.ErrBanned, "BANNED"},
+	{storage.ErrHidden, "HIDDEN"},
+	{storage.ErrAlreadyExists, "ALREADY_EXISTS"},
+	{service.ErrContentRejected, "CONTENT_REJECTED"},
+}
+
+// ErrorPresenter дополняет ошибки резольверов машинно-читаемым кодом. Ошибки проверки
+// ввода получают код VALIDATION_FAILED и имя поля в extensions.field.
+func ErrorPresenter(ctx context.Context, err error) *gqlerror.Error {
+	gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		if gqlErr.Extensions == nil {
+			gqlErr.Extensions = make(map[string]interface{})
+		}
+		gqlErr.Extensions["code"] = "VALIDATION_FAILED"
+		gqlErr.Extensions["field"] = validationErr.Field
+		return gqlErr
+	}
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			if gqlErr.Extensions == nil {
+				gqlErr.Extensions = make(map[string]interface{})
+			}
+			gqlErr.Extensions["code"] = c.code
+			break
+		}
+	}
+	return gqlErr
+}
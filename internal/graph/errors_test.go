@@ -0,0 +1,31 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"graphql_project/internal/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorPresenter(t *testing.T) {
+	ctx := context.Background()
+
+	forbidden := ErrorPresenter(ctx, fmt.Errorf("delete post: %w", service.ErrForbidden))
+	assert.Equal(t, "FORBIDDEN", forbidden.Extensions["code"])
+	assert.Equal(t, "delete post: forbidden", forbidden.Message)
+
+	rejected := ErrorPresenter(ctx, fmt.Errorf("%w: too many links", service.ErrContentRejected))
+	assert.Equal(t, "CONTENT_REJECTED", rejected.Extensions["code"])
+	assert.Equal(t, "content rejected: too many links", rejected.Message)
+
+	invalid := ErrorPresenter(ctx, &service.ValidationError{Field: "content", Message: "must be at most 2000 characters"})
+	assert.Equal(t, "VALIDATION_FAILED", invalid.Extensions["code"])
+	assert.Equal(t, "content", invalid.Extensions["field"])
+	assert.Equal(t, "content: must be at most 2000 characters", invalid.Message)
+
+	other := ErrorPresenter(ctx, errors.New("boom"))
+	assert.NotContains(t, other.Extensions, "code")
+}
@@ -0,0 +1,151 @@
+package loaders
+
+import (
+	"context"
+	"fmt"
+	"graphql_project/internal/graph/model"
+	"graphql_project/internal/service"
+	"graphql_project/internal/storage"
+	"net/http"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+type ctxKey string
+
+const loadersKey ctxKey = "dataloaders"
+
+type connectionLoader = dataloader.Loader[uuid.UUID, *model.CommentConnection]
+
+// Loaders группирует обращения резольверов к хранилищу в пределах одного запроса,
+// чтобы вложенные уровни дерева комментариев загружались одним запросом на уровень.
+type Loaders struct {
+	svc      *service.Service
+	postByID *dataloader.Loader[uuid.UUID, *model.Post]
+	userByID *dataloader.Loader[uuid.UUID, *model.User]
+
+	// Загрузчики комментариев создаются отдельно для каждого набора аргументов
+	// пагинации: в один батч попадают только запросы с одинаковой страницей.
+	mu             sync.Mutex
+	postComments   map[string]*connectionLoader
+	commentReplies map[string]*connectionLoader
+}
+
+func NewLoaders(svc *service.Service) *Loaders {
+	l := &Loaders{
+		svc:            svc,
+		postComments:   make(map[string]*connectionLoader),
+		commentReplies: make(map[string]*connectionLoader),
+	}
+	l.postByID = dataloader.NewBatchedLoader(l.loadPosts, noCache[*model.Post]())
+	l.userByID = dataloader.NewBatchedLoader(l.loadUsers, noCache[*model.User]())
+	return l
+}
+
+// Middleware создаёт новый набор загрузчиков на каждый HTTP-запрос
+// (для websocket — на всё соединение, поэтому результаты не кешируются).
+func Middleware(svc *service.Service, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), loadersKey, NewLoaders(svc))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func For(ctx context.Context) *Loaders {
+	return ctx.Value(loadersKey).(*Loaders)
+}
+
+func (l *Loaders) GetPost(ctx context.Context, id uuid.UUID) (*model.Post, error) {
+	return l.postByID.Load(ctx, id)()
+}
+
+func (l *Loaders) GetUser(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	return l.userByID.Load(ctx, id)()
+}
+
+func (l *Loaders) GetPostComments(ctx context.Context, postID uuid.UUID, page model.PageArgs) (*model.CommentConnection, error) {
+	loader := l.connectionLoader(l.postComments, page, l.svc.GetPostComments)
+	return loader.Load(ctx, postID)()
+}
+
+func (l *Loaders) GetCommentReplies(ctx context.Context, commentID uuid.UUID, page model.PageArgs) (*model.CommentConnection, error) {
+	loader := l.connectionLoader(l.commentReplies, page, l.svc.GetCommentReplies)
+	return loader.Load(ctx, commentID)()
+}
+
+type connectionsFunc func(context.Context, []uuid.UUID, model.PageArgs) (map[uuid.UUID]*model.CommentConnection, error)
+
+func (l *Loaders) connectionLoader(loaders map[string]*connectionLoader, page model.PageArgs, fetch connectionsFunc) *connectionLoader {
+	key := pageKey(page)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if loader, ok := loaders[key]; ok {
+		return loader
+	}
+	loader := dataloader.NewBatchedLoader(func(ctx context.Context, ids []uuid.UUID) []*dataloader.Result[*model.CommentConnection] {
+		conns, err := fetch(ctx, ids, page)
+		results := make([]*dataloader.Result[*model.CommentConnection], len(ids))
+		for i, id := range ids {
+			results[i] = &dataloader.Result[*model.CommentConnection]{Data: conns[id], Error: err}
+		}
+		return results
+	}, noCache[*model.CommentConnection]())
+	loaders[key] = loader
+	return loader
+}
+
+func (l *Loaders) loadPosts(ctx context.Context, ids []uuid.UUID) []*dataloader.Result[*model.Post] {
+	posts, err := l.svc.GetPostsByIDs(ctx, ids)
+	return resultsByID(ids, posts, err, func(post *model.Post) uuid.UUID { return post.ID })
+}
+
+func (l *Loaders) loadUsers(ctx context.Context, ids []uuid.UUID) []*dataloader.Result[*model.User] {
+	users, err := l.svc.GetUsersByIDs(ctx, ids)
+	return resultsByID(ids, users, err, func(user *model.User) uuid.UUID { return user.ID })
+}
+
+// resultsByID раскладывает найденные объекты по порядку запрошенных ids; для
+// отсутствующих возвращается storage.ErrNotFound.
+func resultsByID[V any](ids []uuid.UUID, found []V, err error, key func(V) uuid.UUID) []*dataloader.Result[V] {
+	byID := make(map[uuid.UUID]V, len(found))
+	for _, v := range found {
+		byID[key(v)] = v
+	}
+
+	results := make([]*dataloader.Result[V], len(ids))
+	for i, id := range ids {
+		switch v, ok := byID[id]; {
+		case err != nil:
+			results[i] = &dataloader.Result[V]{Error: err}
+		case !ok:
+			results[i] = &dataloader.Result[V]{Error: storage.ErrNotFound}
+		default:
+			results[i] = &dataloader.Result[V]{Data: v}
+		}
+	}
+	return results
+}
+
+func noCache[V any]() dataloader.Option[uuid.UUID, V] {
+	return dataloader.WithCache[uuid.UUID, V](&dataloader.NoCache[uuid.UUID, V]{})
+}
+
+func pageKey(page model.PageArgs) string {
+	key := func(n *int) string {
+		if n == nil {
+			return "-"
+		}
+		return fmt.Sprint(*n)
+	}
+	cursor := func(c *model.Cursor) string {
+		if c == nil {
+			return "-"
+		}
+		return c.Encode()
+	}
+	return key(page.First) + "|" + cursor(page.After) + "|" + key(page.Last) + "|" + cursor(page.Before) + "|" + string(page.Sort)
+}
@@ -0,0 +1,109 @@
+package loaders
+
+import (
+	"context"
+	"graphql_project/internal/graph/model"
+	"graphql_project/internal/service"
+	"graphql_project/internal/storage"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingStorage struct {
+	storage.Storage
+	postCalls  atomic.Int32
+	replyCalls atomic.Int32
+}
+
+func (c *countingStorage) GetPostsByIDs(ctx context.Context, ids []uuid.UUID) ([]*model.Post, error) {
+	c.postCalls.Add(1)
+	return c.Storage.GetPostsByIDs(ctx, ids)
+}
+
+func (c *countingStorage) GetRepliesByCommentIDs(ctx context.Context, ids []uuid.UUID, page model.PageArgs) (map[uuid.UUID]*model.CommentPage, error) {
+	c.replyCalls.Add(1)
+	return c.Storage.GetRepliesByCommentIDs(ctx, ids, page)
+}
+
+func TestLoaders_BatchReplies(t *testing.T) {
+	ctx := context.Background()
+	store := &countingStorage{Storage: storage.NewInMemStorage()}
+	l := NewLoaders(service.NewService(store))
+
+	author, err := store.CreateUser(ctx, model.NewUser{Handle: "author"})
+	require.NoError(t, err)
+	post, err := store.CreatePost(ctx, model.NewPost{Title: "Post", Commentable: true, AuthorID: author.ID})
+	require.NoError(t, err)
+	postID := post.ID
+
+	var parents []*model.Comment
+	for i := 0; i < 3; i++ {
+		c, err := store.CreateComment(ctx, model.NewComment{Content: "Root", PostID: &postID, AuthorID: author.ID})
+		require.NoError(t, err)
+		parentID := c.ID
+		_, err = store.CreateComment(ctx, model.NewComment{Content: "Reply", CommentID: &parentID, AuthorID: author.ID})
+		require.NoError(t, err)
+		parents = append(parents, c)
+	}
+
+	first := 10
+	page := model.PageArgs{First: &first}
+
+	var wg sync.WaitGroup
+	conns := make([]*model.CommentConnection, len(parents))
+	for i, parent := range parents {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := l.GetCommentReplies(ctx, parent.ID, page)
+			assert.NoError(t, err)
+			conns[i] = conn
+		}()
+	}
+	wg.Wait()
+
+	assert.EqualValues(t, 1, store.replyCalls.Load())
+	for i, conn := range conns {
+		require.Len(t, conn.Edges, 1)
+		assert.Equal(t, parents[i].ID, *conn.Edges[0].Node.ParentID)
+	}
+}
+
+func TestLoaders_GetPost(t *testing.T) {
+	ctx := context.Background()
+	store := &countingStorage{Storage: storage.NewInMemStorage()}
+	l := NewLoaders(service.NewService(store))
+
+	author, err := store.CreateUser(ctx, model.NewUser{Handle: "author"})
+	require.NoError(t, err)
+	post, err := store.CreatePost(ctx, model.NewPost{Title: "Post", AuthorID: author.ID})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	var found *model.Post
+	var missingErr error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		found, _ = l.GetPost(ctx, post.ID)
+	}()
+	go func() {
+		defer wg.Done()
+		_, missingErr = l.GetPost(ctx, uuid.New())
+	}()
+	wg.Wait()
+
+	assert.EqualValues(t, 1, store.postCalls.Load())
+	require.NotNil(t, found)
+	assert.Equal(t, post.ID, found.ID)
+	assert.ErrorIs(t, missingErr, storage.ErrNotFound)
+
+	user, err := l.GetUser(ctx, found.AuthorID)
+	require.NoError(t, err)
+	assert.Equal(t, "author", user.Handle)
+}
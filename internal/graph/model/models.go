@@ -1,13 +1,332 @@
 package model
 
 import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/google/uuid"
 )
 
-type TempComment struct {
-	ID              uuid.UUID
-	PostID          uuid.UUID
-	ParentCommentID *uuid.UUID
-	Author          string
-	Content         string
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type Post struct {
+	ID             uuid.UUID      `json:"id"`
+	Title          string         `json:"title"`
+	AuthorID       uuid.UUID      `json:"authorId"`
+	Content        string         `json:"content"`
+	Commentable    bool           `json:"commentable"`
+	ModerationMode ModerationMode `json:"moderationMode"`
+	CreatedAt      time.Time      `json:"createdAt"`
+	UpdatedAt      *time.Time     `json:"updatedAt,omitempty"`
+	LockedAt       *time.Time     `json:"lockedAt,omitempty"`
+	LockReason     *string        `json:"lockReason,omitempty"`
+	Upvotes        int32          `json:"upvotes"`
+	Downvotes      int32          `json:"downvotes"`
+	DeletedAt      *time.Time     `json:"-"`
+	// UnlockedAt — когда пост явно открыли для комментариев; такой пост не закрывается
+	// автоматически.
+	UnlockedAt *time.Time `json:"-"`
+}
+
+func (Post) IsSearchResult() {}
+func (Post) IsReportTarget() {}
+
+func (p *Post) Score() int32 {
+	return p.Upvotes - p.Downvotes
+}
+
+// Comment хранит ссылки на пост и родительский комментарий; ответы загружаются
+// отдельно через резольвер поля comments.
+type Comment struct {
+	ID        uuid.UUID     `json:"id"`
+	AuthorID  uuid.UUID     `json:"authorId"`
+	Content   string        `json:"content"`
+	PostID    *uuid.UUID    `json:"postId,omitempty"`
+	ParentID  *uuid.UUID    `json:"parentId,omitempty"`
+	CreatedAt time.Time     `json:"createdAt"`
+	EditedAt  *time.Time    `json:"editedAt,omitempty"`
+	Upvotes   int32         `json:"upvotes"`
+	Downvotes int32         `json:"downvotes"`
+	DeletedAt *time.Time    `json:"deletedAt,omitempty"`
+	HiddenAt  *time.Time    `json:"hiddenAt,omitempty"`
+	Status    CommentStatus `json:"status"`
+}
+
+func (Comment) IsSearchResult() {}
+func (Comment) IsReportTarget() {}
+
+func (c *Comment) Score() int32 {
+	return c.Upvotes - c.Downvotes
+}
+
+// NewPost — входные данные createPost. AuthorID не приходит от клиента: сервис берёт
+// его из аутентифицированного пользователя запроса.
+type NewPost struct {
+	Title          string         `json:"title"`
+	Content        string         `json:"content"`
+	Commentable    bool           `json:"commentable"`
+	ModerationMode ModerationMode `json:"moderationMode"`
+	AuthorID       uuid.UUID      `json:"-"`
+	// FilterResults — отметки фильтров содержимого, сохраняемые вместе с постом.
+	FilterResults []*FilterResult `json:"-"`
+}
+
+// UpdatePost — входные данные updatePost; FilterResults заполняется сервисом, как у NewPost.
+type UpdatePost struct {
+	Title          *string         `json:"title,omitempty"`
+	Content        *string         `json:"content,omitempty"`
+	Commentable    *bool           `json:"commentable,omitempty"`
+	ModerationMode *ModerationMode `json:"moderationMode,omitempty"`
+	// FilterResults — отметки фильтров нового заголовка и текста; дописываются к прежним.
+	FilterResults []*FilterResult `json:"-"`
+}
+
+// NewComment — входные данные createComment; AuthorID заполняется сервисом, как у NewPost.
+type NewComment struct {
+	Content   string     `json:"content"`
+	CommentID *uuid.UUID `json:"commentId,omitempty"`
+	PostID    *uuid.UUID `json:"postId,omitempty"`
+	AuthorID  uuid.UUID  `json:"-"`
+	// FilterResults — отметки фильтров содержимого, сохраняемые вместе с комментарием.
+	FilterResults []*FilterResult `json:"-"`
+}
+
+// User — автор постов и комментариев. Handle уникален и используется как имя для входа.
+type User struct {
+	ID          uuid.UUID  `json:"id"`
+	Handle      string     `json:"handle"`
+	DisplayName string     `json:"displayName"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	BannedAt    *time.Time `json:"bannedAt,omitempty"`
+}
+
+// ModerationAction — запись журнала модерации: кто, когда и над каким комментарием или
+// пользователем выполнил действие.
+type ModerationAction struct {
+	ID          uuid.UUID            `json:"id"`
+	ModeratorID uuid.UUID            `json:"moderatorId"`
+	Action      ModerationActionType `json:"action"`
+	TargetID    uuid.UUID            `json:"targetId"`
+	Reason      *string              `json:"reason,omitempty"`
+	CreatedAt   time.Time            `json:"createdAt"`
+}
+
+// NewReport — входные данные reportContent; ReporterID заполняется сервисом, как у NewPost.
+type NewReport struct {
+	TargetType ReportTargetType `json:"targetType"`
+	TargetID   uuid.UUID        `json:"targetId"`
+	Reason     string           `json:"reason"`
+	ReporterID uuid.UUID        `json:"-"`
+}
+
+// Report — жалоба читателя на пост или комментарий. Открытая жалоба ждёт решения
+// модератора; после resolveReport заполняются ResolvedAt, ResolverID и Resolution.
+type Report struct {
+	ID         uuid.UUID        `json:"id"`
+	ReporterID uuid.UUID        `json:"reporterId"`
+	TargetType ReportTargetType `json:"targetType"`
+	TargetID   uuid.UUID        `json:"targetId"`
+	Reason     string           `json:"reason"`
+	CreatedAt  time.Time        `json:"createdAt"`
+	ResolvedAt *time.Time       `json:"resolvedAt,omitempty"`
+	ResolverID *uuid.UUID       `json:"resolverId,omitempty"`
+	Resolution *ReportAction    `json:"resolution,omitempty"`
+}
+
+// ReportQueueItem объединяет открытые жалобы на один объект.
+type ReportQueueItem struct {
+	TargetType      ReportTargetType `json:"targetType"`
+	TargetID        uuid.UUID        `json:"targetId"`
+	ReportCount     int32            `json:"reportCount"`
+	FirstReportedAt time.Time        `json:"firstReportedAt"`
+	LastReportedAt  time.Time        `json:"lastReportedAt"`
+	Reports         []*Report        `json:"reports"`
+}
+
+// Значения голоса; пользователь может отдать за пост или комментарий только один голос.
+const (
+	VoteUp   = 1
+	VoteDown = -1
+)
+
+// DeletedContent подставляется вместо текста удалённого комментария, у которого остались ответы.
+const DeletedContent = "[deleted]"
+
+// HiddenContent подставляется вместо текста комментария, скрытого модератором.
+const HiddenContent = "[hidden]"
+
+// Redacted сообщает, что комментарий показывается без текста и автора: он удалён или скрыт.
+func (c *Comment) Redacted() bool {
+	return c.DeletedAt != nil || c.HiddenAt != nil
+}
+
+// Tombstone возвращает копию удалённого или скрытого комментария без текста и автора.
+func (c *Comment) Tombstone() *Comment {
+	tombstone := *c
+	tombstone.AuthorID, tombstone.Content = uuid.Nil, DeletedContent
+	if c.DeletedAt == nil {
+		tombstone.Content = HiddenContent
+	}
+	return &tombstone
+}
+
+// Cursor указывает на позицию элемента в выборке, упорядоченной по (Rank, CreatedAt, ID).
+// Rank задают только сортировки по голосам (см. CommentSort.Rank), в остальных он равен 0.
+type Cursor struct {
+	Rank      int64
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (c Cursor) Compare(other Cursor) int {
+	if c.Rank != other.Rank {
+		if c.Rank < other.Rank {
+			return -1
+		}
+		return 1
+	}
+	if cmp := c.CreatedAt.Compare(other.CreatedAt); cmp != 0 {
+		return cmp
+	}
+	return bytes.Compare(c.ID[:], other.ID[:])
+}
+
+// Encode возвращает непрозрачное строковое представление курсора для клиента.
+func (c Cursor) Encode() string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + ":" + c.ID.String()
+	if c.Rank != 0 {
+		raw += ":" + strconv.FormatInt(c.Rank, 10)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 2 && len(parts) != 3 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	parsed, err := uuid.Parse(parts[1])
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	cursor := Cursor{CreatedAt: time.Unix(0, nanos).UTC(), ID: parsed}
+	if len(parts) == 3 {
+		if cursor.Rank, err = strconv.ParseInt(parts[2], 10, 64); err != nil {
+			return Cursor{}, ErrInvalidCursor
+		}
+	}
+	return cursor, nil
+}
+
+// Descending сообщает, идут ли комментарии по убыванию ключа курсора. Пустое значение
+// равносильно OLD.
+func (s CommentSort) Descending() bool {
+	return s == CommentSortNew || s == CommentSortTop || s == CommentSortControversial
+}
+
+// Rank возвращает первичный ключ сортировки комментария: счёт для TOP и число голосов
+// меньшинства для CONTROVERSIAL — чем больше голосов набрала проигрывающая сторона,
+// тем выше комментарий.
+func (s CommentSort) Rank(c *Comment) int64 {
+	switch s {
+	case CommentSortTop:
+		return int64(c.Score())
+	case CommentSortControversial:
+		return int64(min(c.Upvotes, c.Downvotes))
+	default:
+		return 0
+	}
+}
+
+// PageArgs описывает keyset-пагинацию: First/After листают вперёд, Last/Before — назад.
+// Одновременно может быть задан только один из First и Last. Sort задаёт порядок
+// комментариев; для постов он не используется.
+type PageArgs struct {
+	First  *int
+	After  *Cursor
+	Last   *int
+	Before *Cursor
+	Sort   CommentSort
+}
+
+func (p PageArgs) Backward() bool {
+	return p.First == nil && p.Last != nil
+}
+
+type PostPage struct {
+	Posts           []*Post
+	HasNextPage     bool
+	HasPreviousPage bool
+	TotalCount      int
+}
+
+// PostRevision — сохранённая предыдущая версия поста. CreatedAt — момент, когда эта
+// версия была записана (создание поста или предыдущая правка).
+type PostRevision struct {
+	PostID      uuid.UUID `json:"postId"`
+	Version     int32     `json:"version"`
+	Title       string    `json:"title"`
+	Content     string    `json:"content"`
+	Commentable bool      `json:"commentable"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// CommentRevision — предыдущий текст комментария; CreatedAt — когда он был записан.
+type CommentRevision struct {
+	CommentID uuid.UUID `json:"commentId"`
+	Version   int32     `json:"version"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// SearchHit — найденный пост или комментарий с фрагментом текста, в котором совпавшие
+// слова обёрнуты в <mark>.
+type SearchHit struct {
+	Node    SearchResult
+	Snippet string
+	Rank    float64
+}
+
+type SearchPage struct {
+	Hits       []*SearchHit
+	TotalCount int
+}
+
+// ModerationLogPage — страница журнала модерации, от новых записей к старым.
+type ModerationLogPage struct {
+	Actions         []*ModerationAction
+	HasNextPage     bool
+	HasPreviousPage bool
+	TotalCount      int
+}
+
+type CommentPage struct {
+	Comments        []*Comment
+	HasNextPage     bool
+	HasPreviousPage bool
+	TotalCount      int
+}
+
+func (p *Post) Cursor() Cursor {
+	return Cursor{CreatedAt: p.CreatedAt, ID: p.ID}
+}
+
+func (a *ModerationAction) Cursor() Cursor {
+	return Cursor{CreatedAt: a.CreatedAt, ID: a.ID}
+}
+
+func (c *Comment) Cursor(sort CommentSort) Cursor {
+	return Cursor{Rank: sort.Rank(c), CreatedAt: c.CreatedAt, ID: c.ID}
 }
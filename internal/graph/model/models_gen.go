@@ -3,45 +3,478 @@
 package model
 
 import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
 	"github.com/google/uuid"
 )
 
-type Comment struct {
-	ID       uuid.UUID  `json:"id"`
-	Author   string     `json:"author"`
-	Content  string     `json:"content"`
-	PostID   *uuid.UUID `json:"postId,omitempty"`
-	Comments []*Comment `json:"comments,omitempty"`
+type PostUpdate interface {
+	IsPostUpdate()
+}
+
+type ReportTarget interface {
+	IsReportTarget()
+}
+
+type SearchResult interface {
+	IsSearchResult()
+}
+
+type CommentConnection struct {
+	Edges    []*CommentEdge `json:"edges"`
+	PageInfo *PageInfo      `json:"pageInfo"`
+}
+
+type CommentEdge struct {
+	Cursor string   `json:"cursor"`
+	Node   *Comment `json:"node"`
+}
+
+type EditComment struct {
+	Content string `json:"content"`
+}
+
+type FilterResult struct {
+	Filter string       `json:"filter"`
+	Action FilterAction `json:"action"`
+	Reason string       `json:"reason"`
+}
+
+type ModerationActionEdge struct {
+	Cursor string            `json:"cursor"`
+	Node   *ModerationAction `json:"node"`
+}
+
+type ModerationLogConnection struct {
+	Edges    []*ModerationActionEdge `json:"edges"`
+	PageInfo *PageInfo               `json:"pageInfo"`
 }
 
 type Mutation struct {
 }
 
-type NewComment struct {
-	Content   string  `json:"content"`
-	Author    string  `json:"author"`
-	CommentID *string `json:"commentId,omitempty"`
-	PostID    *string `json:"postId,omitempty"`
+type NewUser struct {
+	Handle      string  `json:"handle"`
+	DisplayName *string `json:"displayName,omitempty"`
+}
+
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor,omitempty"`
+	EndCursor       *string `json:"endCursor,omitempty"`
+	TotalCount      int32   `json:"totalCount"`
 }
 
-type NewPost struct {
-	Title       string `json:"title"`
-	Content     string `json:"content"`
-	Commentable bool   `json:"commentable"`
-	Author      string `json:"author"`
+type PostConnection struct {
+	Edges    []*PostEdge `json:"edges"`
+	PageInfo *PageInfo   `json:"pageInfo"`
 }
 
-type Post struct {
-	ID          uuid.UUID  `json:"id"`
-	Title       string     `json:"title"`
-	Author      string     `json:"author"`
-	Content     string     `json:"content"`
-	Commentable bool       `json:"commentable"`
-	Comments    []*Comment `json:"comments,omitempty"`
+// Пост удалён; после этого события подписка завершается.
+type PostDeleted struct {
+	PostID    uuid.UUID `json:"postId"`
+	DeletedAt time.Time `json:"deletedAt"`
 }
 
+func (PostDeleted) IsPostUpdate() {}
+
+type PostEdge struct {
+	Cursor string `json:"cursor"`
+	Node   *Post  `json:"node"`
+}
+
+// Пост изменён через updatePost.
+type PostEdited struct {
+	Post *Post `json:"post"`
+}
+
+func (PostEdited) IsPostUpdate() {}
+
+type PostFilter struct {
+	AuthorID    *uuid.UUID `json:"authorId,omitempty"`
+	Commentable *bool      `json:"commentable,omitempty"`
+}
+
+// Пост закрыт для комментариев.
+type PostLocked struct {
+	Post *Post `json:"post"`
+}
+
+func (PostLocked) IsPostUpdate() {}
+
+// Пост снова открыт для комментариев.
+type PostUnlocked struct {
+	Post *Post `json:"post"`
+}
+
+func (PostUnlocked) IsPostUpdate() {}
+
 type Query struct {
 }
 
+type SearchConnection struct {
+	Edges    []*SearchEdge `json:"edges"`
+	PageInfo *PageInfo     `json:"pageInfo"`
+}
+
+type SearchEdge struct {
+	Cursor  string       `json:"cursor"`
+	Node    SearchResult `json:"node"`
+	Snippet string       `json:"snippet"`
+	Rank    float64      `json:"rank"`
+}
+
 type Subscription struct {
 }
+
+type CommentSort string
+
+const (
+	CommentSortNew           CommentSort = "NEW"
+	CommentSortOld           CommentSort = "OLD"
+	CommentSortTop           CommentSort = "TOP"
+	CommentSortControversial CommentSort = "CONTROVERSIAL"
+)
+
+var AllCommentSort = []CommentSort{
+	CommentSortNew,
+	CommentSortOld,
+	CommentSortTop,
+	CommentSortControversial,
+}
+
+func (e CommentSort) IsValid() bool {
+	switch e {
+	case CommentSortNew, CommentSortOld, CommentSortTop, CommentSortControversial:
+		return true
+	}
+	return false
+}
+
+func (e CommentSort) String() string {
+	return string(e)
+}
+
+func (e *CommentSort) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = CommentSort(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid CommentSort", str)
+	}
+	return nil
+}
+
+func (e CommentSort) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// PENDING — комментарий к посту на премодерации, ещё не одобренный модератором.
+type CommentStatus string
+
+const (
+	CommentStatusPublished CommentStatus = "PUBLISHED"
+	CommentStatusPending   CommentStatus = "PENDING"
+)
+
+var AllCommentStatus = []CommentStatus{
+	CommentStatusPublished,
+	CommentStatusPending,
+}
+
+func (e CommentStatus) IsValid() bool {
+	switch e {
+	case CommentStatusPublished, CommentStatusPending:
+		return true
+	}
+	return false
+}
+
+func (e CommentStatus) String() string {
+	return string(e)
+}
+
+func (e *CommentStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = CommentStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid CommentStatus", str)
+	}
+	return nil
+}
+
+func (e CommentStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// Решение фильтра содержимого: отметить для модераторов, переписать текст или отклонить.
+type FilterAction string
+
+const (
+	FilterActionFlag    FilterAction = "FLAG"
+	FilterActionRewrite FilterAction = "REWRITE"
+	FilterActionReject  FilterAction = "REJECT"
+)
+
+var AllFilterAction = []FilterAction{
+	FilterActionFlag,
+	FilterActionRewrite,
+	FilterActionReject,
+}
+
+func (e FilterAction) IsValid() bool {
+	switch e {
+	case FilterActionFlag, FilterActionRewrite, FilterActionReject:
+		return true
+	}
+	return false
+}
+
+func (e FilterAction) String() string {
+	return string(e)
+}
+
+func (e *FilterAction) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = FilterAction(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid FilterAction", str)
+	}
+	return nil
+}
+
+func (e FilterAction) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ModerationActionType string
+
+const (
+	ModerationActionTypeHideComment    ModerationActionType = "HIDE_COMMENT"
+	ModerationActionTypeRestoreComment ModerationActionType = "RESTORE_COMMENT"
+	ModerationActionTypeBanAuthor      ModerationActionType = "BAN_AUTHOR"
+	ModerationActionTypeUnbanAuthor    ModerationActionType = "UNBAN_AUTHOR"
+	ModerationActionTypeApproveComment ModerationActionType = "APPROVE_COMMENT"
+)
+
+var AllModerationActionType = []ModerationActionType{
+	ModerationActionTypeHideComment,
+	ModerationActionTypeRestoreComment,
+	ModerationActionTypeBanAuthor,
+	ModerationActionTypeUnbanAuthor,
+	ModerationActionTypeApproveComment,
+}
+
+func (e ModerationActionType) IsValid() bool {
+	switch e {
+	case ModerationActionTypeHideComment, ModerationActionTypeRestoreComment, ModerationActionTypeBanAuthor, ModerationActionTypeUnbanAuthor, ModerationActionTypeApproveComment:
+		return true
+	}
+	return false
+}
+
+func (e ModerationActionType) String() string {
+	return string(e)
+}
+
+func (e *ModerationActionType) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ModerationActionType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ModerationActionType", str)
+	}
+	return nil
+}
+
+func (e ModerationActionType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// PRE — премодерация: комментарии к посту видны только после approveComment.
+type ModerationMode string
+
+const (
+	ModerationModeNone ModerationMode = "NONE"
+	ModerationModePre  ModerationMode = "PRE"
+)
+
+var AllModerationMode = []ModerationMode{
+	ModerationModeNone,
+	ModerationModePre,
+}
+
+func (e ModerationMode) IsValid() bool {
+	switch e {
+	case ModerationModeNone, ModerationModePre:
+		return true
+	}
+	return false
+}
+
+func (e ModerationMode) String() string {
+	return string(e)
+}
+
+func (e *ModerationMode) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ModerationMode(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ModerationMode", str)
+	}
+	return nil
+}
+
+func (e ModerationMode) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// Решение модератора по жалобам: отклонить, скрыть комментарий или удалить объект.
+type ReportAction string
+
+const (
+	ReportActionDismiss ReportAction = "DISMISS"
+	ReportActionHide    ReportAction = "HIDE"
+	ReportActionDelete  ReportAction = "DELETE"
+)
+
+var AllReportAction = []ReportAction{
+	ReportActionDismiss,
+	ReportActionHide,
+	ReportActionDelete,
+}
+
+func (e ReportAction) IsValid() bool {
+	switch e {
+	case ReportActionDismiss, ReportActionHide, ReportActionDelete:
+		return true
+	}
+	return false
+}
+
+func (e ReportAction) String() string {
+	return string(e)
+}
+
+func (e *ReportAction) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ReportAction(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ReportAction", str)
+	}
+	return nil
+}
+
+func (e ReportAction) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ReportTargetType string
+
+const (
+	ReportTargetTypePost    ReportTargetType = "POST"
+	ReportTargetTypeComment ReportTargetType = "COMMENT"
+)
+
+var AllReportTargetType = []ReportTargetType{
+	ReportTargetTypePost,
+	ReportTargetTypeComment,
+}
+
+func (e ReportTargetType) IsValid() bool {
+	switch e {
+	case ReportTargetTypePost, ReportTargetTypeComment:
+		return true
+	}
+	return false
+}
+
+func (e ReportTargetType) String() string {
+	return string(e)
+}
+
+func (e *ReportTargetType) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ReportTargetType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ReportTargetType", str)
+	}
+	return nil
+}
+
+func (e ReportTargetType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type Role string
+
+const (
+	RoleUser      Role = "USER"
+	RoleModerator Role = "MODERATOR"
+	RoleAdmin     Role = "ADMIN"
+)
+
+var AllRole = []Role{
+	RoleUser,
+	RoleModerator,
+	RoleAdmin,
+}
+
+func (e Role) IsValid() bool {
+	switch e {
+	case RoleUser, RoleModerator, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+func (e Role) String() string {
+	return string(e)
+}
+
+func (e *Role) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Role(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Role", str)
+	}
+	return nil
+}
+
+func (e Role) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
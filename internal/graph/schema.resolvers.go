@@ -6,22 +6,66 @@ package graph
 
 import (
 	"context"
+	"errors"
+	"graphql_project/internal/graph/loaders"
 	"graphql_project/internal/graph/model"
+	"graphql_project/internal/service"
+	"graphql_project/internal/storage"
+
+	"github.com/google/uuid"
 )
 
-func (r *commentResolver) Comments(ctx context.Context, obj *model.Comment, offset *int, limit *int) ([]*model.Comment, error) {
-	if len(obj.Comments) == 0 {
-		return obj.Comments, nil
+// Author is the resolver for the author field.
+func (r *commentResolver) Author(ctx context.Context, obj *model.Comment) (*model.User, error) {
+	if obj.AuthorID == uuid.Nil {
+		return nil, nil
 	}
-	off := 0
-	if offset != nil && int(*offset) < len(obj.Comments) {
-		off = int(*offset)
+	return loaders.For(ctx).GetUser(ctx, obj.AuthorID)
+}
+
+// Post is the resolver for the post field.
+func (r *commentResolver) Post(ctx context.Context, obj *model.Comment) (*model.Post, error) {
+	if obj.PostID == nil {
+		return nil, nil
 	}
-	lim := len(obj.Comments)
-	if limit != nil && int(*limit) <= len(obj.Comments) {
-		lim = int(*limit)
+	return loaders.For(ctx).GetPost(ctx, *obj.PostID)
+}
+
+// Cursor is the resolver for the cursor field.
+func (r *commentResolver) Cursor(ctx context.Context, obj *model.Comment) (string, error) {
+	return obj.Cursor(model.CommentSortOld).Encode(), nil
+}
+
+// History is the resolver for the history field.
+func (r *commentResolver) History(ctx context.Context, obj *model.Comment) ([]*model.CommentRevision, error) {
+	if obj.Redacted() {
+		return []*model.CommentRevision{}, nil
+	}
+	return r.Service.GetCommentHistory(ctx, obj.ID)
+}
+
+// FilterResults is the resolver for the filterResults field.
+func (r *commentResolver) FilterResults(ctx context.Context, obj *model.Comment) ([]*model.FilterResult, error) {
+	return r.Service.GetCommentFilterResults(ctx, obj.ID)
+}
+
+// Comments is the resolver for the comments field.
+func (r *commentResolver) Comments(ctx context.Context, obj *model.Comment, first *int32, after *string, last *int32, before *string, sort *model.CommentSort) (*model.CommentConnection, error) {
+	page, err := service.ParseCommentPageArgs(intPtr(first), after, intPtr(last), before, sort)
+	if err != nil {
+		return nil, err
 	}
-	return obj.Comments[off:lim], nil
+	return loaders.For(ctx).GetCommentReplies(ctx, obj.ID, page)
+}
+
+// Moderator is the resolver for the moderator field.
+func (r *moderationActionResolver) Moderator(ctx context.Context, obj *model.ModerationAction) (*model.User, error) {
+	return loaders.For(ctx).GetUser(ctx, obj.ModeratorID)
+}
+
+// CreateUser is the resolver for the createUser field.
+func (r *mutationResolver) CreateUser(ctx context.Context, input model.NewUser) (*model.User, error) {
+	return r.Service.CreateUser(ctx, input)
 }
 
 // CreatePost is the resolver for the createPost field.
@@ -29,39 +73,123 @@ func (r *mutationResolver) CreatePost(ctx context.Context, input model.NewPost)
 	return r.Service.CreatePost(ctx, input)
 }
 
+// UpdatePost is the resolver for the updatePost field.
+func (r *mutationResolver) UpdatePost(ctx context.Context, id uuid.UUID, input model.UpdatePost) (*model.Post, error) {
+	return r.Service.UpdatePost(ctx, id, input)
+}
+
+// SetCommentable is the resolver for the setCommentable field.
+func (r *mutationResolver) SetCommentable(ctx context.Context, postID uuid.UUID, value bool, reason *string) (*model.Post, error) {
+	return r.Service.SetCommentable(ctx, postID, value, reason)
+}
+
+// DeletePost is the resolver for the deletePost field.
+func (r *mutationResolver) DeletePost(ctx context.Context, id uuid.UUID) (bool, error) {
+	if err := r.Service.DeletePost(ctx, id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UpvotePost is the resolver for the upvotePost field.
+func (r *mutationResolver) UpvotePost(ctx context.Context, id uuid.UUID) (*model.Post, error) {
+	return r.Service.VotePost(ctx, id, model.VoteUp)
+}
+
+// DownvotePost is the resolver for the downvotePost field.
+func (r *mutationResolver) DownvotePost(ctx context.Context, id uuid.UUID) (*model.Post, error) {
+	return r.Service.VotePost(ctx, id, model.VoteDown)
+}
+
 // CreateComment is the resolver for the createComment field.
 func (r *mutationResolver) CreateComment(ctx context.Context, input model.NewComment) (*model.Comment, error) {
-	comment, err := r.Service.CreateComment(ctx, input)
-	if err != nil {
-		return nil, err
-	}
+	return r.Service.CreateComment(ctx, input)
+}
+
+// EditComment is the resolver for the editComment field.
+func (r *mutationResolver) EditComment(ctx context.Context, id uuid.UUID, input model.EditComment) (*model.Comment, error) {
+	return r.Service.EditComment(ctx, id, input)
+}
 
-	for _, observer := range r.observers {
-		if comment.PostID.String() == observer.postID {
-			observer.ch <- comment
-		}
+// DeleteComment is the resolver for the deleteComment field.
+func (r *mutationResolver) DeleteComment(ctx context.Context, id uuid.UUID) (bool, error) {
+	if err := r.Service.DeleteComment(ctx, id); err != nil {
+		return false, err
 	}
+	return true, nil
+}
 
-	return comment, nil
+// UpvoteComment is the resolver for the upvoteComment field.
+func (r *mutationResolver) UpvoteComment(ctx context.Context, id uuid.UUID) (*model.Comment, error) {
+	return r.Service.VoteComment(ctx, id, model.VoteUp)
 }
 
-func (r *postResolver) Comments(ctx context.Context, obj *model.Post, offset *int, limit *int) ([]*model.Comment, error) {
-	if len(obj.Comments) == 0 {
-		return obj.Comments, nil
-	}
-	off := 0
-	if offset != nil && int(*offset) < len(obj.Comments) {
-		off = int(*offset)
-	}
-	lim := len(obj.Comments)
-	if limit != nil && int(*limit) <= len(obj.Comments) {
-		lim = int(*limit)
+// DownvoteComment is the resolver for the downvoteComment field.
+func (r *mutationResolver) DownvoteComment(ctx context.Context, id uuid.UUID) (*model.Comment, error) {
+	return r.Service.VoteComment(ctx, id, model.VoteDown)
+}
+
+// ReportContent is the resolver for the reportContent field.
+func (r *mutationResolver) ReportContent(ctx context.Context, input model.NewReport) (*model.Report, error) {
+	return r.Service.ReportContent(ctx, input)
+}
+
+// ResolveReport is the resolver for the resolveReport field.
+func (r *mutationResolver) ResolveReport(ctx context.Context, targetType model.ReportTargetType, targetID uuid.UUID, action model.ReportAction) ([]*model.Report, error) {
+	return r.Service.ResolveReport(ctx, targetType, targetID, action)
+}
+
+// ApproveComment is the resolver for the approveComment field.
+func (r *mutationResolver) ApproveComment(ctx context.Context, id uuid.UUID) (*model.Comment, error) {
+	return r.Service.ApproveComment(ctx, id)
+}
+
+// HideComment is the resolver for the hideComment field.
+func (r *mutationResolver) HideComment(ctx context.Context, id uuid.UUID, reason *string) (*model.Comment, error) {
+	return r.Service.HideComment(ctx, id, reason)
+}
+
+// RestoreComment is the resolver for the restoreComment field.
+func (r *mutationResolver) RestoreComment(ctx context.Context, id uuid.UUID) (*model.Comment, error) {
+	return r.Service.RestoreComment(ctx, id)
+}
+
+// BanAuthor is the resolver for the banAuthor field.
+func (r *mutationResolver) BanAuthor(ctx context.Context, userID uuid.UUID, reason *string) (*model.User, error) {
+	return r.Service.BanAuthor(ctx, userID, reason)
+}
+
+// UnbanAuthor is the resolver for the unbanAuthor field.
+func (r *mutationResolver) UnbanAuthor(ctx context.Context, userID uuid.UUID) (*model.User, error) {
+	return r.Service.UnbanAuthor(ctx, userID)
+}
+
+// Author is the resolver for the author field.
+func (r *postResolver) Author(ctx context.Context, obj *model.Post) (*model.User, error) {
+	return loaders.For(ctx).GetUser(ctx, obj.AuthorID)
+}
+
+// Revisions is the resolver for the revisions field.
+func (r *postResolver) Revisions(ctx context.Context, obj *model.Post) ([]*model.PostRevision, error) {
+	return r.Service.GetPostRevisions(ctx, obj.ID)
+}
+
+// FilterResults is the resolver for the filterResults field.
+func (r *postResolver) FilterResults(ctx context.Context, obj *model.Post) ([]*model.FilterResult, error) {
+	return r.Service.GetPostFilterResults(ctx, obj.ID)
+}
+
+// Comments is the resolver for the comments field.
+func (r *postResolver) Comments(ctx context.Context, obj *model.Post, first *int32, after *string, last *int32, before *string, sort *model.CommentSort) (*model.CommentConnection, error) {
+	page, err := service.ParseCommentPageArgs(intPtr(first), after, intPtr(last), before, sort)
+	if err != nil {
+		return nil, err
 	}
-	return obj.Comments[off:lim], nil
+	return loaders.For(ctx).GetPostComments(ctx, obj.ID, page)
 }
 
 // Posts is the resolver for the posts field.
-func (r *queryResolver) Posts(ctx context.Context, offset *int, limit *int) ([]*model.Post, error) {
+func (r *queryResolver) Posts(ctx context.Context, offset *int32, limit *int32) ([]*model.Post, error) {
 	var offsetInt *int
 	if offset != nil {
 		tmp := int(*offset)
@@ -77,63 +205,133 @@ func (r *queryResolver) Posts(ctx context.Context, offset *int, limit *int) ([]*
 	return r.Service.GetAllPosts(ctx, offsetInt, limitInt)
 }
 
+// PostsConnection is the resolver for the postsConnection field.
+func (r *queryResolver) PostsConnection(ctx context.Context, first *int32, after *string, last *int32, before *string) (*model.PostConnection, error) {
+	return r.Service.GetPostsConnection(ctx, intPtr(first), after, intPtr(last), before)
+}
+
 // Post is the resolver for the post field.
 func (r *queryResolver) Post(ctx context.Context, id string) (*model.Post, error) {
 	return r.Service.GetPostByID(ctx, id)
 }
 
+// User is the resolver for the user field.
+func (r *queryResolver) User(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	return r.Service.GetUserByID(ctx, id)
+}
+
+// UserByHandle is the resolver for the userByHandle field.
+func (r *queryResolver) UserByHandle(ctx context.Context, handle string) (*model.User, error) {
+	return r.Service.GetUserByHandle(ctx, handle)
+}
+
+// Search is the resolver for the search field.
+func (r *queryResolver) Search(ctx context.Context, query string, first *int32, after *string) (*model.SearchConnection, error) {
+	return r.Service.Search(ctx, query, intPtr(first), after)
+}
+
+// ModerationLog is the resolver for the moderationLog field.
+func (r *queryResolver) ModerationLog(ctx context.Context, first *int32, after *string, last *int32, before *string) (*model.ModerationLogConnection, error) {
+	return r.Service.GetModerationLog(ctx, intPtr(first), after, intPtr(last), before)
+}
+
+// ReportQueue is the resolver for the reportQueue field.
+func (r *queryResolver) ReportQueue(ctx context.Context, offset *int32, limit *int32) ([]*model.ReportQueueItem, error) {
+	return r.Service.GetReportQueue(ctx, intPtr(offset), intPtr(limit))
+}
+
+// PendingComments is the resolver for the pendingComments field.
+func (r *queryResolver) PendingComments(ctx context.Context, postID *uuid.UUID, first *int32, after *string, last *int32, before *string) (*model.CommentConnection, error) {
+	return r.Service.GetPendingComments(ctx, postID, intPtr(first), after, intPtr(last), before)
+}
+
+// Reporter is the resolver for the reporter field.
+func (r *reportResolver) Reporter(ctx context.Context, obj *model.Report) (*model.User, error) {
+	return loaders.For(ctx).GetUser(ctx, obj.ReporterID)
+}
+
+// ResolvedBy is the resolver for the resolvedBy field.
+func (r *reportResolver) ResolvedBy(ctx context.Context, obj *model.Report) (*model.User, error) {
+	if obj.ResolverID == nil {
+		return nil, nil
+	}
+	return loaders.For(ctx).GetUser(ctx, *obj.ResolverID)
+}
+
+// Target is the resolver for the target field.
+func (r *reportQueueItemResolver) Target(ctx context.Context, obj *model.ReportQueueItem) (model.ReportTarget, error) {
+	// Объект мог быть удалён после жалобы: тогда target равен null.
+	var (
+		target model.ReportTarget
+		err    error
+	)
+	if obj.TargetType == model.ReportTargetTypePost {
+		target, err = loaders.For(ctx).GetPost(ctx, obj.TargetID)
+	} else {
+		target, err = r.Service.GetCommentByID(ctx, obj.TargetID)
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
 // CommentAdded is the resolver for the commentAdded field.
-func (r *subscriptionResolver) CommentAdded(ctx context.Context, postID string) (<-chan *model.Comment, error) {
-	newObserver := observer{
-		ch:     make(chan *model.Comment),
-		postID: postID,
+func (r *subscriptionResolver) CommentAdded(ctx context.Context, postID string, since *string) (<-chan *model.Comment, error) {
+	id, err := uuid.Parse(postID)
+	if err != nil {
+		return nil, storage.ErrBadRequest
 	}
+	return r.Service.SubscribeCommentAdded(ctx, id, since)
+}
 
-	r.mu.Lock()
-	r.observers = append(r.observers, newObserver)
-	r.mu.Unlock()
+// ReplyAdded is the resolver for the replyAdded field.
+func (r *subscriptionResolver) ReplyAdded(ctx context.Context, commentID uuid.UUID, maxDepth *int32) (<-chan *model.Comment, error) {
+	return r.Service.SubscribeReplyAdded(ctx, commentID, intPtr(maxDepth))
+}
 
-	id := len(r.observers) - 1
-	go func() {
-		<-ctx.Done()
-		r.mu.Lock()
-		defer r.mu.Unlock()
-		r.observers = append(r.observers[:id], r.observers[:id+1]...)
-	}()
+// PostAdded is the resolver for the postAdded field.
+func (r *subscriptionResolver) PostAdded(ctx context.Context, filter *model.PostFilter) (<-chan *model.Post, error) {
+	return r.Service.SubscribePostAdded(ctx, filter)
+}
 
-	return r.observers[id].ch, nil
+// PostUpdated is the resolver for the postUpdated field.
+func (r *subscriptionResolver) PostUpdated(ctx context.Context, id uuid.UUID) (<-chan model.PostUpdate, error) {
+	return r.Service.SubscribePostUpdated(ctx, id)
 }
 
+// Comment returns CommentResolver implementation.
 func (r *Resolver) Comment() CommentResolver { return &commentResolver{r} }
 
-func (r *Resolver) Post() PostResolver { return &postResolver{r} }
+// ModerationAction returns ModerationActionResolver implementation.
+func (r *Resolver) ModerationAction() ModerationActionResolver { return &moderationActionResolver{r} }
 
 // Mutation returns MutationResolver implementation.
 func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 
+// Post returns PostResolver implementation.
+func (r *Resolver) Post() PostResolver { return &postResolver{r} }
+
 // Query returns QueryResolver implementation.
 func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
 
+// Report returns ReportResolver implementation.
+func (r *Resolver) Report() ReportResolver { return &reportResolver{r} }
+
+// ReportQueueItem returns ReportQueueItemResolver implementation.
+func (r *Resolver) ReportQueueItem() ReportQueueItemResolver { return &reportQueueItemResolver{r} }
+
 // Subscription returns SubscriptionResolver implementation.
 func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionResolver{r} }
 
 type commentResolver struct{ *Resolver }
-type postResolver struct{ *Resolver }
+type moderationActionResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
+type postResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type reportResolver struct{ *Resolver }
+type reportQueueItemResolver struct{ *Resolver }
 type subscriptionResolver struct{ *Resolver }
-
-// !!! WARNING !!!
-// The code below was going to be deleted when updating resolvers. It has been copied here so you have
-// one last chance to move it out of harms way if you want. There are two reasons this happens:
-//  - When renaming or deleting a resolver the old code will be put in here. You can safely delete
-//    it when you're done.
-//  - You have helper methods in this file. Move them out to keep these resolver files clean.
-/*
-	func (r *mutationResolver) CreateTodo(ctx context.Context, input model.NewTodo) (*model.Todo, error) {
-	panic(fmt.Errorf("not implemented: CreateTodo - createTodo"))
-}
-func (r *queryResolver) Todos(ctx context.Context) ([]*model.Todo, error) {
-	panic(fmt.Errorf("not implemented: Todos - todos"))
-}
-*/
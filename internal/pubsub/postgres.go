@@ -0,0 +1,92 @@
+package pubsub
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Postgres — PubSub поверх LISTEN/NOTIFY: сообщение, опубликованное любым экземпляром
+// сервера, получают подписчики всех экземпляров, подключённых к той же базе. Тема
+// становится именем канала, поэтому размер сообщения ограничен 8000 байт.
+type Postgres struct {
+	db       *sql.DB
+	listener *pq.Listener
+	local    *InMem
+
+	mu        sync.Mutex
+	listening map[string]bool
+}
+
+var _ PubSub = (*Postgres)(nil)
+
+func NewPostgres(dsn string, opts ...Option) (*Postgres, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	listener := pq.NewListener(dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("PubSub listener: %v", err)
+		}
+	})
+	p := &Postgres{
+		db:        db,
+		listener:  listener,
+		local:     NewInMem(opts...),
+		listening: make(map[string]bool),
+	}
+	go p.run()
+	return p, nil
+}
+
+// run пересылает уведомления из базы локальным подписчикам. Уведомления, отправленные,
+// пока соединение восстанавливалось, теряются.
+func (p *Postgres) run() {
+	for n := range p.listener.Notify {
+		if n == nil {
+			continue
+		}
+		p.local.Publish(context.Background(), n.Channel, []byte(n.Extra))
+	}
+}
+
+func (p *Postgres) Publish(ctx context.Context, topic string, payload []byte) error {
+	if _, err := p.db.ExecContext(ctx, "SELECT pg_notify($1, $2)", topic, string(payload)); err != nil {
+		return fmt.Errorf("notify %q: %w", topic, err)
+	}
+	return nil
+}
+
+// Subscribe начинает слушать канал topic при первой подписке на него и не перестаёт:
+// число тем ограничено кодом сервиса.
+func (p *Postgres) Subscribe(ctx context.Context, topic string) (<-chan []byte, error) {
+	p.mu.Lock()
+	if !p.listening[topic] {
+		if err := p.listener.Listen(topic); err != nil && err != pq.ErrChannelAlreadyOpen {
+			p.mu.Unlock()
+			return nil, fmt.Errorf("listen %q: %w", topic, err)
+		}
+		p.listening[topic] = true
+	}
+	p.mu.Unlock()
+
+	return p.local.Subscribe(ctx, topic)
+}
+
+func (p *Postgres) Close() error {
+	if err := p.listener.Close(); err != nil {
+		return err
+	}
+	return p.db.Close()
+}
@@ -0,0 +1,84 @@
+package pubsub
+
+import (
+	"context"
+	"graphql_project/internal/broker"
+	"sync"
+)
+
+// Параметры очередей подписчиков по умолчанию.
+const (
+	defaultBuffer = 16
+	defaultPolicy = broker.Drop
+)
+
+// PubSub доставляет сообщения всем подписчикам темы. Сообщения — небольшие
+// сериализованные события: реализация может передавать их между экземплярами сервера.
+type PubSub interface {
+	// Publish отправляет payload подписчикам topic.
+	Publish(ctx context.Context, topic string, payload []byte) error
+	// Subscribe возвращает канал сообщений topic; подписка снимается, а канал
+	// закрывается, когда завершается ctx.
+	Subscribe(ctx context.Context, topic string) (<-chan []byte, error)
+}
+
+// Option настраивает очереди подписчиков.
+type Option func(*options)
+
+type options struct {
+	buffer int
+	policy broker.Policy
+}
+
+// WithQueue задаёт размер очереди каждого подписчика и что делать, когда она заполнена.
+func WithQueue(buffer int, policy broker.Policy) Option {
+	return func(o *options) {
+		o.buffer, o.policy = buffer, policy
+	}
+}
+
+// InMem — PubSub в пределах одного процесса: по брокеру на тему.
+type InMem struct {
+	opts options
+
+	mu      sync.Mutex
+	brokers map[string]*broker.Broker[[]byte]
+}
+
+var _ PubSub = (*InMem)(nil)
+
+func NewInMem(opts ...Option) *InMem {
+	p := &InMem{
+		opts:    options{buffer: defaultBuffer, policy: defaultPolicy},
+		brokers: make(map[string]*broker.Broker[[]byte]),
+	}
+	for _, opt := range opts {
+		opt(&p.opts)
+	}
+	return p
+}
+
+// Publish не ждёт медленных подписчиков: с переполненной очередью поступают по политике
+// из WithQueue.
+func (p *InMem) Publish(ctx context.Context, topic string, payload []byte) error {
+	p.topic(topic).Publish(payload)
+	return nil
+}
+
+func (p *InMem) Subscribe(ctx context.Context, topic string) (<-chan []byte, error) {
+	return p.topic(topic).Subscribe(ctx), nil
+}
+
+// topic возвращает брокер темы, создавая его при первом обращении. Брокеры не удаляются:
+// число тем ограничено кодом сервиса.
+func (p *InMem) topic(name string) *broker.Broker[[]byte] {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	b, ok := p.brokers[name]
+	if !ok {
+		b = broker.New[[]byte](p.opts.buffer, p.opts.policy)
+		p.brokers[name] = b
+	}
+	return b
+}
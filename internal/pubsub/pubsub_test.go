@@ -0,0 +1,63 @@
+package pubsub
+
+import (
+	"context"
+	"graphql_project/internal/broker"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMem(t *testing.T) {
+	ps := NewInMem()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first, err := ps.Subscribe(ctx, "comments")
+	require.NoError(t, err)
+	second, err := ps.Subscribe(ctx, "comments")
+	require.NoError(t, err)
+	other, err := ps.Subscribe(ctx, "posts")
+	require.NoError(t, err)
+
+	require.NoError(t, ps.Publish(ctx, "comments", []byte("hello")))
+	assert.Equal(t, []byte("hello"), <-first)
+	assert.Equal(t, []byte("hello"), <-second)
+	assert.Empty(t, other, "other topics are not notified")
+
+	t.Run("slow subscriber does not block publishing", func(t *testing.T) {
+		for i := 0; i < defaultBuffer+1; i++ {
+			require.NoError(t, ps.Publish(ctx, "comments", []byte("spam")))
+		}
+		assert.Len(t, first, defaultBuffer)
+	})
+
+	t.Run("disconnect policy", func(t *testing.T) {
+		ps := NewInMem(WithQueue(1, broker.Disconnect))
+		ch, err := ps.Subscribe(ctx, "comments")
+		require.NoError(t, err)
+		require.NoError(t, ps.Publish(ctx, "comments", []byte("1")))
+		require.NoError(t, ps.Publish(ctx, "comments", []byte("2")))
+
+		assert.Equal(t, []byte("1"), <-ch)
+		_, ok := <-ch
+		assert.False(t, ok)
+	})
+
+	t.Run("unsubscribe on cancel", func(t *testing.T) {
+		subCtx, subCancel := context.WithCancel(ctx)
+		ch, err := ps.Subscribe(subCtx, "replies")
+		require.NoError(t, err)
+		subCancel()
+
+		select {
+		case _, ok := <-ch:
+			assert.False(t, ok)
+		case <-time.After(time.Second):
+			t.Fatal("channel is not closed after cancel")
+		}
+		assert.Equal(t, 0, ps.topic("replies").Subscribers())
+	})
+}
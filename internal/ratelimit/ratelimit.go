@@ -0,0 +1,193 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"graphql_project/internal/auth"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+type ctxKey string
+
+const clientIPKey ctxKey = "client_ip"
+
+// sweepEvery — как часто Limiter забывает заполненные корзины, чтобы их число не росло
+// вместе с числом клиентов.
+const sweepEvery = time.Minute
+
+// Limiter — набор корзин токенов, по одной на ключ. Корзина вмещает burst токенов и
+// пополняется со скоростью rate в секунду; каждый запрос забирает токен.
+type Limiter struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+}
+
+// NewLimiter создаёт ограничение perMinute запросов в минуту на ключ; столько же запросов
+// можно выполнить подряд.
+func NewLimiter(perMinute int) *Limiter {
+	return &Limiter{
+		rate:    float64(perMinute) / time.Minute.Seconds(),
+		burst:   float64(perMinute),
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow забирает по токену из корзины каждого ключа. Если хотя бы одна корзина пуста,
+// ни один токен не тратится, а возвращается время, через которое запрос пройдёт.
+func (l *Limiter) Allow(keys ...string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	var wait time.Duration
+	buckets := make([]*bucket, len(keys))
+	for i, key := range keys {
+		b, ok := l.buckets[key]
+		if !ok {
+			b = &bucket{tokens: l.burst, at: now}
+			l.buckets[key] = b
+		}
+		b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.at).Seconds()*l.rate)
+		b.at = now
+		if b.tokens < 1 {
+			wait = max(wait, time.Duration((1-b.tokens)/l.rate*float64(time.Second)))
+		}
+		buckets[i] = b
+	}
+	if wait > 0 {
+		return false, wait
+	}
+
+	for _, b := range buckets {
+		b.tokens--
+	}
+	return true, 0
+}
+
+// sweep удаляет корзины, которые успели заполниться; вызывающий должен удерживать l.mu.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < sweepEvery {
+		return
+	}
+	l.swept = now
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.at).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Extension — расширение gqlgen, ограничивающее частоту мутаций отдельно для каждого
+// автора и каждого IP-адреса клиента.
+type Extension struct {
+	limits map[string]*Limiter
+}
+
+var (
+	_ graphql.HandlerExtension = (*Extension)(nil)
+	_ graphql.FieldInterceptor = (*Extension)(nil)
+)
+
+// New создаёт ограничения частоты: ключ perMinute — поле Mutation, значение — число
+// запросов в минуту; 0 снимает ограничение.
+func New(perMinute map[string]int) *Extension {
+	limits := make(map[string]*Limiter, len(perMinute))
+	for field, n := range perMinute {
+		if n > 0 {
+			limits[field] = NewLimiter(n)
+		}
+	}
+	return &Extension{limits: limits}
+}
+
+func (e *Extension) ExtensionName() string {
+	return "RateLimit"
+}
+
+// Validate проверяет, что все ограничиваемые поля есть в схеме.
+func (e *Extension) Validate(schema graphql.ExecutableSchema) error {
+	mutation := schema.Schema().Mutation
+	for field := range e.limits {
+		if mutation == nil || mutation.Fields.ForName(field) == nil {
+			return fmt.Errorf("rate limit: unknown mutation %q", field)
+		}
+	}
+	return nil
+}
+
+// InterceptField отклоняет мутацию с кодом RATE_LIMITED, если исчерпан лимит автора
+// или IP-адреса; extensions.retryAfter — через сколько секунд можно повторить запрос.
+func (e *Extension) InterceptField(ctx context.Context, next graphql.Resolver) (any, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || fc.Object != "Mutation" {
+		return next(ctx)
+	}
+	limiter, ok := e.limits[fc.Field.Name]
+	if !ok {
+		return next(ctx)
+	}
+
+	var keys []string
+	if p, ok := auth.FromContext(ctx); ok {
+		keys = append(keys, "author:"+p.UserID.String())
+	}
+	if ip := ClientIP(ctx); ip != "" {
+		keys = append(keys, "ip:"+ip)
+	}
+	if len(keys) == 0 {
+		return next(ctx)
+	}
+
+	if allowed, wait := limiter.Allow(keys...); !allowed {
+		return nil, &gqlerror.Error{
+			Message: "rate limit exceeded",
+			Path:    fc.Path(),
+			Extensions: map[string]interface{}{
+				"code":       "RATE_LIMITED",
+				"retryAfter": int(math.Ceil(wait.Seconds())),
+			},
+		}
+	}
+	return next(ctx)
+}
+
+// Middleware кладёт в контекст IP-адрес клиента из адреса соединения.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+		next.ServeHTTP(w, r.WithContext(WithClientIP(r.Context(), ip)))
+	})
+}
+
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey, ip)
+}
+
+// ClientIP возвращает IP-адрес клиента или пустую строку, если он неизвестен.
+func ClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey).(string)
+	return ip
+}
@@ -0,0 +1,117 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"graphql_project/internal/auth"
+	"graphql_project/internal/graph"
+	"graphql_project/internal/graph/model"
+	"graphql_project/internal/service"
+	"graphql_project/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter(t *testing.T) {
+	limiter := NewLimiter(2)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		allowed, _ := limiter.Allow("author:a")
+		assert.True(t, allowed, "burst of %d requests", i+1)
+	}
+	allowed, wait := limiter.Allow("author:a")
+	assert.False(t, allowed)
+	assert.Equal(t, 30*time.Second, wait)
+
+	allowed, _ = limiter.Allow("author:b")
+	assert.True(t, allowed, "keys have separate buckets")
+	allowed, _ = limiter.Allow("author:b", "ip:1")
+	assert.True(t, allowed)
+	allowed, _ = limiter.Allow("author:b", "ip:1")
+	assert.False(t, allowed, "one empty bucket rejects the request")
+	allowed, _ = limiter.Allow("ip:1")
+	assert.True(t, allowed, "the rejected request spent no tokens")
+
+	now = now.Add(30 * time.Second)
+	allowed, _ = limiter.Allow("author:a")
+	assert.True(t, allowed, "the bucket refills over time")
+}
+
+func TestExtension(t *testing.T) {
+	svc := service.NewService(storage.NewInMemStorage())
+	ctx := context.Background()
+	author, err := svc.CreateUser(ctx, model.NewUser{Handle: "author"})
+	require.NoError(t, err)
+	asAuthor := auth.WithPrincipal(ctx, auth.Principal{UserID: author.ID, Role: auth.RoleUser})
+	post, err := svc.CreatePost(asAuthor, model.NewPost{Title: "Post", Commentable: true})
+	require.NoError(t, err)
+
+	srv := handler.New(graph.NewExecutableSchema(graph.Config{
+		Resolvers:  graph.NewResolver(svc),
+		Directives: graph.Directives(),
+	}))
+	srv.AddTransport(transport.POST{})
+	srv.SetErrorPresenter(graph.ErrorPresenter)
+	srv.Use(New(map[string]int{"createComment": 1, "createUser": 1}))
+
+	type response struct {
+		Errors []struct {
+			Message    string                 `json:"message"`
+			Extensions map[string]interface{} `json:"extensions"`
+		} `json:"errors"`
+	}
+	do := func(query string, principal *auth.Principal, ip string) response {
+		req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(query))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = ip + ":12345"
+		if principal != nil {
+			req = req.WithContext(auth.WithPrincipal(req.Context(), *principal))
+		}
+		rec := httptest.NewRecorder()
+		Middleware(srv).ServeHTTP(rec, req)
+
+		var resp response
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp
+	}
+	createComment := func(principal *auth.Principal, ip string) response {
+		return do(fmt.Sprintf(`{"query": "mutation { createComment(input: {content: \"hi\", postId: \"%s\"}) { id } }"}`, post.ID), principal, ip)
+	}
+	createUser := func(handle, ip string) response {
+		return do(fmt.Sprintf(`{"query": "mutation { createUser(input: {handle: \"%s\"}) { id } }"}`, handle), nil, ip)
+	}
+	principal := &auth.Principal{UserID: author.ID, Role: auth.RoleUser}
+
+	assert.Empty(t, createComment(principal, "10.0.0.1").Errors)
+
+	resp := createComment(principal, "10.0.0.2")
+	require.Len(t, resp.Errors, 1, "the author is limited from any address")
+	assert.Equal(t, "RATE_LIMITED", resp.Errors[0].Extensions["code"])
+	assert.Equal(t, float64(60), resp.Errors[0].Extensions["retryAfter"])
+
+	resp = createComment(&auth.Principal{UserID: uuid.New(), Role: auth.RoleUser}, "10.0.0.1")
+	require.Len(t, resp.Errors, 1, "the address is limited for any author")
+	assert.Equal(t, "RATE_LIMITED", resp.Errors[0].Extensions["code"])
+
+	resp = createComment(nil, "10.0.0.3")
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "UNAUTHENTICATED", resp.Errors[0].Extensions["code"], "other addresses are not limited")
+
+	assert.Empty(t, createUser("first", "10.0.0.4").Errors)
+	resp = createUser("second", "10.0.0.4")
+	require.Len(t, resp.Errors, 1, "sign-ups are limited per address")
+	assert.Equal(t, "RATE_LIMITED", resp.Errors[0].Extensions["code"])
+	assert.Empty(t, createUser("third", "10.0.0.5").Errors)
+}
@@ -0,0 +1,370 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"graphql_project/internal/graph/model"
+	"graphql_project/internal/pubsub"
+	"graphql_project/internal/storage"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// topicCommentAdded — тема событий о новых опубликованных комментариях.
+	topicCommentAdded = "comment_added"
+	// topicPostAdded и topicPostUpdated — темы событий о новых и изменённых постах.
+	topicPostAdded   = "post_added"
+	topicPostUpdated = "post_updated"
+	// replayBatch — сколько пропущенных комментариев читается из хранилища за раз.
+	replayBatch = 100
+	// publishTimeout ограничивает отправку события, которая уже не зависит от запроса.
+	publishTimeout = 5 * time.Second
+)
+
+// commentEvent — событие о комментарии. В нём только идентификаторы: подписчики читают
+// сам комментарий из хранилища, поэтому событие помещается в уведомление Postgres.
+type commentEvent struct {
+	ID       uuid.UUID  `json:"id"`
+	PostID   uuid.UUID  `json:"postId"`
+	ParentID *uuid.UUID `json:"parentId,omitempty"`
+}
+
+// postEventKind — что произошло с постом.
+type postEventKind string
+
+const (
+	postAdded    postEventKind = "added"
+	postEdited   postEventKind = "edited"
+	postLocked   postEventKind = "locked"
+	postUnlocked postEventKind = "unlocked"
+	postDeleted  postEventKind = "deleted"
+)
+
+// postEvent — событие о посте; как и commentEvent, содержит только идентификаторы.
+type postEvent struct {
+	ID       uuid.UUID     `json:"id"`
+	AuthorID uuid.UUID     `json:"authorId"`
+	Kind     postEventKind `json:"kind"`
+	At       time.Time     `json:"at"`
+}
+
+// WithPubSub задаёт, через что рассылаются события подписок; по умолчанию — pubsub.InMem,
+// и события видны только в пределах процесса.
+func WithPubSub(ps pubsub.PubSub) Option {
+	return func(s *Service) {
+		s.pubsub = ps
+	}
+}
+
+// publish отправляет событие подписчикам topic. Изменение уже сохранено, поэтому отмена
+// запроса не должна прерывать отправку, а ошибка доставки только записывается в лог.
+func (s *Service) publish(ctx context.Context, topic string, event any) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), publishTimeout)
+	defer cancel()
+
+	payload, err := json.Marshal(event)
+	if err == nil {
+		err = s.pubsub.Publish(ctx, topic, payload)
+	}
+	if err != nil {
+		log.Printf("Publishing %s event failed: %v", topic, err)
+	}
+}
+
+// publishCommentAdded сообщает подписчикам об опубликованном комментарии.
+func (s *Service) publishCommentAdded(ctx context.Context, comment *model.Comment) {
+	if comment.Status != model.CommentStatusPublished || comment.PostID == nil {
+		return
+	}
+	s.publish(ctx, topicCommentAdded, commentEvent{ID: comment.ID, PostID: *comment.PostID, ParentID: comment.ParentID})
+}
+
+// publishPost сообщает подписчикам о новом или изменённом посте.
+func (s *Service) publishPost(ctx context.Context, kind postEventKind, id, authorID uuid.UUID) {
+	topic := topicPostUpdated
+	if kind == postAdded {
+		topic = topicPostAdded
+	}
+	s.publish(ctx, topic, postEvent{ID: id, AuthorID: authorID, Kind: kind, At: time.Now().UTC()})
+}
+
+// commentableEvent возвращает вид события для поста, открытого или закрытого для комментариев.
+func commentableEvent(commentable bool) postEventKind {
+	if commentable {
+		return postUnlocked
+	}
+	return postLocked
+}
+
+// SubscribeCommentAdded возвращает канал комментариев, опубликованных под постом postID;
+// канал закрывается, когда завершается ctx. Если задан since — курсор последнего
+// полученного клиентом комментария, — сначала приходят комментарии, опубликованные после
+// него, а затем новые.
+func (s *Service) SubscribeCommentAdded(ctx context.Context, postID uuid.UUID, since *string) (<-chan *model.Comment, error) {
+	var after *model.Cursor
+	if since != nil {
+		cursor, err := model.DecodeCursor(*since)
+		if err != nil {
+			return nil, err
+		}
+		after = &cursor
+	}
+
+	// Подписываемся до чтения хранилища: комментарий, сохранённый во время повтора,
+	// придёт событием, а не потеряется между ними.
+	events, err := s.pubsub.Subscribe(ctx, topicCommentAdded)
+	if err != nil {
+		return nil, err
+	}
+
+	comments := make(chan *model.Comment)
+	go func() {
+		defer close(comments)
+		send := func(comment *model.Comment) bool {
+			select {
+			case comments <- comment:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		replayed, pending, ok := s.replayComments(ctx, postID, after, comments, events)
+		if !ok {
+			return
+		}
+		for _, payload := range pending {
+			if comment := s.eventComment(ctx, payload, postID, replayed); comment != nil && !send(comment) {
+				return
+			}
+		}
+		for payload := range events {
+			if comment := s.eventComment(ctx, payload, postID, replayed); comment != nil && !send(comment) {
+				return
+			}
+		}
+	}()
+	return comments, nil
+}
+
+// replayComments отправляет в out комментарии поста, опубликованные после after, от старых
+// к новым. Пока клиент их читает, события копятся в pending, чтобы не переполнить очередь
+// подписчика. Возвращает идентификаторы отправленных комментариев — их события надо
+// пропустить — и false, если подписка завершилась.
+func (s *Service) replayComments(ctx context.Context, postID uuid.UUID, after *model.Cursor, out chan<- *model.Comment, events <-chan []byte) (map[uuid.UUID]bool, [][]byte, bool) {
+	replayed := make(map[uuid.UUID]bool)
+	var pending [][]byte
+	if after == nil {
+		return replayed, pending, true
+	}
+
+	size := replayBatch
+	page := model.PageArgs{First: &size, After: after, Sort: model.CommentSortOld}
+	for {
+		batch, err := s.storage.GetPostComments(ctx, postID, page)
+		if err != nil {
+			log.Printf("Replaying comments of post %s failed: %v", postID, err)
+			return nil, nil, false
+		}
+		for _, comment := range batch.Comments {
+			replayed[comment.ID] = true
+			for sent := false; !sent; {
+				select {
+				case out <- comment:
+					sent = true
+				case payload, ok := <-events:
+					if !ok {
+						return nil, nil, false
+					}
+					pending = append(pending, payload)
+				case <-ctx.Done():
+					return nil, nil, false
+				}
+			}
+		}
+		if !batch.HasNextPage || len(batch.Comments) == 0 {
+			return replayed, pending, true
+		}
+		cursor := batch.Comments[len(batch.Comments)-1].Cursor(model.CommentSortOld)
+		page.After = &cursor
+	}
+}
+
+// eventComment читает из хранилища комментарий из события, если он относится к postID и
+// ещё не был отправлен при повторе.
+func (s *Service) eventComment(ctx context.Context, payload []byte, postID uuid.UUID, replayed map[uuid.UUID]bool) *model.Comment {
+	var event commentEvent
+	if err := json.Unmarshal(payload, &event); err != nil || event.PostID != postID || replayed[event.ID] {
+		return nil
+	}
+	comment, err := s.storage.GetCommentByID(ctx, event.ID)
+	if err != nil {
+		return nil
+	}
+	return redacted(comment)
+}
+
+// redacted возвращает комментарий в том виде, в каком его видят подписчики: скрытый
+// модератором за то время, пока событие шло до подписчика, приходит без текста и автора.
+func redacted(comment *model.Comment) *model.Comment {
+	if comment.Redacted() {
+		return comment.Tombstone()
+	}
+	return comment
+}
+
+// SubscribeReplyAdded возвращает канал новых ответов на комментарий commentID — прямых и
+// вложенных, но не глубже maxDepth (nil — без ограничения); канал закрывается, когда
+// завершается ctx.
+func (s *Service) SubscribeReplyAdded(ctx context.Context, commentID uuid.UUID, maxDepth *int) (<-chan *model.Comment, error) {
+	if maxDepth != nil && *maxDepth < 1 {
+		return nil, storage.ErrBadRequest
+	}
+	root, err := s.storage.GetCommentByID(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := s.pubsub.Subscribe(ctx, topicCommentAdded)
+	if err != nil {
+		return nil, err
+	}
+
+	replies := make(chan *model.Comment)
+	go func() {
+		defer close(replies)
+		for payload := range events {
+			var event commentEvent
+			if err := json.Unmarshal(payload, &event); err != nil || event.PostID != *root.PostID || event.ParentID == nil {
+				continue
+			}
+			depth, err := s.replyDepth(ctx, event, commentID)
+			if err != nil || depth == 0 || (maxDepth != nil && depth > *maxDepth) {
+				continue
+			}
+			reply, err := s.storage.GetCommentByID(ctx, event.ID)
+			if err != nil {
+				continue
+			}
+			select {
+			case replies <- redacted(reply):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return replies, nil
+}
+
+// replyDepth возвращает, на какой глубине под ancestorID находится комментарий из события,
+// или 0, если он не в этой ветке. Прямой ответ распознаётся без обращения к хранилищу.
+func (s *Service) replyDepth(ctx context.Context, event commentEvent, ancestorID uuid.UUID) (int, error) {
+	if *event.ParentID == ancestorID {
+		return 1, nil
+	}
+	ancestors, err := s.storage.GetCommentAncestors(ctx, event.ID)
+	if err != nil {
+		return 0, err
+	}
+	for i, id := range ancestors {
+		if id == ancestorID {
+			return i + 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// SubscribePostAdded возвращает канал новых постов, подходящих под filter; канал
+// закрывается, когда завершается ctx.
+func (s *Service) SubscribePostAdded(ctx context.Context, filter *model.PostFilter) (<-chan *model.Post, error) {
+	if filter == nil {
+		filter = &model.PostFilter{}
+	}
+	events, err := s.pubsub.Subscribe(ctx, topicPostAdded)
+	if err != nil {
+		return nil, err
+	}
+
+	posts := make(chan *model.Post)
+	go func() {
+		defer close(posts)
+		for payload := range events {
+			var event postEvent
+			if err := json.Unmarshal(payload, &event); err != nil {
+				continue
+			}
+			if filter.AuthorID != nil && event.AuthorID != *filter.AuthorID {
+				continue
+			}
+			post, err := s.storage.GetPostByID(ctx, event.ID.String())
+			if err != nil || (filter.Commentable != nil && post.Commentable != *filter.Commentable) {
+				continue
+			}
+			select {
+			case posts <- post:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return posts, nil
+}
+
+// SubscribePostUpdated возвращает канал изменений поста id. После удаления поста приходит
+// PostDeleted, и канал закрывается; иначе — когда завершается ctx.
+func (s *Service) SubscribePostUpdated(ctx context.Context, id uuid.UUID) (<-chan model.PostUpdate, error) {
+	if _, err := s.storage.GetPostByID(ctx, id.String()); err != nil {
+		return nil, err
+	}
+	events, err := s.pubsub.Subscribe(ctx, topicPostUpdated)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(chan model.PostUpdate)
+	go func() {
+		defer close(updates)
+		for payload := range events {
+			var event postEvent
+			if err := json.Unmarshal(payload, &event); err != nil || event.ID != id {
+				continue
+			}
+			update := s.postUpdate(ctx, event)
+			if update == nil {
+				continue
+			}
+			select {
+			case updates <- update:
+			case <-ctx.Done():
+				return
+			}
+			if event.Kind == postDeleted {
+				return
+			}
+		}
+	}()
+	return updates, nil
+}
+
+// postUpdate превращает событие в значение PostUpdate, читая пост из хранилища; nil —
+// если пост уже недоступен.
+func (s *Service) postUpdate(ctx context.Context, event postEvent) model.PostUpdate {
+	if event.Kind == postDeleted {
+		return &model.PostDeleted{PostID: event.ID, DeletedAt: event.At}
+	}
+	post, err := s.storage.GetPostByID(ctx, event.ID.String())
+	if err != nil {
+		return nil
+	}
+	switch event.Kind {
+	case postLocked:
+		return &model.PostLocked{Post: post}
+	case postUnlocked:
+		return &model.PostUnlocked{Post: post}
+	default:
+		return &model.PostEdited{Post: post}
+	}
+}
@@ -0,0 +1,387 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"graphql_project/internal/auth"
+	"graphql_project/internal/graph/model"
+	"graphql_project/internal/pubsub"
+	"graphql_project/internal/storage"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestService_CommentAdded проверяет подписку на двух сервисах с общим хранилищем и
+// PubSub — так работают несколько экземпляров сервера: комментарий, созданный одним,
+// получают подписчики другого.
+func TestService_CommentAdded(t *testing.T) {
+	store := storage.NewInMemStorage()
+	events := pubsub.NewInMem()
+	writer := NewService(store, WithPubSub(events))
+	reader := NewService(store, WithPubSub(events))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	author, err := writer.CreateUser(ctx, model.NewUser{Handle: "author"})
+	require.NoError(t, err)
+	moderator, err := writer.CreateUser(ctx, model.NewUser{Handle: "moderator"})
+	require.NoError(t, err)
+	asAuthor := auth.WithPrincipal(ctx, auth.Principal{UserID: author.ID, Role: auth.RoleUser})
+	asModerator := auth.WithPrincipal(ctx, auth.Principal{UserID: moderator.ID, Role: auth.RoleModerator})
+
+	post, err := writer.CreatePost(asAuthor, model.NewPost{Title: "Post", Commentable: true})
+	require.NoError(t, err)
+	other, err := writer.CreatePost(asAuthor, model.NewPost{Title: "Other", Commentable: true, ModerationMode: model.ModerationModePre})
+	require.NoError(t, err)
+	postID, otherID := post.ID, other.ID
+
+	comments, err := reader.SubscribeCommentAdded(ctx, post.ID, nil)
+	require.NoError(t, err)
+	pending, err := reader.SubscribeCommentAdded(ctx, other.ID, nil)
+	require.NoError(t, err)
+
+	receive := func(ch <-chan *model.Comment) *model.Comment {
+		t.Helper()
+		select {
+		case c := <-ch:
+			return c
+		case <-time.After(time.Second):
+			t.Fatal("no comment received")
+			return nil
+		}
+	}
+
+	created, err := writer.CreateComment(asAuthor, model.NewComment{Content: "Hello", PostID: &postID})
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, receive(comments).ID)
+
+	awaiting, err := writer.CreateComment(asAuthor, model.NewComment{Content: "Awaiting", PostID: &otherID})
+	require.NoError(t, err)
+	select {
+	case c := <-pending:
+		t.Fatalf("pending comment %s delivered", c.ID)
+	case <-time.After(50 * time.Millisecond):
+	}
+	_, err = writer.ApproveComment(asModerator, awaiting.ID)
+	require.NoError(t, err)
+	assert.Equal(t, awaiting.ID, receive(pending).ID, "approved comment is delivered")
+	assert.Empty(t, comments, "comments of other posts are not delivered")
+
+	cancel()
+	_, ok := <-comments
+	assert.False(t, ok, "channel is closed when the subscription ends")
+}
+
+func TestService_CommentAddedSince(t *testing.T) {
+	store := storage.NewInMemStorage()
+	events := pubsub.NewInMem()
+	service := NewService(store, WithPubSub(events))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	author, err := service.CreateUser(ctx, model.NewUser{Handle: "author"})
+	require.NoError(t, err)
+	asAuthor := auth.WithPrincipal(ctx, auth.Principal{UserID: author.ID, Role: auth.RoleUser})
+	post, err := service.CreatePost(asAuthor, model.NewPost{Title: "Post", Commentable: true})
+	require.NoError(t, err)
+	postID := post.ID
+
+	// Больше одной порции повтора, с ответом среди комментариев.
+	var created []*model.Comment
+	for i := 0; i < replayBatch+5; i++ {
+		newComment := model.NewComment{Content: fmt.Sprintf("Comment %d", i), PostID: &postID}
+		if i == 3 {
+			newComment = model.NewComment{Content: "Reply", CommentID: &created[1].ID}
+		}
+		c, err := service.CreateComment(asAuthor, newComment)
+		require.NoError(t, err)
+		created = append(created, c)
+	}
+
+	since := created[0].Cursor(model.CommentSortOld).Encode()
+	comments, err := service.SubscribeCommentAdded(ctx, post.ID, &since)
+	require.NoError(t, err)
+
+	// Событие о комментарии, который придёт и при повторе, не должно его продублировать.
+	payload, err := json.Marshal(commentEvent{ID: created[2].ID, PostID: post.ID})
+	require.NoError(t, err)
+	require.NoError(t, events.Publish(ctx, topicCommentAdded, payload))
+
+	receive := func() *model.Comment {
+		t.Helper()
+		select {
+		case c := <-comments:
+			return c
+		case <-time.After(time.Second):
+			t.Fatal("no comment received")
+			return nil
+		}
+	}
+	for _, want := range created[1:] {
+		assert.Equal(t, want.ID, receive().ID)
+	}
+
+	live, err := service.CreateComment(asAuthor, model.NewComment{Content: "Live", PostID: &postID})
+	require.NoError(t, err)
+	assert.Equal(t, live.ID, receive().ID, "live comments follow the replay without duplicates")
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		bad := "not a cursor"
+		_, err := service.SubscribeCommentAdded(ctx, post.ID, &bad)
+		assert.ErrorIs(t, err, model.ErrInvalidCursor)
+	})
+}
+
+// publishContexts запоминает ошибку контекста каждой публикации.
+type publishContexts struct {
+	pubsub.PubSub
+	errs []error
+}
+
+func (p *publishContexts) Publish(ctx context.Context, topic string, payload []byte) error {
+	p.errs = append(p.errs, ctx.Err())
+	return p.PubSub.Publish(ctx, topic, payload)
+}
+
+// TestService_PublishAfterCancel проверяет, что событие уходит, даже если клиент отменил
+// запрос, когда изменение уже сохранено.
+func TestService_PublishAfterCancel(t *testing.T) {
+	events := &publishContexts{PubSub: pubsub.NewInMem()}
+	service := NewService(storage.NewInMemStorage(), WithPubSub(events))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	author, err := service.CreateUser(ctx, model.NewUser{Handle: "author"})
+	require.NoError(t, err)
+	asAuthor := auth.WithPrincipal(ctx, auth.Principal{UserID: author.ID, Role: auth.RoleUser})
+	post, err := service.CreatePost(asAuthor, model.NewPost{Title: "Post", Commentable: true})
+	require.NoError(t, err)
+	postID := post.ID
+
+	comments, err := service.SubscribeCommentAdded(ctx, post.ID, nil)
+	require.NoError(t, err)
+
+	request, cancelRequest := context.WithCancel(asAuthor)
+	cancelRequest()
+	created, err := service.CreateComment(request, model.NewComment{Content: "Hello", PostID: &postID})
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, receive(t, comments).ID)
+	assert.Equal(t, []error{nil, nil}, events.errs, "events are published with a context detached from the request")
+}
+
+// TestService_CommentAddedHidden проверяет, что скрытые модератором комментарии не
+// раскрываются подписчикам ни при повторе, ни в живых событиях.
+func TestService_CommentAddedHidden(t *testing.T) {
+	events := pubsub.NewInMem()
+	service := NewService(storage.NewInMemStorage(), WithPubSub(events))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	author, err := service.CreateUser(ctx, model.NewUser{Handle: "author"})
+	require.NoError(t, err)
+	moderator, err := service.CreateUser(ctx, model.NewUser{Handle: "moderator"})
+	require.NoError(t, err)
+	asAuthor := auth.WithPrincipal(ctx, auth.Principal{UserID: author.ID, Role: auth.RoleUser})
+	asModerator := auth.WithPrincipal(ctx, auth.Principal{UserID: moderator.ID, Role: auth.RoleModerator})
+	post, err := service.CreatePost(asAuthor, model.NewPost{Title: "Post", Commentable: true})
+	require.NoError(t, err)
+	postID := post.ID
+
+	var created []*model.Comment
+	for _, content := range []string{"First", "Spam", "Third"} {
+		c, err := service.CreateComment(asAuthor, model.NewComment{Content: content, PostID: &postID})
+		require.NoError(t, err)
+		created = append(created, c)
+	}
+	_, err = service.HideComment(asModerator, created[1].ID, nil)
+	require.NoError(t, err)
+
+	since := created[0].Cursor(model.CommentSortOld).Encode()
+	comments, err := service.SubscribeCommentAdded(ctx, post.ID, &since)
+	require.NoError(t, err)
+	assert.Equal(t, created[2].ID, receive(t, comments).ID, "hidden comment is not replayed")
+
+	// Комментарий скрыли, пока событие о нём шло к подписчику.
+	payload, err := json.Marshal(commentEvent{ID: created[1].ID, PostID: post.ID})
+	require.NoError(t, err)
+	require.NoError(t, events.Publish(ctx, topicCommentAdded, payload))
+	hidden := receive(t, comments)
+	assert.Equal(t, created[1].ID, hidden.ID)
+	assert.Equal(t, model.HiddenContent, hidden.Content)
+	assert.Equal(t, uuid.Nil, hidden.AuthorID)
+}
+
+func TestService_ReplyAdded(t *testing.T) {
+	service := NewService(storage.NewInMemStorage())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	author, err := service.CreateUser(ctx, model.NewUser{Handle: "author"})
+	require.NoError(t, err)
+	asAuthor := auth.WithPrincipal(ctx, auth.Principal{UserID: author.ID, Role: auth.RoleUser})
+	post, err := service.CreatePost(asAuthor, model.NewPost{Title: "Post", Commentable: true})
+	require.NoError(t, err)
+	postID := post.ID
+
+	thread, err := service.CreateComment(asAuthor, model.NewComment{Content: "My thread", PostID: &postID})
+	require.NoError(t, err)
+	busy, err := service.CreateComment(asAuthor, model.NewComment{Content: "Busy thread", PostID: &postID})
+	require.NoError(t, err)
+
+	maxDepth := 2
+	replies, err := service.SubscribeReplyAdded(ctx, thread.ID, &maxDepth)
+	require.NoError(t, err)
+
+	reply := func(parentID uuid.UUID, content string) *model.Comment {
+		t.Helper()
+		c, err := service.CreateComment(asAuthor, model.NewComment{Content: content, CommentID: &parentID})
+		require.NoError(t, err)
+		return c
+	}
+	receive := func() *model.Comment {
+		t.Helper()
+		select {
+		case c := <-replies:
+			return c
+		case <-time.After(time.Second):
+			t.Fatal("no reply received")
+			return nil
+		}
+	}
+
+	_, err = service.CreateComment(asAuthor, model.NewComment{Content: "Root", PostID: &postID})
+	require.NoError(t, err)
+	reply(busy.ID, "Elsewhere")
+	first := reply(thread.ID, "Depth 1")
+	assert.Equal(t, first.ID, receive().ID)
+
+	second := reply(first.ID, "Depth 2")
+	assert.Equal(t, second.ID, receive().ID)
+
+	reply(second.ID, "Depth 3")
+	last := reply(first.ID, "Depth 2 again")
+	assert.Equal(t, last.ID, receive().ID, "replies deeper than maxDepth are skipped")
+
+	t.Run("hidden reply", func(t *testing.T) {
+		moderator, err := service.CreateUser(ctx, model.NewUser{Handle: "moderator"})
+		require.NoError(t, err)
+		asModerator := auth.WithPrincipal(ctx, auth.Principal{UserID: moderator.ID, Role: auth.RoleModerator})
+		_, err = service.HideComment(asModerator, last.ID, nil)
+		require.NoError(t, err)
+
+		// Ответ скрыли, пока событие о нём шло к подписчику.
+		payload, err := json.Marshal(commentEvent{ID: last.ID, PostID: postID, ParentID: &first.ID})
+		require.NoError(t, err)
+		require.NoError(t, service.pubsub.Publish(ctx, topicCommentAdded, payload))
+		hidden := receive()
+		assert.Equal(t, last.ID, hidden.ID)
+		assert.Equal(t, model.HiddenContent, hidden.Content)
+		assert.Equal(t, uuid.Nil, hidden.AuthorID)
+	})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		zero := 0
+		_, err := service.SubscribeReplyAdded(ctx, thread.ID, &zero)
+		assert.ErrorIs(t, err, storage.ErrBadRequest)
+		_, err = service.SubscribeReplyAdded(ctx, uuid.New(), nil)
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+}
+
+func TestService_PostSubscriptions(t *testing.T) {
+	service := NewService(storage.NewInMemStorage())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	alice, err := service.CreateUser(ctx, model.NewUser{Handle: "alice"})
+	require.NoError(t, err)
+	bob, err := service.CreateUser(ctx, model.NewUser{Handle: "bob"})
+	require.NoError(t, err)
+	asAlice := auth.WithPrincipal(ctx, auth.Principal{UserID: alice.ID, Role: auth.RoleUser})
+	asBob := auth.WithPrincipal(ctx, auth.Principal{UserID: bob.ID, Role: auth.RoleUser})
+
+	t.Run("postAdded filters by author and commentable", func(t *testing.T) {
+		commentable := true
+		posts, err := service.SubscribePostAdded(ctx, &model.PostFilter{AuthorID: &alice.ID, Commentable: &commentable})
+		require.NoError(t, err)
+		all, err := service.SubscribePostAdded(ctx, nil)
+		require.NoError(t, err)
+
+		_, err = service.CreatePost(asBob, model.NewPost{Title: "Bob's", Commentable: true})
+		require.NoError(t, err)
+		_, err = service.CreatePost(asAlice, model.NewPost{Title: "Closed"})
+		require.NoError(t, err)
+		open, err := service.CreatePost(asAlice, model.NewPost{Title: "Open", Commentable: true})
+		require.NoError(t, err)
+
+		assert.Equal(t, open.ID, receive(t, posts).ID)
+		for _, title := range []string{"Bob's", "Closed", "Open"} {
+			assert.Equal(t, title, receive(t, all).Title, "no filter receives every post")
+		}
+	})
+
+	t.Run("postUpdated", func(t *testing.T) {
+		post, err := service.CreatePost(asAlice, model.NewPost{Title: "Post", Commentable: true})
+		require.NoError(t, err)
+		other, err := service.CreatePost(asAlice, model.NewPost{Title: "Other", Commentable: true})
+		require.NoError(t, err)
+		updates, err := service.SubscribePostUpdated(ctx, post.ID)
+		require.NoError(t, err)
+
+		title := "Edited"
+		_, err = service.UpdatePost(asAlice, other.ID, model.UpdatePost{Title: &title})
+		require.NoError(t, err)
+		_, err = service.UpdatePost(asAlice, post.ID, model.UpdatePost{Title: &title})
+		require.NoError(t, err)
+		edited, ok := receive(t, updates).(*model.PostEdited)
+		require.True(t, ok)
+		assert.Equal(t, "Edited", edited.Post.Title)
+
+		reason := "off-topic"
+		_, err = service.SetCommentable(asAlice, post.ID, false, &reason)
+		require.NoError(t, err)
+		locked, ok := receive(t, updates).(*model.PostLocked)
+		require.True(t, ok)
+		assert.False(t, locked.Post.Commentable)
+
+		_, err = service.SetCommentable(asAlice, post.ID, true, nil)
+		require.NoError(t, err)
+		assert.IsType(t, &model.PostUnlocked{}, receive(t, updates))
+
+		closed := false
+		_, err = service.UpdatePost(asAlice, post.ID, model.UpdatePost{Commentable: &closed})
+		require.NoError(t, err)
+		assert.IsType(t, &model.PostLocked{}, receive(t, updates), "updatePost locking reports PostLocked, not PostEdited")
+		_, err = service.UpdatePost(asAlice, post.ID, model.UpdatePost{Title: &title, Commentable: &closed})
+		require.NoError(t, err)
+		assert.IsType(t, &model.PostEdited{}, receive(t, updates), "an unchanged commentable adds no lock event")
+
+		require.NoError(t, service.DeletePost(asAlice, post.ID))
+		deleted, ok := receive(t, updates).(*model.PostDeleted)
+		require.True(t, ok)
+		assert.Equal(t, post.ID, deleted.PostID)
+		_, open := <-updates
+		assert.False(t, open, "the subscription ends after deletion")
+
+		_, err = service.SubscribePostUpdated(ctx, post.ID)
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+}
+
+// receive ждёт значение из канала подписки не дольше секунды.
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(time.Second):
+		t.Fatal("nothing received")
+		var zero T
+		return zero
+	}
+}
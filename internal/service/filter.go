@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"graphql_project/internal/graph/model"
+
+	"github.com/google/uuid"
+)
+
+// ErrContentRejected возвращается, когда фильтр содержимого отклонил пост или комментарий.
+var ErrContentRejected = errors.New("content rejected")
+
+// ContentKind — вид проверяемого содержимого.
+type ContentKind string
+
+const (
+	ContentPost    ContentKind = "post"
+	ContentComment ContentKind = "comment"
+)
+
+// Content — пост или комментарий, который проверяют фильтры. Title задан только у поста.
+type Content struct {
+	AuthorID uuid.UUID
+	Kind     ContentKind
+	Title    string
+	Text     string
+}
+
+// ContentFilter проверяет содержимое перед публикацией. Фильтр возвращает nil, если
+// пропускает текст без замечаний, либо результат с действием: FLAG отмечает текст для
+// модераторов, REWRITE означает, что фильтр заменил content.Title или content.Text,
+// REJECT отклоняет публикацию.
+type ContentFilter interface {
+	Filter(ctx context.Context, content *Content) (*model.FilterResult, error)
+}
+
+// ContentRecorder реализуют фильтры, которые сравнивают содержимое с уже сохранённым.
+// Record вызывается только после успешного сохранения и получает итоговое содержимое.
+type ContentRecorder interface {
+	Record(ctx context.Context, content *Content)
+}
+
+// Option настраивает сервис при создании.
+type Option func(*Service)
+
+// WithContentFilters задаёт фильтры содержимого; они применяются по порядку, и каждый
+// следующий видит текст, переписанный предыдущими.
+func WithContentFilters(filters ...ContentFilter) Option {
+	return func(s *Service) {
+		s.filters = append(s.filters, filters...)
+	}
+}
+
+// filterContent прогоняет содержимое через фильтры, оставляя в content итоговый текст, и
+// возвращает сработавшие отметки. Первый отказ прерывает проверку с ErrContentRejected.
+func (s *Service) filterContent(ctx context.Context, content *Content) ([]*model.FilterResult, error) {
+	var results []*model.FilterResult
+	for _, filter := range s.filters {
+		result, err := filter.Filter(ctx, content)
+		if err != nil {
+			return nil, err
+		}
+		if result == nil {
+			continue
+		}
+		if result.Action == model.FilterActionReject {
+			return nil, fmt.Errorf("%w: %s", ErrContentRejected, result.Reason)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// recordContent передаёт сохранённое содержимое фильтрам, реализующим ContentRecorder.
+func (s *Service) recordContent(ctx context.Context, content *Content) {
+	for _, filter := range s.filters {
+		if recorder, ok := filter.(ContentRecorder); ok {
+			recorder.Record(ctx, content)
+		}
+	}
+}
+
+// GetPostFilterResults возвращает отметки фильтров содержимого, сохранённые при создании
+// и правках поста; доступно модераторам.
+func (s *Service) GetPostFilterResults(ctx context.Context, postID uuid.UUID) ([]*model.FilterResult, error) {
+	if _, err := requireModerator(ctx); err != nil {
+		return nil, err
+	}
+
+	results, err := s.storage.GetPostFilterResults(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// GetCommentFilterResults возвращает отметки фильтров содержимого, сохранённые при
+// создании и правках комментария; доступно модераторам.
+func (s *Service) GetCommentFilterResults(ctx context.Context, commentID uuid.UUID) ([]*model.FilterResult, error) {
+	if _, err := requireModerator(ctx); err != nil {
+		return nil, err
+	}
+
+	results, err := s.storage.GetCommentFilterResults(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"graphql_project/internal/graph/model"
+	"hash/fnv"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// wordPattern выделяет слова для сравнения со стоп-списком.
+	wordPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+	// linkPattern находит ссылки: с протоколом http(s) или начинающиеся с www.
+	linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+)
+
+// BlocklistFilter ищет в заголовке и тексте слова из стоп-списка. Список читается из файла — по слову
+// в строке, строки с «#» пропускаются, регистр не учитывается — и перечитывается, если
+// файл изменился, не чаще раза в reloadEvery. При REWRITE найденные слова заменяются
+// звёздочками.
+type BlocklistFilter struct {
+	path        string
+	action      model.FilterAction
+	reloadEvery time.Duration
+	now         func() time.Time
+
+	mu      sync.RWMutex
+	words   map[string]struct{}
+	modTime time.Time
+	checked time.Time
+}
+
+// NewBlocklistFilter загружает стоп-список из path; action — FLAG, REWRITE или REJECT.
+func NewBlocklistFilter(path string, action model.FilterAction, reloadEvery time.Duration) (*BlocklistFilter, error) {
+	if !action.IsValid() {
+		return nil, fmt.Errorf("invalid blocklist action %q", action)
+	}
+	f := &BlocklistFilter{path: path, action: action, reloadEvery: reloadEvery, now: time.Now}
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *BlocklistFilter) Filter(ctx context.Context, content *Content) (*model.FilterResult, error) {
+	f.reload()
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var found []string
+	rewrite := func(text string) string {
+		return wordPattern.ReplaceAllStringFunc(text, func(word string) string {
+			if _, ok := f.words[strings.ToLower(word)]; !ok {
+				return word
+			}
+			found = append(found, strings.ToLower(word))
+			return strings.Repeat("*", utf8.RuneCountInString(word))
+		})
+	}
+	title, text := rewrite(content.Title), rewrite(content.Text)
+	if len(found) == 0 {
+		return nil, nil
+	}
+
+	if f.action == model.FilterActionRewrite {
+		content.Title, content.Text = title, text
+	}
+	return &model.FilterResult{
+		Filter: "blocklist",
+		Action: f.action,
+		Reason: "blocked words: " + strings.Join(found, ", "),
+	}, nil
+}
+
+// reload перечитывает стоп-список, если с последней проверки прошло reloadEvery и файл
+// изменился. При ошибке чтения остаётся прежний список, а следующая попытка будет не
+// раньше чем через reloadEvery.
+func (f *BlocklistFilter) reload() {
+	f.mu.Lock()
+	now := f.now()
+	due := now.Sub(f.checked) >= f.reloadEvery
+	if due {
+		f.checked = now
+	}
+	f.mu.Unlock()
+	if !due {
+		return
+	}
+	if err := f.load(); err != nil {
+		log.Printf("Blocklist reload failed: %v", err)
+	}
+}
+
+func (f *BlocklistFilter) load() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return fmt.Errorf("reading blocklist: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.checked = f.now()
+	if f.words != nil && info.ModTime().Equal(f.modTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("reading blocklist: %w", err)
+	}
+	words := make(map[string]struct{})
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		word := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if word != "" && !strings.HasPrefix(word, "#") {
+			words[word] = struct{}{}
+		}
+	}
+	f.words, f.modTime = words, info.ModTime()
+	return nil
+}
+
+// LinkLimitFilter отклоняет содержимое, в заголовке и тексте которого вместе больше max ссылок.
+type LinkLimitFilter struct {
+	max int
+}
+
+func NewLinkLimitFilter(max int) *LinkLimitFilter {
+	return &LinkLimitFilter{max: max}
+}
+
+func (f *LinkLimitFilter) Filter(ctx context.Context, content *Content) (*model.FilterResult, error) {
+	links := len(linkPattern.FindAllStringIndex(content.Title, -1)) + len(linkPattern.FindAllStringIndex(content.Text, -1))
+	if links <= f.max {
+		return nil, nil
+	}
+	return &model.FilterResult{
+		Filter: "links",
+		Action: model.FilterActionReject,
+		Reason: fmt.Sprintf("too many links: %d, at most %d allowed", links, f.max),
+	}, nil
+}
+
+// RepeatFilter отмечает содержимое, которое автор уже сохранял в течение window. Заголовок
+// и текст сравниваются вместе, без учёта регистра и пробелов; хранятся только их хеши.
+type RepeatFilter struct {
+	window time.Duration
+	now    func() time.Time
+
+	mu     sync.Mutex
+	recent map[uuid.UUID][]repeatEntry
+	swept  time.Time
+}
+
+type repeatEntry struct {
+	hash uint64
+	at   time.Time
+}
+
+func NewRepeatFilter(window time.Duration) *RepeatFilter {
+	return &RepeatFilter{window: window, now: time.Now, recent: make(map[uuid.UUID][]repeatEntry)}
+}
+
+func (f *RepeatFilter) Filter(ctx context.Context, content *Content) (*model.FilterResult, error) {
+	hash := repeatHash(content)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, e := range f.prune(content.AuthorID, f.now()) {
+		if e.hash == hash {
+			return &model.FilterResult{
+				Filter: "repeat",
+				Action: model.FilterActionFlag,
+				Reason: "the author has recently published the same text",
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// Record запоминает сохранённое содержимое автора.
+func (f *RepeatFilter) Record(ctx context.Context, content *Content) {
+	hash := repeatHash(content)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.now()
+	f.recent[content.AuthorID] = append(f.prune(content.AuthorID, now), repeatEntry{hash: hash, at: now})
+	// Записи авторов, которые больше не публикуют, забываем полным проходом не чаще раза
+	// в window, чтобы карта не росла бесконечно.
+	if now.Sub(f.swept) >= f.window {
+		for author := range f.recent {
+			f.prune(author, now)
+		}
+		f.swept = now
+	}
+}
+
+// prune убирает устаревшие записи автора и возвращает оставшиеся; вызывается под f.mu.
+func (f *RepeatFilter) prune(author uuid.UUID, now time.Time) []repeatEntry {
+	entries := f.recent[author]
+	kept := entries[:0]
+	for _, e := range entries {
+		if now.Sub(e.at) < f.window {
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) == 0 {
+		delete(f.recent, author)
+		return nil
+	}
+	f.recent[author] = kept
+	return kept
+}
+
+// repeatHash хеширует заголовок и текст, приведённые к нижнему регистру, с пробелами,
+// схлопнутыми в один.
+func repeatHash(content *Content) uint64 {
+	h := fnv.New64a()
+	for _, s := range []string{content.Title, content.Text} {
+		h.Write([]byte(strings.ToLower(strings.Join(strings.Fields(s), " "))))
+		h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
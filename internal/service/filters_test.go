@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"graphql_project/internal/auth"
+	"graphql_project/internal/graph/model"
+	"graphql_project/internal/storage"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlocklistFilter(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# spam words\nCasino\n\npills\n"), 0o644))
+
+	filter, err := NewBlocklistFilter(path, model.FilterActionRewrite, time.Minute)
+	require.NoError(t, err)
+	now := time.Now()
+	filter.now = func() time.Time { return now }
+
+	t.Run("rewrite", func(t *testing.T) {
+		content := &Content{Text: "Best casino, cheap Pills!"}
+		result, err := filter.Filter(ctx, content)
+		require.NoError(t, err)
+		require.NotNil(t, result)
+		assert.Equal(t, model.FilterActionRewrite, result.Action)
+		assert.Equal(t, "blocked words: casino, pills", result.Reason)
+		assert.Equal(t, "Best ******, cheap *****!", content.Text)
+	})
+
+	t.Run("clean text", func(t *testing.T) {
+		result, err := filter.Filter(ctx, &Content{Text: "casinos are not blocked"})
+		require.NoError(t, err)
+		assert.Nil(t, result)
+	})
+
+	t.Run("hot reload", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte("casinos\n"), 0o644))
+		modTime := now.Add(time.Second)
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+
+		result, err := filter.Filter(ctx, &Content{Text: "casinos"})
+		require.NoError(t, err)
+		assert.Nil(t, result, "the file is not re-read before the reload interval")
+
+		now = now.Add(time.Minute)
+		result, err = filter.Filter(ctx, &Content{Text: "casinos"})
+		require.NoError(t, err)
+		require.NotNil(t, result)
+		result, err = filter.Filter(ctx, &Content{Text: "casino"})
+		require.NoError(t, err)
+		assert.Nil(t, result, "words removed from the file are no longer blocked")
+	})
+
+	t.Run("missing file keeps the list", func(t *testing.T) {
+		require.NoError(t, os.Remove(path))
+		now = now.Add(time.Minute)
+		result, err := filter.Filter(ctx, &Content{Text: "casinos"})
+		require.NoError(t, err)
+		assert.NotNil(t, result)
+		assert.Equal(t, now, filter.checked, "a failed reload waits for the next interval")
+	})
+
+	t.Run("title", func(t *testing.T) {
+		content := &Content{Title: "Casinos", Text: "text"}
+		result, err := filter.Filter(ctx, content)
+		require.NoError(t, err)
+		require.NotNil(t, result)
+		assert.Equal(t, "*******", content.Title)
+	})
+
+	t.Run("invalid action", func(t *testing.T) {
+		_, err := NewBlocklistFilter(path, "DROP", time.Minute)
+		assert.Error(t, err)
+	})
+}
+
+func TestLinkLimitFilter(t *testing.T) {
+	filter := NewLinkLimitFilter(2)
+
+	result, err := filter.Filter(context.Background(), &Content{Text: "see https://a.example and www.b.example"})
+	require.NoError(t, err)
+	assert.Nil(t, result)
+
+	result, err = filter.Filter(context.Background(), &Content{Text: "http://a.example http://b.example HTTPS://c.example"})
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.Equal(t, model.FilterActionReject, result.Action)
+}
+
+func TestRepeatFilter(t *testing.T) {
+	ctx := context.Background()
+	filter := NewRepeatFilter(10 * time.Minute)
+	now := time.Now()
+	filter.now = func() time.Time { return now }
+	author, other := uuid.New(), uuid.New()
+
+	result, err := filter.Filter(ctx, &Content{AuthorID: author, Text: "Buy now"})
+	require.NoError(t, err)
+	assert.Nil(t, result)
+	result, err = filter.Filter(ctx, &Content{AuthorID: author, Text: "Buy now"})
+	require.NoError(t, err)
+	assert.Nil(t, result, "unsaved content is not remembered")
+
+	filter.Record(ctx, &Content{AuthorID: author, Text: "Buy now"})
+	result, err = filter.Filter(ctx, &Content{AuthorID: other, Text: "Buy now"})
+	require.NoError(t, err)
+	assert.Nil(t, result, "other authors may repeat the text")
+
+	result, err = filter.Filter(ctx, &Content{AuthorID: author, Text: "  buy   NOW "})
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.Equal(t, model.FilterActionFlag, result.Action)
+
+	filter.Record(ctx, &Content{AuthorID: author, Title: "Deal", Text: "Buy now"})
+	result, err = filter.Filter(ctx, &Content{AuthorID: author, Title: "Deal", Text: "Sell now"})
+	require.NoError(t, err)
+	assert.Nil(t, result, "title and text are compared together")
+	result, err = filter.Filter(ctx, &Content{AuthorID: author, Title: "Deal buy", Text: "now"})
+	require.NoError(t, err)
+	assert.Nil(t, result, "text does not run into the title")
+
+	now = now.Add(11 * time.Minute)
+	result, err = filter.Filter(ctx, &Content{AuthorID: author, Text: "Buy now"})
+	require.NoError(t, err)
+	assert.Nil(t, result, "the window has passed")
+
+	filter.Record(ctx, &Content{AuthorID: other, Text: "Hello"})
+	assert.NotContains(t, filter.recent, author, "stale authors are swept")
+}
+
+func TestService_ContentFilters(t *testing.T) {
+	authorID := uuid.New()
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: authorID})
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	require.NoError(t, os.WriteFile(path, []byte("darn\n"), 0o644))
+	blocklist, err := NewBlocklistFilter(path, model.FilterActionRewrite, time.Minute)
+	require.NoError(t, err)
+
+	mockStorage := new(MockStorage)
+	service := NewService(mockStorage, WithContentFilters(blocklist, NewRepeatFilter(time.Minute), NewLinkLimitFilter(1)))
+	postID := uuid.New()
+
+	t.Run("rewrite and flag are saved with the comment", func(t *testing.T) {
+		mockStorage.On("CreateComment", ctx, mock.MatchedBy(func(c model.NewComment) bool {
+			return c.Content == "well ****" && len(c.FilterResults) == 1
+		})).
+			Return(&model.Comment{ID: uuid.New()}, nil).
+			Once()
+		_, err := service.CreateComment(ctx, model.NewComment{Content: "well darn", PostID: &postID})
+		require.NoError(t, err)
+
+		mockStorage.On("CreateComment", ctx, mock.MatchedBy(func(c model.NewComment) bool {
+			return len(c.FilterResults) == 2 && c.FilterResults[1].Action == model.FilterActionFlag
+		})).
+			Return(&model.Comment{ID: uuid.New()}, nil).
+			Once()
+		_, err = service.CreateComment(ctx, model.NewComment{Content: "well darn", PostID: &postID})
+		require.NoError(t, err)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		_, err := service.CreatePost(ctx, model.NewPost{Title: "Links", Content: "http://a.example http://b.example"})
+		assert.ErrorIs(t, err, ErrContentRejected)
+		_, err = service.CreateComment(ctx, model.NewComment{Content: "http://a.example http://b.example", PostID: &postID})
+		assert.ErrorIs(t, err, ErrContentRejected)
+		mockStorage.AssertNotCalled(t, "CreatePost", mock.Anything, mock.Anything)
+	})
+
+	t.Run("posts and edits are filtered", func(t *testing.T) {
+		mockStorage.On("CreatePost", ctx, mock.MatchedBy(func(p model.NewPost) bool {
+			return p.Title == "Oh ****" && p.Content == "**** again" && len(p.FilterResults) == 1
+		})).
+			Return(&model.Post{ID: postID, AuthorID: authorID}, nil).
+			Once()
+		_, err := service.CreatePost(ctx, model.NewPost{Title: "Oh darn", Content: "darn again"})
+		require.NoError(t, err)
+
+		mockStorage.On("GetPostByID", ctx, postID.String()).Return(&model.Post{ID: postID, AuthorID: authorID, Title: "Oh ****", Content: "**** again"}, nil).Once()
+		mockStorage.On("UpdatePost", ctx, postID, mock.MatchedBy(func(u model.UpdatePost) bool {
+			return *u.Title == "**** title" && u.Content == nil && len(u.FilterResults) == 1
+		})).
+			Return(&model.Post{ID: postID, AuthorID: authorID}, nil).
+			Once()
+		title := "Darn title"
+		_, err = service.UpdatePost(ctx, postID, model.UpdatePost{Title: &title})
+		require.NoError(t, err)
+		assert.Equal(t, "Darn title", title, "caller's input is not rewritten")
+
+		commentID := uuid.New()
+		mockStorage.On("GetCommentByID", ctx, commentID).Return(&model.Comment{ID: commentID, AuthorID: authorID}, nil)
+		mockStorage.On("EditComment", ctx, commentID, "**** edit", mock.MatchedBy(func(r []*model.FilterResult) bool {
+			return len(r) == 1 && r[0].Action == model.FilterActionRewrite
+		})).
+			Return(&model.Comment{ID: commentID}, nil).
+			Once()
+		_, err = service.EditComment(ctx, commentID, model.EditComment{Content: "darn edit"})
+		require.NoError(t, err)
+
+		_, err = service.EditComment(ctx, commentID, model.EditComment{Content: "http://a.example http://b.example"})
+		assert.ErrorIs(t, err, ErrContentRejected)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("failed saves are not remembered", func(t *testing.T) {
+		mockStorage.On("CreateComment", ctx, mock.MatchedBy(func(c model.NewComment) bool {
+			return c.Content == "first try"
+		})).
+			Return((*model.Comment)(nil), storage.ErrNotCommentable).
+			Once()
+		_, err := service.CreateComment(ctx, model.NewComment{Content: "first try", PostID: &postID})
+		require.ErrorIs(t, err, storage.ErrNotCommentable)
+
+		mockStorage.On("CreateComment", ctx, mock.MatchedBy(func(c model.NewComment) bool {
+			return c.Content == "first try" && len(c.FilterResults) == 0
+		})).
+			Return(&model.Comment{ID: uuid.New()}, nil).
+			Once()
+		_, err = service.CreateComment(ctx, model.NewComment{Content: "first try", PostID: &postID})
+		require.NoError(t, err)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("filter results are visible to moderators", func(t *testing.T) {
+		commentID := uuid.New()
+		moderator := auth.WithPrincipal(context.Background(), auth.Principal{UserID: uuid.New(), Role: auth.RoleModerator})
+		mockStorage.On("GetCommentFilterResults", moderator, commentID).
+			Return([]*model.FilterResult{{Filter: "repeat", Action: model.FilterActionFlag}}, nil).
+			Once()
+
+		results, err := service.GetCommentFilterResults(moderator, commentID)
+		require.NoError(t, err)
+		assert.Len(t, results, 1)
+
+		_, err = service.GetCommentFilterResults(ctx, commentID)
+		assert.ErrorIs(t, err, ErrForbidden)
+
+		mockStorage.On("GetPostFilterResults", moderator, postID).
+			Return([]*model.FilterResult{{Filter: "blocklist", Action: model.FilterActionRewrite}}, nil).
+			Once()
+		results, err = service.GetPostFilterResults(moderator, postID)
+		require.NoError(t, err)
+		assert.Len(t, results, 1)
+
+		_, err = service.GetPostFilterResults(ctx, postID)
+		assert.ErrorIs(t, err, ErrForbidden)
+	})
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"graphql_project/internal/graph/model"
+	"graphql_project/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+// HideComment скрывает комментарий: он остаётся в дереве, но без текста и автора.
+// Доступно модераторам; действие попадает в журнал модерации.
+func (s *Service) HideComment(ctx context.Context, id uuid.UUID, reason *string) (*model.Comment, error) {
+	return s.setCommentHidden(ctx, id, true, reason)
+}
+
+// RestoreComment возвращает скрытый комментарий в выдачу; доступно модераторам.
+func (s *Service) RestoreComment(ctx context.Context, id uuid.UUID) (*model.Comment, error) {
+	return s.setCommentHidden(ctx, id, false, nil)
+}
+
+func (s *Service) setCommentHidden(ctx context.Context, id uuid.UUID, hidden bool, reason *string) (*model.Comment, error) {
+	principal, err := requireModerator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := s.storage.SetCommentHidden(ctx, id, hidden, principal.UserID, reason)
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// ApproveComment публикует комментарий к посту на премодерации; доступно модераторам.
+// Действие попадает в журнал модерации.
+func (s *Service) ApproveComment(ctx context.Context, id uuid.UUID) (*model.Comment, error) {
+	principal, err := requireModerator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := s.storage.ApproveComment(ctx, id, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+	s.publishCommentAdded(ctx, comment)
+	return comment, nil
+}
+
+// GetPendingComments возвращает комментарии, ожидающие премодерации, от старых к новым:
+// по всем постам или только по postID. Доступно модераторам.
+func (s *Service) GetPendingComments(ctx context.Context, postID *uuid.UUID, first *int, after *string, last *int, before *string) (*model.CommentConnection, error) {
+	if _, err := requireModerator(ctx); err != nil {
+		return nil, err
+	}
+	page, err := ParseCommentPageArgs(first, after, last, before, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.storage.GetPendingComments(ctx, postID, page)
+	if err != nil {
+		return nil, err
+	}
+	return commentConnection(result, page.Sort), nil
+}
+
+// BanAuthor запрещает пользователю создавать посты и комментарии. Доступно модераторам;
+// заблокировать самого себя нельзя.
+func (s *Service) BanAuthor(ctx context.Context, userID uuid.UUID, reason *string) (*model.User, error) {
+	return s.setUserBanned(ctx, userID, true, reason)
+}
+
+// UnbanAuthor снимает блокировку с пользователя; доступно модераторам.
+func (s *Service) UnbanAuthor(ctx context.Context, userID uuid.UUID) (*model.User, error) {
+	return s.setUserBanned(ctx, userID, false, nil)
+}
+
+func (s *Service) setUserBanned(ctx context.Context, userID uuid.UUID, banned bool, reason *string) (*model.User, error) {
+	principal, err := requireModerator(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if banned && userID == principal.UserID {
+		return nil, storage.ErrBadRequest
+	}
+
+	user, err := s.storage.SetUserBanned(ctx, userID, banned, principal.UserID, reason)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// GetModerationLog возвращает страницу журнала модерации, от новых записей к старым;
+// доступно модераторам.
+func (s *Service) GetModerationLog(ctx context.Context, first *int, after *string, last *int, before *string) (*model.ModerationLogConnection, error) {
+	if _, err := requireModerator(ctx); err != nil {
+		return nil, err
+	}
+	page, err := ParsePageArgs(first, after, last, before)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.storage.GetModerationLog(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+	return moderationLogConnection(result), nil
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"graphql_project/internal/auth"
+	"graphql_project/internal/graph/model"
+	"graphql_project/internal/storage"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Moderation(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := NewService(mockStorage)
+
+	moderatorID := uuid.New()
+	moderator := auth.WithPrincipal(context.Background(), auth.Principal{UserID: moderatorID, Role: auth.RoleModerator})
+	user := auth.WithPrincipal(context.Background(), auth.Principal{UserID: uuid.New(), Role: auth.RoleUser})
+	commentID, userID := uuid.New(), uuid.New()
+	reason := "spam"
+
+	t.Run("moderator hides comment", func(t *testing.T) {
+		mockStorage.On("SetCommentHidden", moderator, commentID, true, moderatorID, &reason).
+			Return(&model.Comment{ID: commentID}, nil).
+			Once()
+
+		_, err := service.HideComment(moderator, commentID, &reason)
+		require.NoError(t, err)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("moderator bans author", func(t *testing.T) {
+		mockStorage.On("SetUserBanned", moderator, userID, true, moderatorID, (*string)(nil)).
+			Return(&model.User{ID: userID}, nil).
+			Once()
+
+		_, err := service.BanAuthor(moderator, userID, nil)
+		require.NoError(t, err)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("moderator approves comment", func(t *testing.T) {
+		mockStorage.On("ApproveComment", moderator, commentID, moderatorID).
+			Return(&model.Comment{ID: commentID, Status: model.CommentStatusPublished}, nil).
+			Once()
+
+		comment, err := service.ApproveComment(moderator, commentID)
+		require.NoError(t, err)
+		assert.Equal(t, model.CommentStatusPublished, comment.Status)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("pending comments oldest first", func(t *testing.T) {
+		postID := uuid.New()
+		mockStorage.On("GetPendingComments", moderator, &postID, mock.MatchedBy(func(page model.PageArgs) bool {
+			return page.Sort == model.CommentSortOld
+		})).
+			Return(&model.CommentPage{Comments: []*model.Comment{{ID: commentID, Status: model.CommentStatusPending}}, TotalCount: 1}, nil).
+			Once()
+
+		conn, err := service.GetPendingComments(moderator, &postID, nil, nil, nil, nil)
+		require.NoError(t, err)
+		require.Len(t, conn.Edges, 1)
+		assert.Equal(t, commentID, conn.Edges[0].Node.ID)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("moderator cannot ban themselves", func(t *testing.T) {
+		_, err := service.BanAuthor(moderator, moderatorID, nil)
+		assert.ErrorIs(t, err, storage.ErrBadRequest)
+	})
+
+	t.Run("regular users are forbidden", func(t *testing.T) {
+		_, err := service.HideComment(user, commentID, nil)
+		assert.ErrorIs(t, err, ErrForbidden)
+		_, err = service.UnbanAuthor(user, userID)
+		assert.ErrorIs(t, err, ErrForbidden)
+		_, err = service.GetModerationLog(user, nil, nil, nil, nil)
+		assert.ErrorIs(t, err, ErrForbidden)
+		_, err = service.ApproveComment(user, commentID)
+		assert.ErrorIs(t, err, ErrForbidden)
+		_, err = service.GetPendingComments(user, nil, nil, nil, nil, nil)
+		assert.ErrorIs(t, err, ErrForbidden)
+
+		_, err = service.RestoreComment(context.Background(), commentID)
+		assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+		mockStorage.AssertNotCalled(t, "SetCommentHidden", user, commentID, true, mock.Anything, mock.Anything)
+	})
+}
+
+// TestService_BannedAuthor проверяет блокировку на настоящем хранилище: модератор
+// блокирует автора, после чего тот не может публиковать посты и комментарии.
+func TestService_BannedAuthor(t *testing.T) {
+	service := NewService(storage.NewInMemStorage())
+	ctx := context.Background()
+
+	author, err := service.CreateUser(ctx, model.NewUser{Handle: "author"})
+	require.NoError(t, err)
+	moderator, err := service.CreateUser(ctx, model.NewUser{Handle: "moderator"})
+	require.NoError(t, err)
+	asAuthor := auth.WithPrincipal(ctx, auth.Principal{UserID: author.ID, Role: auth.RoleUser})
+	asModerator := auth.WithPrincipal(ctx, auth.Principal{UserID: moderator.ID, Role: auth.RoleModerator})
+
+	post, err := service.CreatePost(asAuthor, model.NewPost{Title: "Post", Commentable: true})
+	require.NoError(t, err)
+	postID := post.ID
+
+	_, err = service.BanAuthor(asModerator, author.ID, nil)
+	require.NoError(t, err)
+
+	_, err = service.CreatePost(asAuthor, model.NewPost{Title: "Post"})
+	assert.ErrorIs(t, err, storage.ErrBanned)
+	_, err = service.CreateComment(asAuthor, model.NewComment{Content: "Comment", PostID: &postID})
+	assert.ErrorIs(t, err, storage.ErrBanned)
+
+	log, err := service.GetModerationLog(asModerator, nil, nil, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, log.Edges, 1)
+	assert.Equal(t, model.ModerationActionTypeBanAuthor, log.Edges[0].Node.Action)
+	assert.Equal(t, author.ID, log.Edges[0].Node.TargetID)
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"encoding/base64"
+	"graphql_project/internal/graph/model"
+	"graphql_project/internal/storage"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+// ParsePageArgs проверяет аргументы Relay-пагинации и разбирает курсоры.
+func ParsePageArgs(first *int, after *string, last *int, before *string) (model.PageArgs, error) {
+	var page model.PageArgs
+
+	if first != nil && last != nil {
+		return page, storage.ErrBadRequest
+	}
+	for _, size := range []*int{first, last} {
+		if size != nil && (*size < 0 || *size > maxPageSize) {
+			return page, storage.ErrBadRequest
+		}
+	}
+
+	if after != nil {
+		cursor, err := model.DecodeCursor(*after)
+		if err != nil {
+			return page, err
+		}
+		page.After = &cursor
+	}
+	if before != nil {
+		cursor, err := model.DecodeCursor(*before)
+		if err != nil {
+			return page, err
+		}
+		page.Before = &cursor
+	}
+
+	page.First, page.Last = first, last
+	if first == nil && last == nil {
+		size := defaultPageSize
+		page.First = &size
+	}
+	return page, nil
+}
+
+// ParseCommentPageArgs разбирает аргументы пагинации комментариев вместе с порядком сортировки.
+func ParseCommentPageArgs(first *int, after *string, last *int, before *string, sort *model.CommentSort) (model.PageArgs, error) {
+	page, err := ParsePageArgs(first, after, last, before)
+	if err != nil {
+		return page, err
+	}
+
+	page.Sort = model.CommentSortOld
+	if sort != nil {
+		if !sort.IsValid() {
+			return page, storage.ErrBadRequest
+		}
+		page.Sort = *sort
+	}
+	return page, nil
+}
+
+func pageInfo(hasNext, hasPrev bool, total int, start, end *model.Cursor) *model.PageInfo {
+	info := &model.PageInfo{
+		HasNextPage:     hasNext,
+		HasPreviousPage: hasPrev,
+		TotalCount:      int32(total),
+	}
+	if start != nil {
+		s := start.Encode()
+		info.StartCursor = &s
+	}
+	if end != nil {
+		e := end.Encode()
+		info.EndCursor = &e
+	}
+	return info
+}
+
+func postConnection(page *model.PostPage) *model.PostConnection {
+	edges := make([]*model.PostEdge, len(page.Posts))
+	for i, post := range page.Posts {
+		edges[i] = &model.PostEdge{Cursor: post.Cursor().Encode(), Node: post}
+	}
+
+	var start, end *model.Cursor
+	if len(page.Posts) > 0 {
+		first, last := page.Posts[0].Cursor(), page.Posts[len(page.Posts)-1].Cursor()
+		start, end = &first, &last
+	}
+
+	return &model.PostConnection{
+		Edges:    edges,
+		PageInfo: pageInfo(page.HasNextPage, page.HasPreviousPage, page.TotalCount, start, end),
+	}
+}
+
+func commentConnection(page *model.CommentPage, sort model.CommentSort) *model.CommentConnection {
+	edges := make([]*model.CommentEdge, len(page.Comments))
+	for i, comment := range page.Comments {
+		if comment.Redacted() {
+			comment = comment.Tombstone()
+		}
+		edges[i] = &model.CommentEdge{Cursor: comment.Cursor(sort).Encode(), Node: comment}
+	}
+
+	var start, end *model.Cursor
+	if len(page.Comments) > 0 {
+		first, last := page.Comments[0].Cursor(sort), page.Comments[len(page.Comments)-1].Cursor(sort)
+		start, end = &first, &last
+	}
+
+	return &model.CommentConnection{
+		Edges:    edges,
+		PageInfo: pageInfo(page.HasNextPage, page.HasPreviousPage, page.TotalCount, start, end),
+	}
+}
+
+func commentConnections(pages map[uuid.UUID]*model.CommentPage, sort model.CommentSort) map[uuid.UUID]*model.CommentConnection {
+	conns := make(map[uuid.UUID]*model.CommentConnection, len(pages))
+	for id, page := range pages {
+		conns[id] = commentConnection(page, sort)
+	}
+	return conns
+}
+
+func moderationLogConnection(page *model.ModerationLogPage) *model.ModerationLogConnection {
+	edges := make([]*model.ModerationActionEdge, len(page.Actions))
+	for i, action := range page.Actions {
+		edges[i] = &model.ModerationActionEdge{Cursor: action.Cursor().Encode(), Node: action}
+	}
+
+	var start, end *model.Cursor
+	if len(page.Actions) > 0 {
+		first, last := page.Actions[0].Cursor(), page.Actions[len(page.Actions)-1].Cursor()
+		start, end = &first, &last
+	}
+
+	return &model.ModerationLogConnection{
+		Edges:    edges,
+		PageInfo: pageInfo(page.HasNextPage, page.HasPreviousPage, page.TotalCount, start, end),
+	}
+}
+
+// Курсор результатов поиска — смещение следующего элемента: ранжирование не даёт
+// устойчивого ключа для keyset-пагинации.
+func encodeOffsetCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+func decodeOffsetCursor(s string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, model.ErrInvalidCursor
+	}
+	n, ok := strings.CutPrefix(string(raw), "offset:")
+	if !ok {
+		return 0, model.ErrInvalidCursor
+	}
+	offset, err := strconv.Atoi(n)
+	if err != nil || offset < 0 {
+		return 0, model.ErrInvalidCursor
+	}
+	return offset, nil
+}
+
+func searchConnection(page *model.SearchPage, offset int) *model.SearchConnection {
+	edges := make([]*model.SearchEdge, len(page.Hits))
+	for i, hit := range page.Hits {
+		edges[i] = &model.SearchEdge{
+			Cursor:  encodeOffsetCursor(offset + i + 1),
+			Node:    hit.Node,
+			Snippet: hit.Snippet,
+			Rank:    hit.Rank,
+		}
+	}
+
+	info := &model.PageInfo{
+		HasNextPage:     offset+len(edges) < page.TotalCount,
+		HasPreviousPage: offset > 0,
+		TotalCount:      int32(page.TotalCount),
+	}
+	if len(edges) > 0 {
+		info.StartCursor = &edges[0].Cursor
+		info.EndCursor = &edges[len(edges)-1].Cursor
+	}
+	return &model.SearchConnection{Edges: edges, PageInfo: info}
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"graphql_project/internal/auth"
+	"graphql_project/internal/graph/model"
+
+	"github.com/google/uuid"
+)
+
+// ErrForbidden возвращается, когда пользователь запроса не вправе изменять объект.
+var ErrForbidden = errors.New("forbidden")
+
+// canModify — политика владения: изменять пост или комментарий может его автор или модератор.
+func canModify(principal auth.Principal, authorID uuid.UUID) bool {
+	return principal.UserID == authorID || principal.IsModerator()
+}
+
+// requireModerator возвращает пользователя запроса, если он модератор, иначе ErrForbidden.
+func requireModerator(ctx context.Context) (auth.Principal, error) {
+	principal, err := auth.Require(ctx)
+	if err != nil {
+		return auth.Principal{}, err
+	}
+	if !principal.IsModerator() {
+		return auth.Principal{}, ErrForbidden
+	}
+	return principal, nil
+}
+
+// authorizePost проверяет, что пользователь запроса может изменять пост postID,
+// и возвращает пост.
+func (s *Service) authorizePost(ctx context.Context, postID uuid.UUID) (*model.Post, error) {
+	principal, err := auth.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	post, err := s.storage.GetPostByID(ctx, postID.String())
+	if err != nil {
+		return nil, err
+	}
+	if !canModify(principal, post.AuthorID) {
+		return nil, ErrForbidden
+	}
+	return post, nil
+}
+
+// authorizeComment проверяет, что пользователь запроса может изменять комментарий commentID,
+// и возвращает комментарий.
+func (s *Service) authorizeComment(ctx context.Context, commentID uuid.UUID) (*model.Comment, error) {
+	principal, err := auth.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := s.storage.GetCommentByID(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if !canModify(principal, comment.AuthorID) {
+		return nil, ErrForbidden
+	}
+	return comment, nil
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"graphql_project/internal/auth"
+	"graphql_project/internal/graph/model"
+	"graphql_project/internal/storage"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Ownership(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := NewService(mockStorage)
+
+	authorID := uuid.New()
+	post := &model.Post{ID: uuid.New(), AuthorID: authorID}
+	comment := &model.Comment{ID: uuid.New(), AuthorID: authorID}
+
+	as := func(userID uuid.UUID, role auth.Role) context.Context {
+		return auth.WithPrincipal(context.Background(), auth.Principal{UserID: userID, Role: role})
+	}
+	author := as(authorID, auth.RoleUser)
+	stranger := as(uuid.New(), auth.RoleUser)
+	moderator := as(uuid.New(), auth.RoleModerator)
+	anonymous := context.Background()
+
+	mockStorage.On("GetPostByID", mock.Anything, post.ID.String()).Return(post, nil)
+	mockStorage.On("GetCommentByID", mock.Anything, comment.ID).Return(comment, nil)
+
+	t.Run("author and moderator may modify", func(t *testing.T) {
+		for _, ctx := range []context.Context{author, moderator} {
+			mockStorage.On("DeletePost", ctx, post.ID).Return(nil).Once()
+			mockStorage.On("DeleteComment", ctx, comment.ID).Return(nil).Once()
+
+			require.NoError(t, service.DeletePost(ctx, post.ID))
+			require.NoError(t, service.DeleteComment(ctx, comment.ID))
+		}
+		mockStorage.AssertNumberOfCalls(t, "DeletePost", 2)
+		mockStorage.AssertNumberOfCalls(t, "DeleteComment", 2)
+	})
+
+	t.Run("other users are forbidden", func(t *testing.T) {
+		title := "Hijacked"
+		_, err := service.UpdatePost(stranger, post.ID, model.UpdatePost{Title: &title})
+		assert.ErrorIs(t, err, ErrForbidden)
+		_, err = service.SetCommentable(stranger, post.ID, false, nil)
+		assert.ErrorIs(t, err, ErrForbidden)
+		assert.ErrorIs(t, service.DeletePost(stranger, post.ID), ErrForbidden)
+
+		_, err = service.EditComment(stranger, comment.ID, model.EditComment{Content: "Hijacked"})
+		assert.ErrorIs(t, err, ErrForbidden)
+		assert.ErrorIs(t, service.DeleteComment(stranger, comment.ID), ErrForbidden)
+
+		mockStorage.AssertNotCalled(t, "UpdatePost", stranger, post.ID, model.UpdatePost{Title: &title})
+		mockStorage.AssertNotCalled(t, "DeleteComment", stranger, comment.ID)
+	})
+
+	t.Run("anonymous", func(t *testing.T) {
+		assert.ErrorIs(t, service.DeletePost(anonymous, post.ID), auth.ErrUnauthenticated)
+		assert.ErrorIs(t, service.DeleteComment(anonymous, comment.ID), auth.ErrUnauthenticated)
+	})
+
+	t.Run("hidden comment", func(t *testing.T) {
+		hiddenAt := time.Now()
+		hidden := &model.Comment{ID: uuid.New(), AuthorID: authorID, HiddenAt: &hiddenAt}
+		mockStorage.On("GetCommentByID", mock.Anything, hidden.ID).Return(hidden, nil)
+
+		for _, ctx := range []context.Context{author, moderator} {
+			_, err := service.EditComment(ctx, hidden.ID, model.EditComment{Content: "Edited"})
+			assert.ErrorIs(t, err, storage.ErrHidden)
+		}
+		mockStorage.AssertNotCalled(t, "EditComment", mock.Anything, hidden.ID, "Edited", mock.Anything)
+	})
+
+	t.Run("missing target", func(t *testing.T) {
+		missing := uuid.New()
+		mockStorage.On("GetCommentByID", author, missing).Return((*model.Comment)(nil), storage.ErrNotFound).Once()
+
+		assert.ErrorIs(t, service.DeleteComment(author, missing), storage.ErrNotFound)
+	})
+}
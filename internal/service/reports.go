@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"graphql_project/internal/auth"
+	"graphql_project/internal/graph/model"
+	"graphql_project/internal/storage"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxReportReason = 500
+	maxReportQueue  = 100
+)
+
+// ReportContent принимает жалобу пользователя запроса на пост или комментарий.
+func (s *Service) ReportContent(ctx context.Context, newReport model.NewReport) (*model.Report, error) {
+	principal, err := auth.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+	newReport.Reason = strings.TrimSpace(newReport.Reason)
+	if err := reasonConstraint.Check("reason", newReport.Reason); err != nil {
+		return nil, err
+	}
+	if !newReport.TargetType.IsValid() {
+		return nil, storage.ErrBadRequest
+	}
+	newReport.ReporterID = principal.UserID
+
+	report, err := s.storage.CreateReport(ctx, newReport)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// GetReportQueue возвращает объекты с открытыми жалобами, самые обсуждаемые первыми;
+// доступно модераторам.
+func (s *Service) GetReportQueue(ctx context.Context, offset, limit *int) ([]*model.ReportQueueItem, error) {
+	if _, err := requireModerator(ctx); err != nil {
+		return nil, err
+	}
+
+	off, lim := 0, defaultPageSize
+	if offset != nil {
+		off = *offset
+	}
+	if limit != nil {
+		lim = *limit
+	}
+	if off < 0 || lim < 0 || lim > maxReportQueue {
+		return nil, storage.ErrBadRequest
+	}
+
+	items, err := s.storage.GetReportQueue(ctx, lim, off)
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// ResolveReport применяет решение модератора к объекту и закрывает все открытые жалобы
+// на него. HIDE допустим только для комментариев; DELETE удаляет пост или комментарий.
+func (s *Service) ResolveReport(ctx context.Context, targetType model.ReportTargetType, targetID uuid.UUID, action model.ReportAction) ([]*model.Report, error) {
+	principal, err := requireModerator(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !targetType.IsValid() || !action.IsValid() {
+		return nil, storage.ErrBadRequest
+	}
+
+	switch action {
+	case model.ReportActionHide:
+		if targetType != model.ReportTargetTypeComment {
+			return nil, storage.ErrBadRequest
+		}
+		if _, err := s.HideComment(ctx, targetID, nil); err != nil {
+			return nil, err
+		}
+	case model.ReportActionDelete:
+		if targetType == model.ReportTargetTypePost {
+			err = s.DeletePost(ctx, targetID)
+		} else {
+			err = s.DeleteComment(ctx, targetID)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	reports, err := s.storage.ResolveReports(ctx, targetType, targetID, principal.UserID, action)
+	if err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"graphql_project/internal/auth"
+	"graphql_project/internal/graph/model"
+	"graphql_project/internal/storage"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Reports(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := NewService(mockStorage)
+
+	reporterID, moderatorID := uuid.New(), uuid.New()
+	reporter := auth.WithPrincipal(context.Background(), auth.Principal{UserID: reporterID, Role: auth.RoleUser})
+	moderator := auth.WithPrincipal(context.Background(), auth.Principal{UserID: moderatorID, Role: auth.RoleModerator})
+	commentID, postID := uuid.New(), uuid.New()
+
+	t.Run("report content", func(t *testing.T) {
+		expected := model.NewReport{TargetType: model.ReportTargetTypeComment, TargetID: commentID, Reason: "spam", ReporterID: reporterID}
+		mockStorage.On("CreateReport", reporter, expected).
+			Return(&model.Report{ID: uuid.New()}, nil).
+			Once()
+
+		_, err := service.ReportContent(reporter, model.NewReport{TargetType: model.ReportTargetTypeComment, TargetID: commentID, Reason: "  spam "})
+		require.NoError(t, err)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("invalid reason", func(t *testing.T) {
+		for _, reason := range []string{"", "   ", strings.Repeat("x", maxReportReason+1)} {
+			_, err := service.ReportContent(reporter, model.NewReport{TargetType: model.ReportTargetTypePost, TargetID: postID, Reason: reason})
+			assert.ErrorIs(t, err, storage.ErrBadRequest)
+		}
+		_, err := service.ReportContent(context.Background(), model.NewReport{TargetType: model.ReportTargetTypePost, TargetID: postID, Reason: "spam"})
+		assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+	})
+
+	t.Run("queue is for moderators", func(t *testing.T) {
+		_, err := service.GetReportQueue(reporter, nil, nil)
+		assert.ErrorIs(t, err, ErrForbidden)
+
+		mockStorage.On("GetReportQueue", moderator, defaultPageSize, 0).
+			Return([]*model.ReportQueueItem{}, nil).
+			Once()
+		_, err = service.GetReportQueue(moderator, nil, nil)
+		require.NoError(t, err)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("resolve by hiding comment", func(t *testing.T) {
+		mockStorage.On("SetCommentHidden", moderator, commentID, true, moderatorID, (*string)(nil)).
+			Return(&model.Comment{ID: commentID}, nil).
+			Once()
+		mockStorage.On("ResolveReports", moderator, model.ReportTargetTypeComment, commentID, moderatorID, model.ReportActionHide).
+			Return([]*model.Report{{ID: uuid.New()}}, nil).
+			Once()
+
+		reports, err := service.ResolveReport(moderator, model.ReportTargetTypeComment, commentID, model.ReportActionHide)
+		require.NoError(t, err)
+		assert.Len(t, reports, 1)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("posts cannot be hidden", func(t *testing.T) {
+		_, err := service.ResolveReport(moderator, model.ReportTargetTypePost, postID, model.ReportActionHide)
+		assert.ErrorIs(t, err, storage.ErrBadRequest)
+	})
+
+	t.Run("resolve by deleting post", func(t *testing.T) {
+		mockStorage.On("GetPostByID", moderator, postID.String()).
+			Return(&model.Post{ID: postID, AuthorID: uuid.New()}, nil).
+			Once()
+		mockStorage.On("DeletePost", moderator, postID).Return(nil).Once()
+		mockStorage.On("ResolveReports", moderator, model.ReportTargetTypePost, postID, moderatorID, model.ReportActionDelete).
+			Return([]*model.Report{{ID: uuid.New()}, {ID: uuid.New()}}, nil).
+			Once()
+
+		reports, err := service.ResolveReport(moderator, model.ReportTargetTypePost, postID, model.ReportActionDelete)
+		require.NoError(t, err)
+		assert.Len(t, reports, 2)
+		mockStorage.AssertExpectations(t)
+	})
+}
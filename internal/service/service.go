@@ -2,25 +2,106 @@ package service
 
 import (
 	"context"
+	"graphql_project/internal/auth"
 	"graphql_project/internal/graph/model"
+	"graphql_project/internal/pubsub"
 	"graphql_project/internal/storage"
+	"strings"
+
+	"github.com/google/uuid"
 )
 
 type Service struct {
 	storage storage.Storage
+	filters []ContentFilter
+	pubsub  pubsub.PubSub
 }
 
-func NewService(storage storage.Storage) *Service {
-	return &Service{
+func NewService(storage storage.Storage, opts ...Option) *Service {
+	s := &Service{
 		storage: storage,
+		pubsub:  pubsub.NewInMem(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// CreateUser регистрирует пользователя с уникальным handle; displayName по умолчанию
+// совпадает с handle.
+func (s *Service) CreateUser(ctx context.Context, newUser model.NewUser) (*model.User, error) {
+	newUser.Handle = strings.TrimSpace(newUser.Handle)
+	if err := handleConstraint.Check("handle", newUser.Handle); err != nil {
+		return nil, err
+	}
+	if newUser.DisplayName != nil {
+		name := strings.TrimSpace(*newUser.DisplayName)
+		if err := displayNameConstraint.Check("displayName", name); err != nil {
+			return nil, err
+		}
+		newUser.DisplayName = &name
+	}
+
+	user, err := s.storage.CreateUser(ctx, newUser)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *Service) GetUserByID(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	user, err := s.storage.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *Service) GetUserByHandle(ctx context.Context, handle string) (*model.User, error) {
+	user, err := s.storage.GetUserByHandle(ctx, handle)
+	if err != nil {
+		return nil, err
 	}
+	return user, nil
 }
 
+func (s *Service) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]*model.User, error) {
+	users, err := s.storage.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// CreatePost публикует пост от имени пользователя запроса. Заголовок и текст вместе проходят
+// фильтры содержимого; их отметки сохраняются вместе с постом для модераторов.
 func (s *Service) CreatePost(ctx context.Context, newPost model.NewPost) (*model.Post, error) {
+	principal, err := auth.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+	newPost.AuthorID = principal.UserID
+	newPost.Title = strings.TrimSpace(newPost.Title)
+	if err := titleConstraint.Check("title", newPost.Title); err != nil {
+		return nil, err
+	}
+	if err := postContentConstraint.Check("content", newPost.Content); err != nil {
+		return nil, err
+	}
+
+	content := &Content{AuthorID: principal.UserID, Kind: ContentPost, Title: newPost.Title, Text: newPost.Content}
+	if newPost.FilterResults, err = s.filterContent(ctx, content); err != nil {
+		return nil, err
+	}
+	newPost.Title, newPost.Content = content.Title, content.Text
+
 	model, err := s.storage.CreatePost(ctx, newPost)
 	if err != nil {
 		return nil, err
 	}
+	s.recordContent(ctx, content)
+	s.publishPost(ctx, postAdded, model.ID, model.AuthorID)
 	return model, nil
 }
 
@@ -32,6 +113,20 @@ func (s *Service) GetAllPosts(ctx context.Context, offset *int, limit *int) ([]*
 	return model, nil
 }
 
+func (s *Service) GetPostsConnection(ctx context.Context, first *int, after *string, last *int, before *string) (*model.PostConnection, error) {
+	args, err := ParsePageArgs(first, after, last, before)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := s.storage.GetPostsPage(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return postConnection(page), nil
+}
+
 func (s *Service) GetPostByID(ctx context.Context, id string) (*model.Post, error) {
 	model, err := s.storage.GetPostByID(ctx, id)
 	if err != nil {
@@ -40,10 +135,269 @@ func (s *Service) GetPostByID(ctx context.Context, id string) (*model.Post, erro
 	return model, nil
 }
 
+// UpdatePost изменяет пост; доступно его автору и модераторам. Если заданы заголовок или
+// текст, пост с ними проходит фильтры содержимого, как при создании.
+func (s *Service) UpdatePost(ctx context.Context, id uuid.UUID, input model.UpdatePost) (*model.Post, error) {
+	if input.Title == nil && input.Content == nil && input.Commentable == nil && input.ModerationMode == nil {
+		return nil, storage.ErrBadRequest
+	}
+	if input.Title != nil {
+		title := strings.TrimSpace(*input.Title)
+		if err := titleConstraint.Check("title", title); err != nil {
+			return nil, err
+		}
+		input.Title = &title
+	}
+	if input.Content != nil {
+		content := *input.Content
+		if err := postContentConstraint.Check("content", content); err != nil {
+			return nil, err
+		}
+		input.Content = &content
+	}
+	current, err := s.authorizePost(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var content *Content
+	if input.Title != nil || input.Content != nil {
+		// Фильтры проверяют пост целиком: недостающее поле берётся из текущей версии, но
+		// сохраняются только заданные.
+		content = &Content{AuthorID: current.AuthorID, Kind: ContentPost, Title: current.Title, Text: current.Content}
+		if input.Title != nil {
+			content.Title = *input.Title
+		}
+		if input.Content != nil {
+			content.Text = *input.Content
+		}
+		if input.FilterResults, err = s.filterContent(ctx, content); err != nil {
+			return nil, err
+		}
+		if input.Title != nil {
+			input.Title = &content.Title
+		}
+		if input.Content != nil {
+			input.Content = &content.Text
+		}
+	}
+
+	post, err := s.storage.UpdatePost(ctx, id, input)
+	if err != nil {
+		return nil, err
+	}
+	if content != nil {
+		s.recordContent(ctx, content)
+	}
+	if input.Title != nil || input.Content != nil || input.ModerationMode != nil {
+		s.publishPost(ctx, postEdited, post.ID, post.AuthorID)
+	}
+	if post.Commentable != current.Commentable {
+		s.publishPost(ctx, commentableEvent(post.Commentable), post.ID, post.AuthorID)
+	}
+	return post, nil
+}
+
+func (s *Service) GetPostRevisions(ctx context.Context, postID uuid.UUID) ([]*model.PostRevision, error) {
+	revisions, err := s.storage.GetPostRevisions(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// SetCommentable открывает или закрывает пост для комментариев; reason учитывается только при закрытии.
+// Доступно автору поста и модераторам.
+func (s *Service) SetCommentable(ctx context.Context, postID uuid.UUID, value bool, reason *string) (*model.Post, error) {
+	if _, err := s.authorizePost(ctx, postID); err != nil {
+		return nil, err
+	}
+
+	post, err := s.storage.SetCommentable(ctx, postID, value, reason)
+	if err != nil {
+		return nil, err
+	}
+	s.publishPost(ctx, commentableEvent(value), post.ID, post.AuthorID)
+	return post, nil
+}
+
+// DeletePost удаляет пост; доступно его автору и модераторам.
+func (s *Service) DeletePost(ctx context.Context, id uuid.UUID) error {
+	if _, err := s.authorizePost(ctx, id); err != nil {
+		return err
+	}
+	if err := s.storage.DeletePost(ctx, id); err != nil {
+		return err
+	}
+	s.publishPost(ctx, postDeleted, id, uuid.Nil)
+	return nil
+}
+
+// VotePost учитывает голос пользователя запроса за пост; повторный голос того же знака
+// ничего не меняет.
+func (s *Service) VotePost(ctx context.Context, postID uuid.UUID, value int) (*model.Post, error) {
+	principal, err := voter(ctx, value)
+	if err != nil {
+		return nil, err
+	}
+
+	post, err := s.storage.VotePost(ctx, postID, principal.UserID, value)
+	if err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// CreateComment добавляет комментарий от имени пользователя запроса. Текст проходит
+// фильтры содержимого; их отметки сохраняются вместе с комментарием для модераторов.
 func (s *Service) CreateComment(ctx context.Context, newComment model.NewComment) (*model.Comment, error) {
+	principal, err := auth.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+	newComment.AuthorID = principal.UserID
+	if err := commentConstraint.Check("content", newComment.Content); err != nil {
+		return nil, err
+	}
+
+	content := &Content{AuthorID: principal.UserID, Kind: ContentComment, Text: newComment.Content}
+	if newComment.FilterResults, err = s.filterContent(ctx, content); err != nil {
+		return nil, err
+	}
+	newComment.Content = content.Text
+
 	model, err := s.storage.CreateComment(ctx, newComment)
 	if err != nil {
 		return nil, err
 	}
+	s.recordContent(ctx, content)
+	s.publishCommentAdded(ctx, model)
 	return model, nil
 }
+
+// EditComment изменяет текст комментария; доступно его автору и модераторам. Новый текст
+// проходит фильтры содержимого, как при создании. Скрытый комментарий изменить нельзя,
+// пока модератор не вернёт его в выдачу.
+func (s *Service) EditComment(ctx context.Context, id uuid.UUID, input model.EditComment) (*model.Comment, error) {
+	if err := commentConstraint.Check("content", input.Content); err != nil {
+		return nil, err
+	}
+	current, err := s.authorizeComment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if current.HiddenAt != nil {
+		return nil, storage.ErrHidden
+	}
+
+	content := &Content{AuthorID: current.AuthorID, Kind: ContentComment, Text: input.Content}
+	results, err := s.filterContent(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := s.storage.EditComment(ctx, id, content.Text, results)
+	if err != nil {
+		return nil, err
+	}
+	s.recordContent(ctx, content)
+	return comment, nil
+}
+
+func (s *Service) GetCommentByID(ctx context.Context, id uuid.UUID) (*model.Comment, error) {
+	comment, err := s.storage.GetCommentByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+func (s *Service) GetCommentHistory(ctx context.Context, commentID uuid.UUID) ([]*model.CommentRevision, error) {
+	history, err := s.storage.GetCommentHistory(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// DeleteComment удаляет комментарий; доступно его автору и модераторам.
+func (s *Service) DeleteComment(ctx context.Context, id uuid.UUID) error {
+	if _, err := s.authorizeComment(ctx, id); err != nil {
+		return err
+	}
+	return s.storage.DeleteComment(ctx, id)
+}
+
+// VoteComment учитывает голос пользователя запроса за комментарий; повторный голос того
+// же знака ничего не меняет.
+func (s *Service) VoteComment(ctx context.Context, commentID uuid.UUID, value int) (*model.Comment, error) {
+	principal, err := voter(ctx, value)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := s.storage.VoteComment(ctx, commentID, principal.UserID, value)
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// voter проверяет значение голоса и возвращает голосующего пользователя запроса.
+func voter(ctx context.Context, value int) (auth.Principal, error) {
+	if value != model.VoteUp && value != model.VoteDown {
+		return auth.Principal{}, storage.ErrBadRequest
+	}
+	return auth.Require(ctx)
+}
+
+func (s *Service) GetPostsByIDs(ctx context.Context, ids []uuid.UUID) ([]*model.Post, error) {
+	posts, err := s.storage.GetPostsByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// GetPostComments возвращает страницу комментариев верхнего уровня для каждого из постов.
+func (s *Service) GetPostComments(ctx context.Context, postIDs []uuid.UUID, page model.PageArgs) (map[uuid.UUID]*model.CommentConnection, error) {
+	pages, err := s.storage.GetCommentsByPostIDs(ctx, postIDs, page)
+	if err != nil {
+		return nil, err
+	}
+	return commentConnections(pages, page.Sort), nil
+}
+
+// GetCommentReplies возвращает страницу ответов для каждого из комментариев.
+func (s *Service) GetCommentReplies(ctx context.Context, commentIDs []uuid.UUID, page model.PageArgs) (map[uuid.UUID]*model.CommentConnection, error) {
+	pages, err := s.storage.GetRepliesByCommentIDs(ctx, commentIDs, page)
+	if err != nil {
+		return nil, err
+	}
+	return commentConnections(pages, page.Sort), nil
+}
+
+// Search выполняет полнотекстовый поиск по постам и комментариям. Результаты
+// упорядочены по релевантности и листаются вперёд курсорами-смещениями.
+func (s *Service) Search(ctx context.Context, query string, first *int, after *string) (*model.SearchConnection, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, storage.ErrBadRequest
+	}
+	page, err := ParsePageArgs(first, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var offset int
+	if after != nil {
+		if offset, err = decodeOffsetCursor(*after); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := s.storage.Search(ctx, query, *page.First, offset)
+	if err != nil {
+		return nil, err
+	}
+	return searchConnection(result, offset), nil
+}
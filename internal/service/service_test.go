@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"graphql_project/internal/auth"
 	"graphql_project/internal/graph/model"
 	"graphql_project/internal/storage"
 	"testing"
@@ -17,6 +18,26 @@ type MockStorage struct {
 	mock.Mock
 }
 
+func (m *MockStorage) CreateUser(ctx context.Context, newUser model.NewUser) (*model.User, error) {
+	args := m.Called(ctx, newUser)
+	return args.Get(0).(*model.User), args.Error(1)
+}
+
+func (m *MockStorage) GetUserByID(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*model.User), args.Error(1)
+}
+
+func (m *MockStorage) GetUserByHandle(ctx context.Context, handle string) (*model.User, error) {
+	args := m.Called(ctx, handle)
+	return args.Get(0).(*model.User), args.Error(1)
+}
+
+func (m *MockStorage) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]*model.User, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]*model.User), args.Error(1)
+}
+
 func (m *MockStorage) CreatePost(ctx context.Context, newPost model.NewPost) (*model.Post, error) {
 	args := m.Called(ctx, newPost)
 	return args.Get(0).(*model.Post), args.Error(1)
@@ -27,24 +48,199 @@ func (m *MockStorage) GetAllPosts(ctx context.Context, offset *int, limit *int)
 	return args.Get(0).([]*model.Post), args.Error(1)
 }
 
+func (m *MockStorage) GetPostsPage(ctx context.Context, page model.PageArgs) (*model.PostPage, error) {
+	args := m.Called(ctx, page)
+	return args.Get(0).(*model.PostPage), args.Error(1)
+}
+
 func (m *MockStorage) GetPostByID(ctx context.Context, id string) (*model.Post, error) {
 	args := m.Called(ctx, id)
 	return args.Get(0).(*model.Post), args.Error(1)
 }
 
+func (m *MockStorage) UpdatePost(ctx context.Context, id uuid.UUID, input model.UpdatePost) (*model.Post, error) {
+	args := m.Called(ctx, id, input)
+	return args.Get(0).(*model.Post), args.Error(1)
+}
+
+func (m *MockStorage) GetPostRevisions(ctx context.Context, postID uuid.UUID) ([]*model.PostRevision, error) {
+	args := m.Called(ctx, postID)
+	return args.Get(0).([]*model.PostRevision), args.Error(1)
+}
+
+func (m *MockStorage) GetCommentByID(ctx context.Context, id uuid.UUID) (*model.Comment, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*model.Comment), args.Error(1)
+}
+
+func (m *MockStorage) EditComment(ctx context.Context, id uuid.UUID, content string, results []*model.FilterResult) (*model.Comment, error) {
+	args := m.Called(ctx, id, content, results)
+	return args.Get(0).(*model.Comment), args.Error(1)
+}
+
+func (m *MockStorage) GetCommentHistory(ctx context.Context, commentID uuid.UUID) ([]*model.CommentRevision, error) {
+	args := m.Called(ctx, commentID)
+	return args.Get(0).([]*model.CommentRevision), args.Error(1)
+}
+
+func (m *MockStorage) SetCommentable(ctx context.Context, postID uuid.UUID, value bool, reason *string) (*model.Post, error) {
+	args := m.Called(ctx, postID, value, reason)
+	return args.Get(0).(*model.Post), args.Error(1)
+}
+
+func (m *MockStorage) VotePost(ctx context.Context, postID uuid.UUID, userID uuid.UUID, value int) (*model.Post, error) {
+	args := m.Called(ctx, postID, userID, value)
+	return args.Get(0).(*model.Post), args.Error(1)
+}
+
+func (m *MockStorage) VoteComment(ctx context.Context, commentID uuid.UUID, userID uuid.UUID, value int) (*model.Comment, error) {
+	args := m.Called(ctx, commentID, userID, value)
+	return args.Get(0).(*model.Comment), args.Error(1)
+}
+
+func (m *MockStorage) Search(ctx context.Context, query string, limit, offset int) (*model.SearchPage, error) {
+	args := m.Called(ctx, query, limit, offset)
+	return args.Get(0).(*model.SearchPage), args.Error(1)
+}
+
+func (m *MockStorage) SetCommentHidden(ctx context.Context, id uuid.UUID, hidden bool, moderatorID uuid.UUID, reason *string) (*model.Comment, error) {
+	args := m.Called(ctx, id, hidden, moderatorID, reason)
+	return args.Get(0).(*model.Comment), args.Error(1)
+}
+
+func (m *MockStorage) SetUserBanned(ctx context.Context, userID uuid.UUID, banned bool, moderatorID uuid.UUID, reason *string) (*model.User, error) {
+	args := m.Called(ctx, userID, banned, moderatorID, reason)
+	return args.Get(0).(*model.User), args.Error(1)
+}
+
+func (m *MockStorage) GetModerationLog(ctx context.Context, page model.PageArgs) (*model.ModerationLogPage, error) {
+	args := m.Called(ctx, page)
+	return args.Get(0).(*model.ModerationLogPage), args.Error(1)
+}
+
+func (m *MockStorage) CreateReport(ctx context.Context, newReport model.NewReport) (*model.Report, error) {
+	args := m.Called(ctx, newReport)
+	return args.Get(0).(*model.Report), args.Error(1)
+}
+
+func (m *MockStorage) GetReportQueue(ctx context.Context, limit, offset int) ([]*model.ReportQueueItem, error) {
+	args := m.Called(ctx, limit, offset)
+	return args.Get(0).([]*model.ReportQueueItem), args.Error(1)
+}
+
+func (m *MockStorage) ResolveReports(ctx context.Context, targetType model.ReportTargetType, targetID uuid.UUID, moderatorID uuid.UUID, resolution model.ReportAction) ([]*model.Report, error) {
+	args := m.Called(ctx, targetType, targetID, moderatorID, resolution)
+	return args.Get(0).([]*model.Report), args.Error(1)
+}
+
+func (m *MockStorage) GetPostFilterResults(ctx context.Context, postID uuid.UUID) ([]*model.FilterResult, error) {
+	args := m.Called(ctx, postID)
+	return args.Get(0).([]*model.FilterResult), args.Error(1)
+}
+
+func (m *MockStorage) GetCommentFilterResults(ctx context.Context, commentID uuid.UUID) ([]*model.FilterResult, error) {
+	args := m.Called(ctx, commentID)
+	return args.Get(0).([]*model.FilterResult), args.Error(1)
+}
+
+func (m *MockStorage) ApproveComment(ctx context.Context, id uuid.UUID, moderatorID uuid.UUID) (*model.Comment, error) {
+	args := m.Called(ctx, id, moderatorID)
+	return args.Get(0).(*model.Comment), args.Error(1)
+}
+
+func (m *MockStorage) GetPendingComments(ctx context.Context, postID *uuid.UUID, page model.PageArgs) (*model.CommentPage, error) {
+	args := m.Called(ctx, postID, page)
+	return args.Get(0).(*model.CommentPage), args.Error(1)
+}
+
+func (m *MockStorage) GetPostComments(ctx context.Context, postID uuid.UUID, page model.PageArgs) (*model.CommentPage, error) {
+	args := m.Called(ctx, postID, page)
+	return args.Get(0).(*model.CommentPage), args.Error(1)
+}
+
+func (m *MockStorage) GetCommentAncestors(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func (m *MockStorage) DeletePost(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockStorage) DeleteComment(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
 func (m *MockStorage) CreateComment(ctx context.Context, newComment model.NewComment) (*model.Comment, error) {
 	args := m.Called(ctx, newComment)
 	return args.Get(0).(*model.Comment), args.Error(1)
 }
 
-func TestService_CreatePost(t *testing.T) {
+func (m *MockStorage) GetPostsByIDs(ctx context.Context, ids []uuid.UUID) ([]*model.Post, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]*model.Post), args.Error(1)
+}
+
+func (m *MockStorage) GetCommentsByPostIDs(ctx context.Context, postIDs []uuid.UUID, page model.PageArgs) (map[uuid.UUID]*model.CommentPage, error) {
+	args := m.Called(ctx, postIDs, page)
+	return args.Get(0).(map[uuid.UUID]*model.CommentPage), args.Error(1)
+}
+
+func (m *MockStorage) GetRepliesByCommentIDs(ctx context.Context, commentIDs []uuid.UUID, page model.PageArgs) (map[uuid.UUID]*model.CommentPage, error) {
+	args := m.Called(ctx, commentIDs, page)
+	return args.Get(0).(map[uuid.UUID]*model.CommentPage), args.Error(1)
+}
+
+func TestService_CreateUser(t *testing.T) {
 	ctx := context.Background()
 	mockStorage := new(MockStorage)
 	service := NewService(mockStorage)
 
+	t.Run("trims handle and display name", func(t *testing.T) {
+		name := "Alice"
+		expected := &model.User{ID: uuid.New(), Handle: "alice", DisplayName: name}
+		mockStorage.On("CreateUser", ctx, model.NewUser{Handle: "alice", DisplayName: &name}).
+			Return(expected, nil).
+			Once()
+
+		padded := "  Alice "
+		result, err := service.CreateUser(ctx, model.NewUser{Handle: " alice ", DisplayName: &padded})
+
+		require.NoError(t, err)
+		assert.Equal(t, expected, result)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("duplicate handle", func(t *testing.T) {
+		mockStorage.On("CreateUser", ctx, model.NewUser{Handle: "bob"}).
+			Return((*model.User)(nil), storage.ErrAlreadyExists).
+			Once()
+
+		_, err := service.CreateUser(ctx, model.NewUser{Handle: "bob"})
+
+		assert.ErrorIs(t, err, storage.ErrAlreadyExists)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("invalid handle", func(t *testing.T) {
+		for _, handle := range []string{"", "   ", "with space", "ЮникоД"} {
+			_, err := service.CreateUser(ctx, model.NewUser{Handle: handle})
+			assert.ErrorIs(t, err, storage.ErrBadRequest, handle)
+		}
+	})
+}
+
+func TestService_CreatePost(t *testing.T) {
+	authorID := uuid.New()
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: authorID})
+	mockStorage := new(MockStorage)
+	service := NewService(mockStorage)
+
 	newPost := model.NewPost{
 		Title:       "Test Post",
-		Author:      "Author",
+		AuthorID:    authorID,
 		Content:     "Content",
 		Commentable: true,
 	}
@@ -52,7 +248,7 @@ func TestService_CreatePost(t *testing.T) {
 	expectedPost := &model.Post{
 		ID:          uuid.New(),
 		Title:       newPost.Title,
-		Author:      newPost.Author,
+		AuthorID:    newPost.AuthorID,
 		Content:     newPost.Content,
 		Commentable: newPost.Commentable,
 	}
@@ -62,7 +258,7 @@ func TestService_CreatePost(t *testing.T) {
 			Return(expectedPost, nil).
 			Once()
 
-		result, err := service.CreatePost(ctx, newPost)
+		result, err := service.CreatePost(ctx, model.NewPost{Title: newPost.Title, Content: newPost.Content, Commentable: true})
 
 		require.NoError(t, err)
 		assert.Equal(t, expectedPost, result)
@@ -79,6 +275,13 @@ func TestService_CreatePost(t *testing.T) {
 		assert.ErrorIs(t, err, storage.ErrNotFound)
 		mockStorage.AssertExpectations(t)
 	})
+
+	t.Run("anonymous", func(t *testing.T) {
+		_, err := service.CreatePost(context.Background(), newPost)
+
+		assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+		mockStorage.AssertNumberOfCalls(t, "CreatePost", 2)
+	})
 }
 
 func TestService_GetAllPosts(t *testing.T) {
@@ -129,6 +332,64 @@ func TestService_GetAllPosts(t *testing.T) {
 	})
 }
 
+func TestService_GetPostsConnection(t *testing.T) {
+	ctx := context.Background()
+	mockStorage := new(MockStorage)
+	service := NewService(mockStorage)
+
+	now := time.Now().UTC()
+	posts := []*model.Post{
+		{ID: uuid.New(), Title: "Post 1", CreatedAt: now},
+		{ID: uuid.New(), Title: "Post 2", CreatedAt: now.Add(time.Second)},
+	}
+
+	t.Run("default page size", func(t *testing.T) {
+		size := defaultPageSize
+		mockStorage.On("GetPostsPage", ctx, model.PageArgs{First: &size}).
+			Return(&model.PostPage{Posts: posts, HasNextPage: true, TotalCount: 5}, nil).
+			Once()
+
+		result, err := service.GetPostsConnection(ctx, nil, nil, nil, nil)
+
+		require.NoError(t, err)
+		require.Len(t, result.Edges, 2)
+		assert.Equal(t, posts[0], result.Edges[0].Node)
+		assert.Equal(t, posts[0].Cursor().Encode(), *result.PageInfo.StartCursor)
+		assert.Equal(t, posts[1].Cursor().Encode(), *result.PageInfo.EndCursor)
+		assert.True(t, result.PageInfo.HasNextPage)
+		assert.EqualValues(t, 5, result.PageInfo.TotalCount)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("after cursor", func(t *testing.T) {
+		first := 2
+		cursor := posts[0].Cursor()
+		after := cursor.Encode()
+		mockStorage.On("GetPostsPage", ctx, model.PageArgs{First: &first, After: &cursor}).
+			Return(&model.PostPage{Posts: posts[1:], HasPreviousPage: true, TotalCount: 2}, nil).
+			Once()
+
+		result, err := service.GetPostsConnection(ctx, &first, &after, nil, nil)
+
+		require.NoError(t, err)
+		require.Len(t, result.Edges, 1)
+		assert.True(t, result.PageInfo.HasPreviousPage)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("first and last together", func(t *testing.T) {
+		first, last := 1, 1
+		_, err := service.GetPostsConnection(ctx, &first, nil, &last, nil)
+		assert.ErrorIs(t, err, storage.ErrBadRequest)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		after := "not a cursor"
+		_, err := service.GetPostsConnection(ctx, nil, &after, nil, nil)
+		assert.ErrorIs(t, err, model.ErrInvalidCursor)
+	})
+}
+
 func TestService_GetPostByID(t *testing.T) {
 	ctx := context.Background()
 	mockStorage := new(MockStorage)
@@ -161,25 +422,99 @@ func TestService_GetPostByID(t *testing.T) {
 	})
 }
 
+func TestService_UpdatePost(t *testing.T) {
+	authorID := uuid.New()
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: authorID})
+	mockStorage := new(MockStorage)
+	service := NewService(mockStorage)
+
+	postID := uuid.New()
+	title := "Updated"
+	input := model.UpdatePost{Title: &title}
+	mockStorage.On("GetPostByID", ctx, postID.String()).
+		Return(&model.Post{ID: postID, AuthorID: authorID}, nil)
+
+	t.Run("success", func(t *testing.T) {
+		expected := &model.Post{ID: postID, Title: title}
+		mockStorage.On("UpdatePost", ctx, postID, input).
+			Return(expected, nil).
+			Once()
+
+		result, err := service.UpdatePost(ctx, postID, input)
+
+		require.NoError(t, err)
+		assert.Equal(t, expected, result)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("empty input", func(t *testing.T) {
+		_, err := service.UpdatePost(ctx, postID, model.UpdatePost{})
+
+		assert.ErrorIs(t, err, storage.ErrBadRequest)
+		mockStorage.AssertNotCalled(t, "UpdatePost", ctx, postID, model.UpdatePost{})
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockStorage.On("UpdatePost", ctx, postID, input).
+			Return((*model.Post)(nil), storage.ErrNotFound).
+			Once()
+
+		_, err := service.UpdatePost(ctx, postID, input)
+
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+		mockStorage.AssertExpectations(t)
+	})
+}
+
+func TestService_VotePost(t *testing.T) {
+	postID, userID := uuid.New(), uuid.New()
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: userID})
+	mockStorage := new(MockStorage)
+	service := NewService(mockStorage)
+
+	t.Run("success", func(t *testing.T) {
+		expected := &model.Post{ID: postID, Upvotes: 1}
+		mockStorage.On("VotePost", ctx, postID, userID, model.VoteUp).
+			Return(expected, nil).
+			Once()
+
+		result, err := service.VotePost(ctx, postID, model.VoteUp)
+
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, result.Score())
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("invalid vote", func(t *testing.T) {
+		_, err := service.VotePost(context.Background(), postID, model.VoteUp)
+		assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+
+		_, err = service.VotePost(ctx, postID, 2)
+		assert.ErrorIs(t, err, storage.ErrBadRequest)
+		mockStorage.AssertNotCalled(t, "VotePost", ctx, postID, userID, 2)
+	})
+}
+
 func TestService_CreateComment(t *testing.T) {
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	authorID := uuid.New()
+	ctx, cancel := context.WithTimeout(auth.WithPrincipal(context.Background(), auth.Principal{UserID: authorID}), 1*time.Second)
 	defer cancel()
 
 	mockStorage := new(MockStorage)
 	service := NewService(mockStorage)
 
-	postID := uuid.New().String()
-	commentID := uuid.New().String()
+	postID := uuid.New()
+	commentID := uuid.New()
 	newComment := model.NewComment{
-		Author:  "User",
-		Content: "Comment",
-		PostID:  &postID,
+		AuthorID: authorID,
+		Content:  "Comment",
+		PostID:   &postID,
 	}
 
 	expectedComment := &model.Comment{
-		ID:      uuid.MustParse(commentID),
-		Author:  newComment.Author,
-		Content: newComment.Content,
+		ID:       commentID,
+		AuthorID: newComment.AuthorID,
+		Content:  newComment.Content,
 	}
 
 	t.Run("success to post", func(t *testing.T) {
@@ -195,9 +530,9 @@ func TestService_CreateComment(t *testing.T) {
 	})
 
 	t.Run("success to comment", func(t *testing.T) {
-		commentID := uuid.New().String()
+		commentID := uuid.New()
 		newComment := model.NewComment{
-			Author:    "User",
+			AuthorID:  authorID,
 			Content:   "Reply",
 			CommentID: &commentID,
 		}
@@ -226,8 +561,8 @@ func TestService_CreateComment(t *testing.T) {
 
 	t.Run("invalid request", func(t *testing.T) {
 		invalidComment := model.NewComment{
-			Author:  "User",
-			Content: "Comment",
+			AuthorID: authorID,
+			Content:  "Comment",
 		}
 
 		mockStorage.On("CreateComment", ctx, invalidComment).
@@ -240,3 +575,216 @@ func TestService_CreateComment(t *testing.T) {
 		mockStorage.AssertExpectations(t)
 	})
 }
+
+func TestService_EditComment(t *testing.T) {
+	authorID := uuid.New()
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: authorID})
+	mockStorage := new(MockStorage)
+	service := NewService(mockStorage)
+
+	commentID := uuid.New()
+	mockStorage.On("GetCommentByID", ctx, commentID).
+		Return(&model.Comment{ID: commentID, AuthorID: authorID}, nil)
+
+	t.Run("success", func(t *testing.T) {
+		expected := &model.Comment{ID: commentID, Content: "Edited"}
+		mockStorage.On("EditComment", ctx, commentID, "Edited", ([]*model.FilterResult)(nil)).
+			Return(expected, nil).
+			Once()
+
+		result, err := service.EditComment(ctx, commentID, model.EditComment{Content: "Edited"})
+
+		require.NoError(t, err)
+		assert.Equal(t, expected, result)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("empty content", func(t *testing.T) {
+		_, err := service.EditComment(ctx, commentID, model.EditComment{})
+
+		assert.ErrorIs(t, err, storage.ErrBadRequest)
+		mockStorage.AssertNotCalled(t, "EditComment", ctx, commentID, "", mock.Anything)
+	})
+}
+
+func TestParseCommentPageArgs(t *testing.T) {
+	page, err := ParseCommentPageArgs(nil, nil, nil, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, model.CommentSortOld, page.Sort)
+	assert.Equal(t, defaultPageSize, *page.First)
+
+	sort := model.CommentSortControversial
+	page, err = ParseCommentPageArgs(nil, nil, nil, nil, &sort)
+	require.NoError(t, err)
+	assert.Equal(t, sort, page.Sort)
+
+	invalid := model.CommentSort("RANDOM")
+	_, err = ParseCommentPageArgs(nil, nil, nil, nil, &invalid)
+	assert.ErrorIs(t, err, storage.ErrBadRequest)
+}
+
+func TestService_GetPostComments(t *testing.T) {
+	ctx := context.Background()
+	mockStorage := new(MockStorage)
+	service := NewService(mockStorage)
+
+	postID := uuid.New()
+	emptyPostID := uuid.New()
+	comments := []*model.Comment{
+		{ID: uuid.New(), Content: "First", PostID: &postID, CreatedAt: time.Now().UTC()},
+	}
+	first := 1
+	page := model.PageArgs{First: &first}
+
+	t.Run("success", func(t *testing.T) {
+		mockStorage.On("GetCommentsByPostIDs", ctx, []uuid.UUID{postID, emptyPostID}, page).
+			Return(map[uuid.UUID]*model.CommentPage{
+				postID:      {Comments: comments, HasNextPage: true, TotalCount: 3},
+				emptyPostID: {Comments: []*model.Comment{}},
+			}, nil).
+			Once()
+
+		result, err := service.GetPostComments(ctx, []uuid.UUID{postID, emptyPostID}, page)
+
+		require.NoError(t, err)
+		require.Len(t, result[postID].Edges, 1)
+		assert.Equal(t, comments[0], result[postID].Edges[0].Node)
+		assert.Equal(t, comments[0].Cursor(page.Sort).Encode(), result[postID].Edges[0].Cursor)
+		assert.True(t, result[postID].PageInfo.HasNextPage)
+		assert.EqualValues(t, 3, result[postID].PageInfo.TotalCount)
+		assert.Empty(t, result[emptyPostID].Edges)
+		assert.Nil(t, result[emptyPostID].PageInfo.EndCursor)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("deleted comment becomes tombstone", func(t *testing.T) {
+		deletedAt := time.Now().UTC()
+		deleted := &model.Comment{ID: uuid.New(), AuthorID: uuid.New(), Content: "Secret", PostID: &postID, DeletedAt: &deletedAt}
+		mockStorage.On("GetCommentsByPostIDs", ctx, []uuid.UUID{postID}, page).
+			Return(map[uuid.UUID]*model.CommentPage{
+				postID: {Comments: []*model.Comment{deleted}, TotalCount: 1},
+			}, nil).
+			Once()
+
+		result, err := service.GetPostComments(ctx, []uuid.UUID{postID}, page)
+
+		require.NoError(t, err)
+		require.Len(t, result[postID].Edges, 1)
+		node := result[postID].Edges[0].Node
+		assert.Equal(t, deleted.ID, node.ID)
+		assert.Equal(t, model.DeletedContent, node.Content)
+		assert.Equal(t, uuid.Nil, node.AuthorID)
+		assert.Equal(t, "Secret", deleted.Content, "stored comment must not change")
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("hidden comment is redacted", func(t *testing.T) {
+		hiddenAt := time.Now().UTC()
+		hidden := &model.Comment{ID: uuid.New(), AuthorID: uuid.New(), Content: "Spam", PostID: &postID, HiddenAt: &hiddenAt}
+		mockStorage.On("GetCommentsByPostIDs", ctx, []uuid.UUID{postID}, page).
+			Return(map[uuid.UUID]*model.CommentPage{
+				postID: {Comments: []*model.Comment{hidden}, TotalCount: 1},
+			}, nil).
+			Once()
+
+		result, err := service.GetPostComments(ctx, []uuid.UUID{postID}, page)
+
+		require.NoError(t, err)
+		node := result[postID].Edges[0].Node
+		assert.Equal(t, model.HiddenContent, node.Content)
+		assert.Equal(t, uuid.Nil, node.AuthorID)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("storage error", func(t *testing.T) {
+		mockStorage.On("GetCommentsByPostIDs", ctx, []uuid.UUID{postID}, page).
+			Return(map[uuid.UUID]*model.CommentPage(nil), storage.ErrNotFound).
+			Once()
+
+		_, err := service.GetPostComments(ctx, []uuid.UUID{postID}, page)
+
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+		mockStorage.AssertExpectations(t)
+	})
+}
+
+func TestService_GetCommentReplies(t *testing.T) {
+	ctx := context.Background()
+	mockStorage := new(MockStorage)
+	service := NewService(mockStorage)
+
+	parentID := uuid.New()
+	replies := []*model.Comment{
+		{ID: uuid.New(), Content: "Reply", ParentID: &parentID, CreatedAt: time.Now().UTC()},
+	}
+
+	last := 5
+	cursor := model.Cursor{CreatedAt: time.Now().UTC(), ID: uuid.New()}
+	page := model.PageArgs{Last: &last, Before: &cursor}
+	mockStorage.On("GetRepliesByCommentIDs", ctx, []uuid.UUID{parentID}, page).
+		Return(map[uuid.UUID]*model.CommentPage{
+			parentID: {Comments: replies, HasNextPage: true, TotalCount: 2},
+		}, nil).
+		Once()
+
+	result, err := service.GetCommentReplies(ctx, []uuid.UUID{parentID}, page)
+
+	require.NoError(t, err)
+	require.Len(t, result[parentID].Edges, 1)
+	assert.False(t, result[parentID].PageInfo.HasPreviousPage)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestService_Search(t *testing.T) {
+	ctx := context.Background()
+	mockStorage := new(MockStorage)
+	service := NewService(mockStorage)
+
+	post := &model.Post{ID: uuid.New(), Title: "GraphQL"}
+	comment := &model.Comment{ID: uuid.New(), Content: "about graphql"}
+	first := 2
+
+	t.Run("pages by offset", func(t *testing.T) {
+		mockStorage.On("Search", ctx, "graphql", first, 0).
+			Return(&model.SearchPage{
+				Hits: []*model.SearchHit{
+					{Node: post, Snippet: "<mark>GraphQL</mark>", Rank: 2},
+					{Node: comment, Snippet: "about <mark>graphql</mark>", Rank: 1},
+				},
+				TotalCount: 3,
+			}, nil).
+			Once()
+
+		result, err := service.Search(ctx, "graphql", &first, nil)
+
+		require.NoError(t, err)
+		require.Len(t, result.Edges, 2)
+		assert.Equal(t, post, result.Edges[0].Node)
+		assert.Equal(t, comment, result.Edges[1].Node)
+		assert.True(t, result.PageInfo.HasNextPage)
+		assert.False(t, result.PageInfo.HasPreviousPage)
+
+		mockStorage.On("Search", ctx, "graphql", first, 2).
+			Return(&model.SearchPage{Hits: []*model.SearchHit{{Node: post}}, TotalCount: 3}, nil).
+			Once()
+
+		result, err = service.Search(ctx, "graphql", &first, result.PageInfo.EndCursor)
+
+		require.NoError(t, err)
+		require.Len(t, result.Edges, 1)
+		assert.False(t, result.PageInfo.HasNextPage)
+		assert.True(t, result.PageInfo.HasPreviousPage)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("empty query", func(t *testing.T) {
+		_, err := service.Search(ctx, "  ", nil, nil)
+		assert.ErrorIs(t, err, storage.ErrBadRequest)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		after := "not-a-cursor"
+		_, err := service.Search(ctx, "graphql", nil, &after)
+		assert.ErrorIs(t, err, model.ErrInvalidCursor)
+	})
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"fmt"
+	"graphql_project/internal/storage"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// maxCommentLength — наибольшая длина комментария в символах.
+const maxCommentLength = 2000
+
+// Ограничения полей ввода; те же значения указаны в директивах @constraint в schema.graphqls.
+var (
+	// handleConstraint ограничивает handle латиницей, цифрами и символами «_», «.», «-».
+	handleConstraint      = Constraint{Pattern: `^[A-Za-z0-9_.-]{1,32}$`}
+	displayNameConstraint = Constraint{MaxLength: 64}
+	titleConstraint       = Constraint{MinLength: 1, MaxLength: 200}
+	postContentConstraint = Constraint{MaxLength: 40000}
+	commentConstraint     = Constraint{MinLength: 1, MaxLength: maxCommentLength}
+	reasonConstraint      = Constraint{MinLength: 1, MaxLength: maxReportReason}
+)
+
+// ValidationError — значение поля ввода не прошло проверку. Ошибка считается
+// storage.ErrBadRequest, а клиент получает имя поля в extensions.field.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == storage.ErrBadRequest
+}
+
+// Constraint — ограничения строкового поля: длина в символах и регулярное выражение.
+// Нулевые значения не ограничивают. Длина и формат проверяются по значению целиком, как
+// оно будет сохранено; строка из одних пробелов считается пустой.
+type Constraint struct {
+	MinLength int
+	MaxLength int
+	Pattern   string
+}
+
+// patterns кеширует скомпилированные выражения Constraint.Pattern: их набор ограничен
+// схемой и кодом сервиса.
+var patterns sync.Map
+
+// Check проверяет значение поля field и возвращает *ValidationError при нарушении.
+func (c Constraint) Check(field, value string) error {
+	length := utf8.RuneCountInString(value)
+	switch {
+	case c.MinLength > 0 && strings.TrimSpace(value) == "":
+		return &ValidationError{Field: field, Message: "must not be empty"}
+	case length < c.MinLength:
+		return &ValidationError{Field: field, Message: fmt.Sprintf("must be at least %d characters", c.MinLength)}
+	case c.MaxLength > 0 && length > c.MaxLength:
+		return &ValidationError{Field: field, Message: fmt.Sprintf("must be at most %d characters", c.MaxLength)}
+	}
+	if c.Pattern == "" {
+		return nil
+	}
+
+	re, err := compilePattern(c.Pattern)
+	if err != nil {
+		return err
+	}
+	if !re.MatchString(value) {
+		return &ValidationError{Field: field, Message: "has invalid format"}
+	}
+	return nil
+}
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid constraint pattern %q: %w", pattern, err)
+	}
+	patterns.Store(pattern, re)
+	return re, nil
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"graphql_project/internal/auth"
+	"graphql_project/internal/graph/model"
+	"graphql_project/internal/storage"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstraint(t *testing.T) {
+	c := Constraint{MinLength: 2, MaxLength: 4, Pattern: `^[a-zа-я]+$`}
+
+	assert.NoError(t, c.Check("name", "жук"), "length is counted in characters")
+	assert.NoError(t, c.Check("name", "abcd"))
+
+	for value, message := range map[string]string{
+		"   ":   "must not be empty",
+		"a":     "must be at least 2 characters",
+		"abcde": "must be at most 4 characters",
+		" жук ": "must be at most 4 characters",
+		"AB":    "has invalid format",
+		" ab":   "has invalid format",
+	} {
+		err := c.Check("name", value)
+		var validationErr *ValidationError
+		require.ErrorAs(t, err, &validationErr, value)
+		assert.Equal(t, "name", validationErr.Field)
+		assert.Equal(t, message, validationErr.Message)
+		assert.ErrorIs(t, err, storage.ErrBadRequest)
+	}
+
+	assert.NoError(t, Constraint{}.Check("name", ""), "zero constraint allows anything")
+	assert.Error(t, Constraint{Pattern: "("}.Check("name", "x"))
+}
+
+func TestService_Validation(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: uuid.New()})
+	mockStorage := new(MockStorage)
+	service := NewService(mockStorage)
+	postID := uuid.New()
+
+	t.Run("comment length", func(t *testing.T) {
+		_, err := service.CreateComment(ctx, model.NewComment{Content: strings.Repeat("ж", maxCommentLength+1), PostID: &postID})
+		var validationErr *ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, "content", validationErr.Field)
+
+		_, err = service.CreateComment(ctx, model.NewComment{Content: " ", PostID: &postID})
+		assert.ErrorIs(t, err, storage.ErrBadRequest)
+		_, err = service.CreateComment(ctx, model.NewComment{Content: "a" + strings.Repeat(" ", 100000), PostID: &postID})
+		assert.ErrorIs(t, err, storage.ErrBadRequest, "padding counts towards the length")
+		_, err = service.EditComment(ctx, uuid.New(), model.EditComment{Content: strings.Repeat("x", maxCommentLength+1)})
+		assert.ErrorIs(t, err, storage.ErrBadRequest)
+		mockStorage.AssertNotCalled(t, "CreateComment", mock.Anything, mock.Anything)
+	})
+
+	t.Run("empty title", func(t *testing.T) {
+		_, err := service.CreatePost(ctx, model.NewPost{Title: "  ", Content: "Content"})
+		var validationErr *ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, "title", validationErr.Field)
+
+		empty := ""
+		_, err = service.UpdatePost(ctx, postID, model.UpdatePost{Title: &empty})
+		assert.ErrorIs(t, err, storage.ErrBadRequest)
+		mockStorage.AssertNotCalled(t, "CreatePost", mock.Anything, mock.Anything)
+	})
+
+	t.Run("title is trimmed", func(t *testing.T) {
+		mockStorage.On("CreatePost", ctx, mock.MatchedBy(func(p model.NewPost) bool { return p.Title == "Title" })).
+			Return(&model.Post{ID: postID}, nil).
+			Once()
+		_, err := service.CreatePost(ctx, model.NewPost{Title: " Title ", Content: "Content"})
+		require.NoError(t, err)
+		mockStorage.AssertExpectations(t)
+	})
+}
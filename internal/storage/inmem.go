@@ -5,6 +5,7 @@ import (
 	"errors"
 	"slices"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"graphql_project/internal/graph/model"
@@ -14,117 +15,902 @@ var (
 	ErrNotCommentable = errors.New("the post is not commentable")
 	ErrNotFound       = errors.New("not found")
 	ErrBadRequest     = errors.New("bad request")
+	ErrAlreadyExists  = errors.New("already exists")
+	ErrBanned         = errors.New("the author is banned")
+	ErrHidden         = errors.New("the comment is hidden by a moderator")
 )
 
 type inmemStorage struct {
-	posts []*model.Post
-	mu sync.RWMutex
+	posts    []*model.Post
+	comments map[uuid.UUID]*model.Comment
+	// children хранит дерево комментариев: ключ — ID поста для комментариев верхнего
+	// уровня или ID родительского комментария для ответов.
+	children  map[uuid.UUID][]*model.Comment
+	revisions map[uuid.UUID][]*model.PostRevision
+	history   map[uuid.UUID][]*model.CommentRevision
+	// filterResults хранит отметки фильтров содержимого по ID поста или комментария.
+	filterResults map[uuid.UUID][]*model.FilterResult
+	users         map[uuid.UUID]*model.User
+	// handles индексирует пользователей по уникальному handle.
+	handles map[string]uuid.UUID
+	// votes хранит голоса по ID поста или комментария и ID пользователя.
+	votes map[uuid.UUID]map[uuid.UUID]int
+	// moderationLog хранит действия модераторов в порядке выполнения.
+	moderationLog []*model.ModerationAction
+	// reports хранит жалобы, открытые и закрытые, в порядке поступления.
+	reports []*model.Report
+	index   *searchIndex
+	opts    options
+	mu      sync.RWMutex
 }
 
-func NewInMemStorage() *inmemStorage {
-	return &inmemStorage{posts: make([]*model.Post, 0)}
+func NewInMemStorage(opts ...Option) *inmemStorage {
+	return &inmemStorage{
+		opts:          newOptions(opts),
+		users:         make(map[uuid.UUID]*model.User),
+		handles:       make(map[string]uuid.UUID),
+		posts:         make([]*model.Post, 0),
+		comments:      make(map[uuid.UUID]*model.Comment),
+		children:      make(map[uuid.UUID][]*model.Comment),
+		revisions:     make(map[uuid.UUID][]*model.PostRevision),
+		history:       make(map[uuid.UUID][]*model.CommentRevision),
+		filterResults: make(map[uuid.UUID][]*model.FilterResult),
+		votes:         make(map[uuid.UUID]map[uuid.UUID]int),
+		index:         newSearchIndex(),
+	}
+}
+
+func (s *inmemStorage) CreateUser(ctx context.Context, newUser model.NewUser) (*model.User, error) {
+	user := &model.User{
+		ID:          uuid.New(),
+		Handle:      newUser.Handle,
+		DisplayName: displayName(newUser),
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.handles[user.Handle]; ok {
+		return nil, ErrAlreadyExists
+	}
+	s.users[user.ID] = user
+	s.handles[user.Handle] = user.ID
+	return user, nil
+}
+
+func (s *inmemStorage) GetUserByID(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return user, nil
+}
+
+func (s *inmemStorage) GetUserByHandle(ctx context.Context, handle string) (*model.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.handles[handle]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return s.users[id], nil
+}
+
+func (s *inmemStorage) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]*model.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]*model.User, 0, len(ids))
+	for _, id := range ids {
+		if user, ok := s.users[id]; ok {
+			users = append(users, user)
+		}
+	}
+	return users, nil
 }
 
 func (s *inmemStorage) CreatePost(ctx context.Context, newPost model.NewPost) (*model.Post, error) {
 	post := &model.Post{
-		ID:          uuid.New(),
-		Title:       newPost.Title,
-		Author:      newPost.Author,
-		Content:     newPost.Content,
-		Commentable: newPost.Commentable,
+		ID:             uuid.New(),
+		Title:          newPost.Title,
+		AuthorID:       newPost.AuthorID,
+		Content:        newPost.Content,
+		Commentable:    newPost.Commentable,
+		ModerationMode: moderationMode(newPost),
+		CreatedAt:      time.Now().UTC(),
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
+
+	if err := s.checkAuthor(post.AuthorID); err != nil {
+		return nil, err
+	}
 	s.posts = append(s.posts, post)
+	s.addFilterResults(post.ID, newPost.FilterResults)
+	s.index.add(post.ID, post.Title, post.Content)
 	return post, nil
 }
 
 func (s *inmemStorage) GetAllPosts(ctx context.Context, offset *int, limit *int) ([]*model.Post, error) {
 	s.mu.RLock()
-	defer s.mu.RUnlock()
+	posts := s.livePosts()
+	s.mu.RUnlock()
+
+	for i, post := range posts {
+		posts[i] = s.opts.withAutoLock(post)
+	}
 
 	var off int
-	if offset != nil && *offset < len(s.posts) {
-		off = *offset
+	if offset != nil {
+		off = min(max(*offset, 0), len(posts))
 	}
-	lim := len(s.posts)
-	if limit != nil && *limit <= len(s.posts) {
-		lim = *limit
+	end := len(posts)
+	if limit != nil {
+		end = min(off+max(*limit, 0), len(posts))
 	}
+	return posts[off:end], nil
+}
+
+func (s *inmemStorage) GetPostsPage(ctx context.Context, page model.PageArgs) (*model.PostPage, error) {
+	s.mu.RLock()
+	sorted := s.livePosts()
+	s.mu.RUnlock()
 
-	if len(s.posts) == 0 {
-		return s.posts, nil
+	for i, post := range sorted {
+		sorted[i] = s.opts.withAutoLock(post)
 	}
-	return s.posts[off:lim], nil
+
+	slices.SortStableFunc(sorted, func(a, b *model.Post) int {
+		return a.Cursor().Compare(b.Cursor())
+	})
+
+	posts, hasNext, hasPrev := finishPage(selectPage(sorted, (*model.Post).Cursor, page), page)
+	return &model.PostPage{
+		Posts:           posts,
+		HasNextPage:     hasNext,
+		HasPreviousPage: hasPrev,
+		TotalCount:      len(sorted),
+	}, nil
 }
 
 func (s *inmemStorage) GetPostByID(ctx context.Context, id string) (*model.Post, error) {
+	postID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	
-	idx := slices.IndexFunc(s.posts, func(post *model.Post) bool {
-		return post.ID.String() == id
-	})
+
+	post := s.findPost(postID)
+	if post == nil {
+		return nil, ErrNotFound
+	}
+	return s.opts.withAutoLock(post), nil
+}
+
+// UpdatePost не изменяет сохранённый пост на месте, а заменяет его копией, чтобы
+// ранее выданные указатели продолжали видеть прежнюю версию.
+func (s *inmemStorage) UpdatePost(ctx context.Context, id uuid.UUID, input model.UpdatePost) (*model.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx := s.postIndex(id)
 	if idx == -1 {
 		return nil, ErrNotFound
 	}
-	return s.posts[idx], nil
+	old := s.posts[idx]
+
+	written := old.CreatedAt
+	if old.UpdatedAt != nil {
+		written = *old.UpdatedAt
+	}
+	s.revisions[id] = append(s.revisions[id], &model.PostRevision{
+		PostID:      id,
+		Version:     int32(len(s.revisions[id]) + 1),
+		Title:       old.Title,
+		Content:     old.Content,
+		Commentable: old.Commentable,
+		CreatedAt:   written,
+	})
+
+	updated := *old
+	now := time.Now().UTC()
+	s.opts.applyPostUpdate(&updated, input, now)
+	updated.UpdatedAt = &now
+	s.posts[idx] = &updated
+	s.addFilterResults(id, input.FilterResults)
+	s.index.add(id, updated.Title, updated.Content)
+	return s.opts.withAutoLock(&updated), nil
+}
+
+func (s *inmemStorage) GetPostRevisions(ctx context.Context, postID uuid.UUID) ([]*model.PostRevision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]*model.PostRevision{}, s.revisions[postID]...), nil
+}
+
+func (s *inmemStorage) GetPostFilterResults(ctx context.Context, postID uuid.UUID) ([]*model.FilterResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]*model.FilterResult{}, s.filterResults[postID]...), nil
+}
+
+// addFilterResults дописывает отметки фильтров к сохранённым для поста или комментария id.
+// Слайс копируется: прежний мог уже уйти читателям. Вызывается под s.mu.
+func (s *inmemStorage) addFilterResults(id uuid.UUID, results []*model.FilterResult) {
+	if len(results) > 0 {
+		s.filterResults[id] = append(append([]*model.FilterResult{}, s.filterResults[id]...), results...)
+	}
 }
 
 func (s *inmemStorage) CreateComment(ctx context.Context, newComment model.NewComment) (*model.Comment, error) {
 	comm := &model.Comment{
-		ID:      uuid.New(),
-		Author:  newComment.Author,
-		Content: newComment.Content,
+		ID:        uuid.New(),
+		AuthorID:  newComment.AuthorID,
+		Content:   newComment.Content,
+		CreatedAt: time.Now().UTC(),
 	}
 
-	if newComment.PostID != nil {
-		s.mu.Lock()
-		defer s.mu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-		idx := slices.IndexFunc(s.posts, func(post *model.Post) bool {
-			return post.ID.String() == *newComment.PostID
-		})
-		if idx == -1 {
+	if err := s.checkAuthor(comm.AuthorID); err != nil {
+		return nil, err
+	}
+
+	var parentID uuid.UUID
+	switch {
+	case newComment.PostID != nil:
+		post := s.findPost(*newComment.PostID)
+		if post == nil {
 			return nil, ErrNotFound
 		}
-		if !s.posts[idx].Commentable {
+		if !s.opts.withAutoLock(post).Commentable {
 			return nil, ErrNotCommentable
 		}
-		parsed, _ := uuid.Parse(*newComment.PostID)
-		comm.PostID = &parsed
-		s.posts[idx].Comments = append(s.posts[idx].Comments, comm)
-	} else if newComment.CommentID != nil {
-		s.mu.Lock()
-		defer s.mu.Unlock()
-
-		parentID := *newComment.CommentID
-		for _, post := range s.posts {
-			if insertComment(post.Comments, comm, parentID) {
-				return comm, nil
-			}
+		parentID = post.ID
+		comm.PostID = &parentID
+		comm.Status = commentStatus(post.ModerationMode)
+
+	case newComment.CommentID != nil:
+		parent, ok := s.comments[*newComment.CommentID]
+		if !ok || parent.DeletedAt != nil || parent.Status == model.CommentStatusPending {
+			return nil, ErrNotFound
 		}
-		return nil, ErrNotFound
-	} else {
+		post := s.findPost(*parent.PostID)
+		if post == nil {
+			return nil, ErrNotFound
+		}
+		if !s.opts.withAutoLock(post).Commentable {
+			return nil, ErrNotCommentable
+		}
+		parentID = parent.ID
+		comm.PostID = parent.PostID
+		comm.ParentID = &parentID
+		comm.Status = commentStatus(post.ModerationMode)
+
+	default:
 		return nil, ErrBadRequest
 	}
 
+	s.comments[comm.ID] = comm
+	s.children[parentID] = append(s.children[parentID], comm)
+	s.addFilterResults(comm.ID, newComment.FilterResults)
+	if comm.Status == model.CommentStatusPublished {
+		s.index.add(comm.ID, "", comm.Content)
+	}
 	return comm, nil
 }
 
-func insertComment(comments []*model.Comment, newComment *model.Comment, parentId string) bool {
-	for _, comment := range comments {
-		if comment.ID.String() == parentId {
-			newComment.PostID = comment.PostID
-			comment.Comments = append(comment.Comments, newComment)
-			return true
+// SetCommentable открывает или закрывает пост для комментариев. Закрытие запоминает
+// время и причину, открытие их сбрасывает и отменяет автозакрытие поста.
+func (s *inmemStorage) SetCommentable(ctx context.Context, postID uuid.UUID, value bool, reason *string) (*model.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx := s.postIndex(postID)
+	if idx == -1 {
+		return nil, ErrNotFound
+	}
+
+	updated := *s.posts[idx]
+	setCommentable(&updated, value, reason, time.Now().UTC())
+	s.posts[idx] = &updated
+	return s.opts.withAutoLock(&updated), nil
+}
+
+// DeletePost помечает пост удалённым; он пропадает из выдачи вместе с комментариями.
+func (s *inmemStorage) DeletePost(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx := s.postIndex(id)
+	if idx == -1 {
+		return ErrNotFound
+	}
+
+	deleted := *s.posts[idx]
+	now := time.Now().UTC()
+	deleted.DeletedAt = &now
+	s.posts[idx] = &deleted
+	s.index.remove(id)
+	return nil
+}
+
+func (s *inmemStorage) VotePost(ctx context.Context, postID uuid.UUID, userID uuid.UUID, value int) (*model.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx := s.postIndex(postID)
+	if _, ok := s.users[userID]; idx == -1 || !ok {
+		return nil, ErrNotFound
+	}
+
+	updated := *s.posts[idx]
+	up, down := s.castVote(postID, userID, value)
+	updated.Upvotes += up
+	updated.Downvotes += down
+	s.posts[idx] = &updated
+	return s.opts.withAutoLock(&updated), nil
+}
+
+// checkAuthor проверяет, что автор существует и не заблокирован; вызывающий должен
+// удерживать s.mu.
+func (s *inmemStorage) checkAuthor(id uuid.UUID) error {
+	user, ok := s.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if user.BannedAt != nil {
+		return ErrBanned
+	}
+	return nil
+}
+
+// postIndex возвращает индекс неудалённого поста или -1; вызывающий должен удерживать s.mu.
+func (s *inmemStorage) postIndex(id uuid.UUID) int {
+	return slices.IndexFunc(s.posts, func(post *model.Post) bool {
+		return post.ID == id && post.DeletedAt == nil
+	})
+}
+
+// findPost ищет неудалённый пост по ID; вызывающий должен удерживать s.mu.
+func (s *inmemStorage) findPost(id uuid.UUID) *model.Post {
+	idx := s.postIndex(id)
+	if idx == -1 {
+		return nil
+	}
+	return s.posts[idx]
+}
+
+// livePosts возвращает копию списка неудалённых постов; вызывающий должен удерживать s.mu.
+func (s *inmemStorage) livePosts() []*model.Post {
+	posts := make([]*model.Post, 0, len(s.posts))
+	for _, post := range s.posts {
+		if post.DeletedAt == nil {
+			posts = append(posts, post)
+		}
+	}
+	return posts
+}
+
+func (s *inmemStorage) GetCommentByID(ctx context.Context, id uuid.UUID) (*model.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	comment, ok := s.comments[id]
+	if !ok || comment.DeletedAt != nil || s.findPost(*comment.PostID) == nil {
+		return nil, ErrNotFound
+	}
+	return comment, nil
+}
+
+func (s *inmemStorage) EditComment(ctx context.Context, id uuid.UUID, content string, results []*model.FilterResult) (*model.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.comments[id]
+	if !ok || old.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	if old.HiddenAt != nil {
+		return nil, ErrHidden
+	}
+
+	written := old.CreatedAt
+	if old.EditedAt != nil {
+		written = *old.EditedAt
+	}
+	s.history[id] = append(s.history[id], &model.CommentRevision{
+		CommentID: id,
+		Version:   int32(len(s.history[id]) + 1),
+		Content:   old.Content,
+		CreatedAt: written,
+	})
+
+	updated := *old
+	now := time.Now().UTC()
+	updated.Content, updated.EditedAt = content, &now
+	s.replaceComment(&updated)
+	s.addFilterResults(id, results)
+	if updated.HiddenAt == nil && updated.Status == model.CommentStatusPublished {
+		s.index.add(id, "", content)
+	}
+	return &updated, nil
+}
+
+func (s *inmemStorage) GetCommentHistory(ctx context.Context, commentID uuid.UUID) ([]*model.CommentRevision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]*model.CommentRevision{}, s.history[commentID]...), nil
+}
+
+func (s *inmemStorage) GetCommentFilterResults(ctx context.Context, commentID uuid.UUID) ([]*model.FilterResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]*model.FilterResult{}, s.filterResults[commentID]...), nil
+}
+
+// DeleteComment помечает комментарий удалённым. Пока под ним есть видимые ответы, он
+// остаётся в дереве надгробием, иначе исчезает из выдачи.
+func (s *inmemStorage) DeleteComment(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.comments[id]
+	if !ok || old.DeletedAt != nil {
+		return ErrNotFound
+	}
+
+	deleted := *old
+	now := time.Now().UTC()
+	deleted.DeletedAt = &now
+	s.replaceComment(&deleted)
+	s.index.remove(id)
+	return nil
+}
+
+func (s *inmemStorage) VoteComment(ctx context.Context, commentID uuid.UUID, userID uuid.UUID, value int) (*model.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Голосовать можно только за видимый комментарий под неудалённым постом.
+	old, ok := s.comments[commentID]
+	if _, known := s.users[userID]; !ok || !known || old.DeletedAt != nil || old.HiddenAt != nil ||
+		old.Status != model.CommentStatusPublished || s.findPost(*old.PostID) == nil {
+		return nil, ErrNotFound
+	}
+
+	updated := *old
+	up, down := s.castVote(commentID, userID, value)
+	updated.Upvotes += up
+	updated.Downvotes += down
+	s.replaceComment(&updated)
+	return &updated, nil
+}
+
+// SetCommentHidden скрывает комментарий или возвращает его в выдачу и записывает действие
+// в журнал модерации. Скрытый комментарий остаётся в дереве без текста и не ищется.
+// Повторная установка того же состояния ничего не меняет и в журнал не попадает.
+func (s *inmemStorage) SetCommentHidden(ctx context.Context, id uuid.UUID, hidden bool, moderatorID uuid.UUID, reason *string) (*model.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.comments[id]
+	if _, known := s.users[moderatorID]; !ok || !known || old.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	if (old.HiddenAt != nil) == hidden {
+		return old, nil
+	}
+
+	updated := *old
+	now := time.Now().UTC()
+	updated.HiddenAt = nil
+	if hidden {
+		updated.HiddenAt = &now
+		s.index.remove(id)
+	} else if updated.Status == model.CommentStatusPublished {
+		s.index.add(id, "", updated.Content)
+	}
+	s.replaceComment(&updated)
+	s.logAction(hideAction(hidden), id, moderatorID, reason, now)
+	return &updated, nil
+}
+
+// SetUserBanned блокирует пользователя или снимает блокировку и записывает действие в
+// журнал модерации. Заблокированный пользователь не может создавать посты и комментарии.
+func (s *inmemStorage) SetUserBanned(ctx context.Context, userID uuid.UUID, banned bool, moderatorID uuid.UUID, reason *string) (*model.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.users[userID]
+	if _, known := s.users[moderatorID]; !ok || !known {
+		return nil, ErrNotFound
+	}
+	if (old.BannedAt != nil) == banned {
+		return old, nil
+	}
+
+	updated := *old
+	now := time.Now().UTC()
+	updated.BannedAt = nil
+	if banned {
+		updated.BannedAt = &now
+	}
+	s.users[userID] = &updated
+	s.logAction(banAction(banned), userID, moderatorID, reason, now)
+	return &updated, nil
+}
+
+// ApproveComment публикует комментарий, ожидающий премодерации, и записывает действие в
+// журнал модерации. Одобрение опубликованного комментария ничего не меняет.
+func (s *inmemStorage) ApproveComment(ctx context.Context, id uuid.UUID, moderatorID uuid.UUID) (*model.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.comments[id]
+	if _, known := s.users[moderatorID]; !ok || !known || old.DeletedAt != nil || s.findPost(*old.PostID) == nil {
+		return nil, ErrNotFound
+	}
+	if old.Status == model.CommentStatusPublished {
+		return old, nil
+	}
+
+	updated := *old
+	updated.Status = model.CommentStatusPublished
+	if updated.HiddenAt == nil {
+		s.index.add(id, "", updated.Content)
+	}
+	s.replaceComment(&updated)
+	s.logAction(model.ModerationActionTypeApproveComment, id, moderatorID, nil, time.Now().UTC())
+	return &updated, nil
+}
+
+// GetPendingComments возвращает страницу комментариев, ожидающих премодерации, по всем
+// постам или только по postID.
+func (s *inmemStorage) GetPendingComments(ctx context.Context, postID *uuid.UUID, page model.PageArgs) (*model.CommentPage, error) {
+	s.mu.RLock()
+	var sorted []*model.Comment
+	for _, comment := range s.comments {
+		if comment.Status != model.CommentStatusPending || comment.DeletedAt != nil || s.findPost(*comment.PostID) == nil {
+			continue
+		}
+		if postID == nil || *comment.PostID == *postID {
+			sorted = append(sorted, comment)
+		}
+	}
+	s.mu.RUnlock()
+
+	return commentPage(sorted, page), nil
+}
+
+// GetPostComments возвращает страницу опубликованных комментариев поста вместе с ответами
+// любой глубины; удалённые и скрытые комментарии пропускаются.
+func (s *inmemStorage) GetPostComments(ctx context.Context, postID uuid.UUID, page model.PageArgs) (*model.CommentPage, error) {
+	s.mu.RLock()
+	var sorted []*model.Comment
+	if s.findPost(postID) != nil {
+		for _, comment := range s.comments {
+			if *comment.PostID == postID && comment.Status == model.CommentStatusPublished && !comment.Redacted() {
+				sorted = append(sorted, comment)
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	return commentPage(sorted, page), nil
+}
+
+// GetCommentAncestors возвращает идентификаторы предков комментария: от родителя до
+// корневого комментария ветки.
+func (s *inmemStorage) GetCommentAncestors(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	comment, ok := s.comments[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	var ancestors []uuid.UUID
+	for ok && comment.ParentID != nil {
+		ancestors = append(ancestors, *comment.ParentID)
+		comment, ok = s.comments[*comment.ParentID]
+	}
+	return ancestors, nil
+}
+
+// logAction добавляет запись в журнал модерации; вызывающий должен удерживать s.mu на запись.
+func (s *inmemStorage) logAction(action model.ModerationActionType, targetID, moderatorID uuid.UUID, reason *string, at time.Time) {
+	s.moderationLog = append(s.moderationLog, &model.ModerationAction{
+		ID:          uuid.New(),
+		ModeratorID: moderatorID,
+		Action:      action,
+		TargetID:    targetID,
+		Reason:      reason,
+		CreatedAt:   at,
+	})
+}
+
+func (s *inmemStorage) GetModerationLog(ctx context.Context, page model.PageArgs) (*model.ModerationLogPage, error) {
+	page = logPage(page)
+
+	s.mu.RLock()
+	sorted := slices.Clone(s.moderationLog)
+	s.mu.RUnlock()
+
+	slices.SortStableFunc(sorted, func(a, b *model.ModerationAction) int {
+		return b.Cursor().Compare(a.Cursor())
+	})
+
+	actions, hasNext, hasPrev := finishPage(selectPage(sorted, (*model.ModerationAction).Cursor, page), page)
+	return &model.ModerationLogPage{
+		Actions:         actions,
+		HasNextPage:     hasNext,
+		HasPreviousPage: hasPrev,
+		TotalCount:      len(sorted),
+	}, nil
+}
+
+// CreateReport сохраняет жалобу на неудалённый пост или комментарий. Пока жалоба
+// пользователя открыта, повторная на тот же объект отклоняется с ErrAlreadyExists.
+func (s *inmemStorage) CreateReport(ctx context.Context, newReport model.NewReport) (*model.Report, error) {
+	report := &model.Report{
+		ID:         uuid.New(),
+		ReporterID: newReport.ReporterID,
+		TargetType: newReport.TargetType,
+		TargetID:   newReport.TargetID,
+		Reason:     newReport.Reason,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[report.ReporterID]; !ok {
+		return nil, ErrNotFound
+	}
+	if err := s.checkReportTarget(report.TargetType, report.TargetID); err != nil {
+		return nil, err
+	}
+	for _, r := range s.reports {
+		if r.ResolvedAt == nil && r.TargetID == report.TargetID && r.ReporterID == report.ReporterID {
+			return nil, ErrAlreadyExists
+		}
+	}
+	s.reports = append(s.reports, report)
+	return report, nil
+}
+
+// checkReportTarget проверяет, что объект жалобы существует и не удалён; вызывающий
+// должен удерживать s.mu.
+func (s *inmemStorage) checkReportTarget(targetType model.ReportTargetType, id uuid.UUID) error {
+	switch targetType {
+	case model.ReportTargetTypePost:
+		if s.findPost(id) == nil {
+			return ErrNotFound
+		}
+	case model.ReportTargetTypeComment:
+		comment, ok := s.comments[id]
+		if !ok || comment.DeletedAt != nil || s.findPost(*comment.PostID) == nil {
+			return ErrNotFound
+		}
+	default:
+		return ErrBadRequest
+	}
+	return nil
+}
+
+// GetReportQueue группирует открытые жалобы по объекту; первыми идут объекты с
+// наибольшим числом жалоб, при равенстве — с самой свежей жалобой.
+func (s *inmemStorage) GetReportQueue(ctx context.Context, limit, offset int) ([]*model.ReportQueueItem, error) {
+	s.mu.RLock()
+	byTarget := make(map[uuid.UUID]*model.ReportQueueItem)
+	var items []*model.ReportQueueItem
+	for _, r := range s.reports {
+		if r.ResolvedAt != nil {
+			continue
+		}
+		item, ok := byTarget[r.TargetID]
+		if !ok {
+			item = &model.ReportQueueItem{TargetType: r.TargetType, TargetID: r.TargetID, FirstReportedAt: r.CreatedAt}
+			byTarget[r.TargetID] = item
+			items = append(items, item)
+		}
+		item.Reports = append(item.Reports, r)
+		item.ReportCount++
+		item.LastReportedAt = r.CreatedAt
+	}
+	s.mu.RUnlock()
+
+	slices.SortFunc(items, compareQueueItems)
+	return items[min(offset, len(items)):min(offset+limit, len(items))], nil
+}
+
+// ResolveReports закрывает все открытые жалобы на объект решением модератора и
+// возвращает их; ErrNotFound — открытых жалоб нет.
+func (s *inmemStorage) ResolveReports(ctx context.Context, targetType model.ReportTargetType, targetID uuid.UUID, moderatorID uuid.UUID, resolution model.ReportAction) ([]*model.Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	var resolved []*model.Report
+	for i, r := range s.reports {
+		if r.ResolvedAt != nil || r.TargetType != targetType || r.TargetID != targetID {
+			continue
+		}
+		updated := *r
+		updated.ResolvedAt, updated.ResolverID, updated.Resolution = &now, &moderatorID, &resolution
+		s.reports[i] = &updated
+		resolved = append(resolved, &updated)
+	}
+	if len(resolved) == 0 {
+		return nil, ErrNotFound
+	}
+	return resolved, nil
+}
+
+// castVote запоминает голос пользователя и возвращает изменение счётчиков; вызывающий
+// должен удерживать s.mu на запись.
+func (s *inmemStorage) castVote(targetID uuid.UUID, userID uuid.UUID, value int) (int32, int32) {
+	if s.votes[targetID] == nil {
+		s.votes[targetID] = make(map[uuid.UUID]int)
+	}
+	prev := s.votes[targetID][userID]
+	s.votes[targetID][userID] = value
+	return voteDelta(prev, value)
+}
+
+// visible сообщает, нужно ли показывать комментарий: удалённый остаётся видимым, только
+// если видим хотя бы один из его потомков. Вызывающий должен удерживать s.mu.
+func (s *inmemStorage) visible(comment *model.Comment) bool {
+	if comment.Status == model.CommentStatusPending {
+		return false
+	}
+	if comment.DeletedAt == nil {
+		return true
+	}
+	return slices.ContainsFunc(s.children[comment.ID], s.visible)
+}
+
+// replaceComment подменяет комментарий в индексе и в дереве новой копией;
+// вызывающий должен удерживать s.mu на запись.
+func (s *inmemStorage) replaceComment(comment *model.Comment) {
+	s.comments[comment.ID] = comment
+
+	parentID := *comment.PostID
+	if comment.ParentID != nil {
+		parentID = *comment.ParentID
+	}
+	siblings := s.children[parentID]
+	if idx := slices.IndexFunc(siblings, func(c *model.Comment) bool { return c.ID == comment.ID }); idx != -1 {
+		siblings[idx] = comment
+	}
+}
+
+func (s *inmemStorage) GetPostsByIDs(ctx context.Context, ids []uuid.UUID) ([]*model.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	posts := make([]*model.Post, 0, len(ids))
+	for _, id := range ids {
+		if post := s.findPost(id); post != nil {
+			posts = append(posts, s.opts.withAutoLock(post))
 		}
+	}
+	return posts, nil
+}
+
+func (s *inmemStorage) GetCommentsByPostIDs(ctx context.Context, postIDs []uuid.UUID, page model.PageArgs) (map[uuid.UUID]*model.CommentPage, error) {
+	pages := make(map[uuid.UUID]*model.CommentPage, len(postIDs))
+	for _, id := range postIDs {
+		pages[id] = s.commentsPage(id, page)
+	}
+	return pages, nil
+}
+
+func (s *inmemStorage) GetRepliesByCommentIDs(ctx context.Context, commentIDs []uuid.UUID, page model.PageArgs) (map[uuid.UUID]*model.CommentPage, error) {
+	pages := make(map[uuid.UUID]*model.CommentPage, len(commentIDs))
+	for _, id := range commentIDs {
+		pages[id] = s.commentsPage(id, page)
+	}
+	return pages, nil
+}
 
-		if insertComment(comment.Comments, newComment, parentId) {
-			return true
+// commentsPage возвращает страницу непосредственных потомков узла дерева комментариев.
+func (s *inmemStorage) commentsPage(parentID uuid.UUID, page model.PageArgs) *model.CommentPage {
+	s.mu.RLock()
+	sorted := make([]*model.Comment, 0, len(s.children[parentID]))
+	for _, comment := range s.children[parentID] {
+		if s.visible(comment) {
+			sorted = append(sorted, comment)
 		}
 	}
+	s.mu.RUnlock()
+
+	return commentPage(sorted, page)
+}
 
-	return false
+// commentPage упорядочивает комментарии по page.Sort и выбирает из них страницу.
+func commentPage(sorted []*model.Comment, page model.PageArgs) *model.CommentPage {
+	cursor := func(c *model.Comment) model.Cursor {
+		return c.Cursor(page.Sort)
+	}
+	slices.SortStableFunc(sorted, func(a, b *model.Comment) int {
+		return compareInOrder(cursor(a), cursor(b), page.Sort)
+	})
+
+	comments, hasNext, hasPrev := finishPage(selectPage(sorted, cursor, page), page)
+	return &model.CommentPage{
+		Comments:        comments,
+		HasNextPage:     hasNext,
+		HasPreviousPage: hasPrev,
+		TotalCount:      len(sorted),
+	}
+}
+
+// Search ищет посты и комментарии, содержащие все слова запроса, по обратному индексу.
+// Комментарии удалённых постов не возвращаются.
+func (s *inmemStorage) Search(ctx context.Context, query string, limit, offset int) (*model.SearchPage, error) {
+	terms := tokenize(query)
+
+	type found struct {
+		hit    *model.SearchHit
+		cursor model.Cursor
+	}
+	var matches []found
+
+	s.mu.RLock()
+	for id, rank := range s.index.match(terms) {
+		if post := s.findPost(id); post != nil {
+			matches = append(matches, found{
+				hit: &model.SearchHit{
+					Node:    s.opts.withAutoLock(post),
+					Snippet: highlight(post.Title+" "+post.Content, terms),
+					Rank:    rank,
+				},
+				cursor: post.Cursor(),
+			})
+			continue
+		}
+		if comment, ok := s.comments[id]; ok && s.findPost(*comment.PostID) != nil {
+			matches = append(matches, found{
+				hit: &model.SearchHit{
+					Node:    comment,
+					Snippet: highlight(comment.Content, terms),
+					Rank:    rank,
+				},
+				cursor: comment.Cursor(model.CommentSortOld),
+			})
+		}
+	}
+	s.mu.RUnlock()
+
+	// Как и в PostgresStorage: по убыванию ранга, затем сначала новые.
+	slices.SortFunc(matches, func(a, b found) int {
+		if a.hit.Rank != b.hit.Rank {
+			if a.hit.Rank > b.hit.Rank {
+				return -1
+			}
+			return 1
+		}
+		return b.cursor.Compare(a.cursor)
+	})
+
+	page := &model.SearchPage{Hits: []*model.SearchHit{}, TotalCount: len(matches)}
+	for _, m := range matches[min(offset, len(matches)):min(offset+limit, len(matches))] {
+		page.Hits = append(page.Hits, m.hit)
+	}
+	return page, nil
 }
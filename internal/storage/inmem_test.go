@@ -2,8 +2,10 @@ package storage
 
 import (
 	"context"
+	"fmt"
 	"graphql_project/internal/graph/model"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -13,11 +15,12 @@ import (
 func TestCreatePost(t *testing.T) {
 	s := NewInMemStorage()
 	ctx := context.Background()
+	author := createUser(t, s, "author").ID
 
 	t.Run("successful creation", func(t *testing.T) {
 		newPost := model.NewPost{
 			Title:       "Test Post",
-			Author:      "Author",
+			AuthorID:    author,
 			Content:     "Content",
 			Commentable: true,
 		}
@@ -26,10 +29,53 @@ func TestCreatePost(t *testing.T) {
 		require.NoError(t, err)
 		assert.NotEqual(t, uuid.Nil, post.ID)
 		assert.Equal(t, newPost.Title, post.Title)
-		assert.Equal(t, newPost.Author, post.Author)
+		assert.Equal(t, newPost.AuthorID, post.AuthorID)
 		assert.Equal(t, newPost.Content, post.Content)
 		assert.Equal(t, 1, len(s.posts))
 	})
+
+	t.Run("unknown author", func(t *testing.T) {
+		_, err := s.CreatePost(ctx, model.NewPost{Title: "Post", AuthorID: uuid.New()})
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestUsers(t *testing.T) {
+	s := NewInMemStorage()
+	ctx := context.Background()
+
+	name := "Alice Liddell"
+	alice, err := s.CreateUser(ctx, model.NewUser{Handle: "alice", DisplayName: &name})
+	require.NoError(t, err)
+	assert.Equal(t, name, alice.DisplayName)
+
+	bob := createUser(t, s, "bob")
+	assert.Equal(t, "bob", bob.DisplayName, "display name defaults to handle")
+
+	_, err = s.CreateUser(ctx, model.NewUser{Handle: "alice"})
+	assert.ErrorIs(t, err, ErrAlreadyExists)
+
+	found, err := s.GetUserByHandle(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, alice.ID, found.ID)
+
+	found, err = s.GetUserByID(ctx, bob.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "bob", found.Handle)
+
+	_, err = s.GetUserByHandle(ctx, "carol")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	users, err := s.GetUsersByIDs(ctx, []uuid.UUID{bob.ID, uuid.New(), alice.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []*model.User{bob, alice}, users)
+}
+
+func createUser(t *testing.T, s *inmemStorage, handle string) *model.User {
+	t.Helper()
+	user, err := s.CreateUser(context.Background(), model.NewUser{Handle: handle})
+	require.NoError(t, err)
+	return user
 }
 
 func TestGetAllPosts(t *testing.T) {
@@ -40,7 +86,7 @@ func TestGetAllPosts(t *testing.T) {
 		s.posts = append(s.posts, &model.Post{
 			ID:          uuid.New(),
 			Title:       "Post",
-			Author:      "Author",
+			AuthorID:    uuid.New(),
 			Content:     "Content",
 			Commentable: true,
 		})
@@ -51,6 +97,88 @@ func TestGetAllPosts(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, 5, len(posts))
 	})
+
+	t.Run("offset and limit", func(t *testing.T) {
+		offset, limit := 1, 3
+		posts, err := s.GetAllPosts(ctx, &offset, &limit)
+		require.NoError(t, err)
+		assert.Equal(t, s.posts[1:4], posts, "limit counts from the offset")
+
+		offset = 4
+		posts, err = s.GetAllPosts(ctx, &offset, &limit)
+		require.NoError(t, err)
+		assert.Equal(t, s.posts[4:], posts)
+
+		offset = 10
+		posts, err = s.GetAllPosts(ctx, &offset, &limit)
+		require.NoError(t, err)
+		assert.Empty(t, posts)
+	})
+}
+
+func TestGetPostsPage(t *testing.T) {
+	s := NewInMemStorage()
+	ctx := context.Background()
+
+	base := time.Now().UTC()
+	for i := 0; i < 5; i++ {
+		s.posts = append(s.posts, &model.Post{
+			ID:        uuid.New(),
+			Title:     "Post",
+			CreatedAt: base.Add(time.Duration(4-i) * time.Second),
+		})
+	}
+
+	first := 2
+	t.Run("first page", func(t *testing.T) {
+		page, err := s.GetPostsPage(ctx, model.PageArgs{First: &first})
+		require.NoError(t, err)
+		require.Len(t, page.Posts, 2)
+		assert.Equal(t, s.posts[4].ID, page.Posts[0].ID)
+		assert.Equal(t, s.posts[3].ID, page.Posts[1].ID)
+		assert.True(t, page.HasNextPage)
+		assert.False(t, page.HasPreviousPage)
+		assert.Equal(t, 5, page.TotalCount)
+	})
+
+	t.Run("after cursor", func(t *testing.T) {
+		after := s.posts[3].Cursor()
+		page, err := s.GetPostsPage(ctx, model.PageArgs{First: &first, After: &after})
+		require.NoError(t, err)
+		require.Len(t, page.Posts, 2)
+		assert.Equal(t, s.posts[2].ID, page.Posts[0].ID)
+		assert.Equal(t, s.posts[1].ID, page.Posts[1].ID)
+		assert.True(t, page.HasNextPage)
+		assert.True(t, page.HasPreviousPage)
+	})
+
+	t.Run("last before cursor", func(t *testing.T) {
+		before := s.posts[0].Cursor()
+		page, err := s.GetPostsPage(ctx, model.PageArgs{Last: &first, Before: &before})
+		require.NoError(t, err)
+		require.Len(t, page.Posts, 2)
+		assert.Equal(t, s.posts[2].ID, page.Posts[0].ID)
+		assert.Equal(t, s.posts[1].ID, page.Posts[1].ID)
+		assert.True(t, page.HasNextPage)
+		assert.True(t, page.HasPreviousPage)
+	})
+}
+
+func TestGetPostsByIDs(t *testing.T) {
+	s := NewInMemStorage()
+	ctx := context.Background()
+	author := createUser(t, s, "author").ID
+
+	first, err := s.CreatePost(ctx, model.NewPost{AuthorID: author, Title: "First"})
+	require.NoError(t, err)
+	second, err := s.CreatePost(ctx, model.NewPost{AuthorID: author, Title: "Second"})
+	require.NoError(t, err)
+
+	posts, err := s.GetPostsByIDs(ctx, []uuid.UUID{second.ID, uuid.New(), first.ID})
+	require.NoError(t, err)
+	require.Len(t, posts, 2)
+	assert.Equal(t, second.ID, posts[0].ID)
+	assert.Equal(t, first.ID, posts[1].ID)
 }
 
 func TestGetPostByID(t *testing.T) {
@@ -60,7 +188,7 @@ func TestGetPostByID(t *testing.T) {
 	post := &model.Post{
 		ID:          uuid.New(),
 		Title:       "Test Post",
-		Author:      "Author",
+		AuthorID:    uuid.New(),
 		Content:     "Content",
 		Commentable: true,
 	}
@@ -81,33 +209,911 @@ func TestGetPostByID(t *testing.T) {
 func TestCreateComment(t *testing.T) {
 	s := NewInMemStorage()
 	ctx := context.Background()
+	author := createUser(t, s, "author").ID
 
 	post, err := s.CreatePost(ctx, model.NewPost{
 		Title:       "Test Post",
-		Author:      "Author",
+		AuthorID:    author,
 		Content:     "Content",
 		Commentable: true,
 	})
 	require.NoError(t, err)
 
-	postIDStr := post.ID.String()
+	postID := post.ID
 	comment, err := s.CreateComment(ctx, model.NewComment{
-		Author:  "Commenter",
-		Content: "Test Comment",
-		PostID:  &postIDStr,
+		AuthorID: author,
+		Content:  "Test Comment",
+		PostID:   &postID,
 	})
 	require.NoError(t, err)
 
 	assert.NotEqual(t, uuid.Nil, comment.ID)
-	assert.Equal(t, "Commenter", comment.Author)
+	assert.Equal(t, author, comment.AuthorID)
 	assert.Equal(t, "Test Comment", comment.Content)
 	assert.Equal(t, post.ID, *comment.PostID)
 
-	postFromStorage, err := s.GetPostByID(ctx, post.ID.String())
+	pages, err := s.GetCommentsByPostIDs(ctx, []uuid.UUID{post.ID}, model.PageArgs{})
 	require.NoError(t, err)
 
-	if assert.Len(t, postFromStorage.Comments, 1) {
-		assert.Equal(t, comment.ID, postFromStorage.Comments[0].ID)
-		assert.Equal(t, "Test Comment", postFromStorage.Comments[0].Content)
+	if assert.Len(t, pages[post.ID].Comments, 1) {
+		assert.Equal(t, comment.ID, pages[post.ID].Comments[0].ID)
+		assert.Equal(t, "Test Comment", pages[post.ID].Comments[0].Content)
 	}
+
+	results, err := s.GetCommentFilterResults(ctx, comment.ID)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	flag := &model.FilterResult{Filter: "repeat", Action: model.FilterActionFlag, Reason: "repeated"}
+	flagged, err := s.CreateComment(ctx, model.NewComment{
+		AuthorID:      author,
+		Content:       "Test Comment",
+		PostID:        &postID,
+		FilterResults: []*model.FilterResult{flag},
+	})
+	require.NoError(t, err)
+	results, err = s.GetCommentFilterResults(ctx, flagged.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []*model.FilterResult{flag}, results)
+}
+
+func TestCommentThreads(t *testing.T) {
+	s := NewInMemStorage()
+	ctx := context.Background()
+	author := createUser(t, s, "author").ID
+
+	post, err := s.CreatePost(ctx, model.NewPost{AuthorID: author, Title: "Post", Commentable: true})
+	require.NoError(t, err)
+	postID := post.ID
+
+	var roots []*model.Comment
+	for i := 0; i < 3; i++ {
+		c, err := s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Root", PostID: &postID})
+		require.NoError(t, err)
+		roots = append(roots, c)
+	}
+	parentID := roots[0].ID
+	reply, err := s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Reply", CommentID: &parentID})
+	require.NoError(t, err)
+	assert.Equal(t, post.ID, *reply.PostID)
+	assert.Equal(t, roots[0].ID, *reply.ParentID)
+
+	t.Run("top level only", func(t *testing.T) {
+		first := 2
+		pages, err := s.GetCommentsByPostIDs(ctx, []uuid.UUID{post.ID}, model.PageArgs{First: &first})
+		require.NoError(t, err)
+		page := pages[post.ID]
+		require.Len(t, page.Comments, 2)
+		assert.Equal(t, roots[0].ID, page.Comments[0].ID)
+		assert.Equal(t, roots[1].ID, page.Comments[1].ID)
+		assert.True(t, page.HasNextPage)
+		assert.Equal(t, 3, page.TotalCount)
+	})
+
+	t.Run("replies", func(t *testing.T) {
+		pages, err := s.GetRepliesByCommentIDs(ctx, []uuid.UUID{roots[0].ID, roots[1].ID}, model.PageArgs{})
+		require.NoError(t, err)
+		require.Len(t, pages[roots[0].ID].Comments, 1)
+		assert.Equal(t, reply.ID, pages[roots[0].ID].Comments[0].ID)
+		assert.Empty(t, pages[roots[1].ID].Comments)
+	})
+
+	t.Run("unknown parent", func(t *testing.T) {
+		missing := uuid.New()
+		_, err := s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Reply", CommentID: &missing})
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestUpdatePost(t *testing.T) {
+	s := NewInMemStorage()
+	ctx := context.Background()
+	author := createUser(t, s, "author").ID
+
+	post, err := s.CreatePost(ctx, model.NewPost{AuthorID: author, Title: "v1", Content: "Content", Commentable: true})
+	require.NoError(t, err)
+
+	title := "v2"
+	updated, err := s.UpdatePost(ctx, post.ID, model.UpdatePost{Title: &title})
+	require.NoError(t, err)
+	assert.Equal(t, "v2", updated.Title)
+	assert.Equal(t, "Content", updated.Content)
+	require.NotNil(t, updated.UpdatedAt)
+	assert.Equal(t, "v1", post.Title, "previously returned post must not change")
+
+	commentable := false
+	_, err = s.UpdatePost(ctx, post.ID, model.UpdatePost{Commentable: &commentable})
+	require.NoError(t, err)
+
+	revisions, err := s.GetPostRevisions(ctx, post.ID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.EqualValues(t, 1, revisions[0].Version)
+	assert.Equal(t, "v1", revisions[0].Title)
+	assert.Equal(t, post.CreatedAt, revisions[0].CreatedAt)
+	assert.EqualValues(t, 2, revisions[1].Version)
+	assert.True(t, revisions[1].Commentable)
+	assert.Equal(t, *updated.UpdatedAt, revisions[1].CreatedAt)
+
+	_, err = s.UpdatePost(ctx, uuid.New(), model.UpdatePost{Title: &title})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	t.Run("commentable keeps lock bookkeeping", func(t *testing.T) {
+		locked, err := s.UpdatePost(ctx, post.ID, model.UpdatePost{Commentable: &commentable})
+		require.NoError(t, err)
+		assert.False(t, locked.Commentable)
+		require.NotNil(t, locked.LockedAt, "locking through updatePost records the time")
+
+		reason := "off-topic"
+		_, err = s.SetCommentable(ctx, post.ID, false, &reason)
+		require.NoError(t, err)
+		same, err := s.UpdatePost(ctx, post.ID, model.UpdatePost{Title: &title, Commentable: &commentable})
+		require.NoError(t, err)
+		assert.Equal(t, reason, *same.LockReason, "an unchanged value keeps the lock reason")
+
+		open := true
+		opened, err := s.UpdatePost(ctx, post.ID, model.UpdatePost{Commentable: &open})
+		require.NoError(t, err)
+		assert.True(t, opened.Commentable)
+		assert.Nil(t, opened.LockedAt)
+		assert.Nil(t, opened.LockReason)
+	})
+}
+
+func TestFilterResults(t *testing.T) {
+	s := NewInMemStorage()
+	ctx := context.Background()
+	author := createUser(t, s, "author").ID
+	rewrite := &model.FilterResult{Filter: "blocklist", Action: model.FilterActionRewrite, Reason: "blocked words: darn"}
+	flag := &model.FilterResult{Filter: "repeat", Action: model.FilterActionFlag, Reason: "repeated"}
+
+	post, err := s.CreatePost(ctx, model.NewPost{AuthorID: author, Title: "Post", Commentable: true, FilterResults: []*model.FilterResult{rewrite}})
+	require.NoError(t, err)
+	title := "Edited"
+	_, err = s.UpdatePost(ctx, post.ID, model.UpdatePost{Title: &title, FilterResults: []*model.FilterResult{flag}})
+	require.NoError(t, err)
+
+	results, err := s.GetPostFilterResults(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []*model.FilterResult{rewrite, flag}, results, "edits append to the results of creation")
+
+	postID := post.ID
+	comment, err := s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "v1", PostID: &postID})
+	require.NoError(t, err)
+	_, err = s.EditComment(ctx, comment.ID, "v2", []*model.FilterResult{flag})
+	require.NoError(t, err)
+
+	results, err = s.GetCommentFilterResults(ctx, comment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []*model.FilterResult{flag}, results)
+}
+
+func TestEditComment(t *testing.T) {
+	s := NewInMemStorage()
+	ctx := context.Background()
+	author := createUser(t, s, "author").ID
+
+	post, err := s.CreatePost(ctx, model.NewPost{AuthorID: author, Title: "Post", Commentable: true})
+	require.NoError(t, err)
+	postID := post.ID
+	comment, err := s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "v1", PostID: &postID})
+	require.NoError(t, err)
+
+	edited, err := s.EditComment(ctx, comment.ID, "v2", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "v2", edited.Content)
+	require.NotNil(t, edited.EditedAt)
+	assert.Equal(t, "v1", comment.Content, "previously returned comment must not change")
+
+	pages, err := s.GetCommentsByPostIDs(ctx, []uuid.UUID{post.ID}, model.PageArgs{})
+	require.NoError(t, err)
+	require.Len(t, pages[post.ID].Comments, 1)
+	assert.Equal(t, "v2", pages[post.ID].Comments[0].Content)
+
+	_, err = s.EditComment(ctx, comment.ID, "v3", nil)
+	require.NoError(t, err)
+
+	history, err := s.GetCommentHistory(ctx, comment.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "v1", history[0].Content)
+	assert.Equal(t, comment.CreatedAt, history[0].CreatedAt)
+	assert.Equal(t, "v2", history[1].Content)
+	assert.Equal(t, *edited.EditedAt, history[1].CreatedAt)
+
+	_, err = s.EditComment(ctx, uuid.New(), "v2", nil)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = s.SetCommentHidden(ctx, comment.ID, true, author, nil)
+	require.NoError(t, err)
+	_, err = s.EditComment(ctx, comment.ID, "v4", nil)
+	assert.ErrorIs(t, err, ErrHidden)
+	history, err = s.GetCommentHistory(ctx, comment.ID)
+	require.NoError(t, err)
+	assert.Len(t, history, 2, "rejected edit must not add a revision")
+}
+
+func TestDeleteComment(t *testing.T) {
+	s := NewInMemStorage()
+	ctx := context.Background()
+	author := createUser(t, s, "author").ID
+
+	post, err := s.CreatePost(ctx, model.NewPost{AuthorID: author, Title: "Post", Commentable: true})
+	require.NoError(t, err)
+	postID := post.ID
+
+	root, err := s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Root", PostID: &postID})
+	require.NoError(t, err)
+	leaf, err := s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Leaf", PostID: &postID})
+	require.NoError(t, err)
+	rootID := root.ID
+	reply, err := s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Reply", CommentID: &rootID})
+	require.NoError(t, err)
+
+	require.NoError(t, s.DeleteComment(ctx, root.ID))
+	require.NoError(t, s.DeleteComment(ctx, leaf.ID))
+	assert.ErrorIs(t, s.DeleteComment(ctx, leaf.ID), ErrNotFound)
+
+	t.Run("tombstone keeps replies reachable", func(t *testing.T) {
+		pages, err := s.GetCommentsByPostIDs(ctx, []uuid.UUID{post.ID}, model.PageArgs{})
+		require.NoError(t, err)
+		require.Len(t, pages[post.ID].Comments, 1)
+		assert.Equal(t, root.ID, pages[post.ID].Comments[0].ID)
+		assert.NotNil(t, pages[post.ID].Comments[0].DeletedAt)
+		assert.Equal(t, 1, pages[post.ID].TotalCount)
+
+		replies, err := s.GetRepliesByCommentIDs(ctx, []uuid.UUID{root.ID}, model.PageArgs{})
+		require.NoError(t, err)
+		require.Len(t, replies[root.ID].Comments, 1)
+		assert.Equal(t, reply.ID, replies[root.ID].Comments[0].ID)
+	})
+
+	t.Run("tombstone disappears with its last reply", func(t *testing.T) {
+		require.NoError(t, s.DeleteComment(ctx, reply.ID))
+
+		pages, err := s.GetCommentsByPostIDs(ctx, []uuid.UUID{post.ID}, model.PageArgs{})
+		require.NoError(t, err)
+		assert.Empty(t, pages[post.ID].Comments)
+	})
+
+	t.Run("deleted comment cannot be edited or replied to", func(t *testing.T) {
+		_, err := s.EditComment(ctx, root.ID, "Edited", nil)
+		assert.ErrorIs(t, err, ErrNotFound)
+		_, err = s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Reply", CommentID: &rootID})
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestDeletePost(t *testing.T) {
+	s := NewInMemStorage()
+	ctx := context.Background()
+	author := createUser(t, s, "author").ID
+
+	post, err := s.CreatePost(ctx, model.NewPost{AuthorID: author, Title: "Post", Commentable: true})
+	require.NoError(t, err)
+	kept, err := s.CreatePost(ctx, model.NewPost{AuthorID: author, Title: "Kept"})
+	require.NoError(t, err)
+	postID := post.ID
+	comment, err := s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Comment", PostID: &postID})
+	require.NoError(t, err)
+
+	found, err := s.GetCommentByID(ctx, comment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, author, found.AuthorID)
+
+	require.NoError(t, s.DeletePost(ctx, post.ID))
+	assert.ErrorIs(t, s.DeletePost(ctx, post.ID), ErrNotFound)
+
+	_, err = s.GetPostByID(ctx, post.ID.String())
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	posts, err := s.GetAllPosts(ctx, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, posts, 1)
+	assert.Equal(t, kept.ID, posts[0].ID)
+
+	page, err := s.GetPostsPage(ctx, model.PageArgs{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, page.TotalCount)
+
+	_, err = s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Comment", PostID: &postID})
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = s.GetCommentByID(ctx, comment.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "comments of a deleted post are gone")
+}
+
+func TestSetCommentable(t *testing.T) {
+	s := NewInMemStorage()
+	ctx := context.Background()
+	author := createUser(t, s, "author").ID
+
+	post, err := s.CreatePost(ctx, model.NewPost{AuthorID: author, Title: "Post", Commentable: true})
+	require.NoError(t, err)
+	postID := post.ID
+
+	reason := "off-topic"
+	locked, err := s.SetCommentable(ctx, post.ID, false, &reason)
+	require.NoError(t, err)
+	assert.False(t, locked.Commentable)
+	require.NotNil(t, locked.LockedAt)
+	assert.Equal(t, reason, *locked.LockReason)
+
+	_, err = s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Comment", PostID: &postID})
+	assert.ErrorIs(t, err, ErrNotCommentable)
+
+	unlocked, err := s.SetCommentable(ctx, post.ID, true, nil)
+	require.NoError(t, err)
+	assert.True(t, unlocked.Commentable)
+	assert.Nil(t, unlocked.LockedAt)
+	assert.Nil(t, unlocked.LockReason)
+
+	_, err = s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Comment", PostID: &postID})
+	assert.NoError(t, err)
+
+	_, err = s.SetCommentable(ctx, uuid.New(), false, nil)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestAutoLock(t *testing.T) {
+	s := NewInMemStorage(WithAutoLock(24 * time.Hour))
+	ctx := context.Background()
+	author := createUser(t, s, "author").ID
+
+	fresh, err := s.CreatePost(ctx, model.NewPost{AuthorID: author, Title: "Fresh", Commentable: true})
+	require.NoError(t, err)
+	old, err := s.CreatePost(ctx, model.NewPost{AuthorID: author, Title: "Old", Commentable: true})
+	require.NoError(t, err)
+	s.posts[1].CreatedAt = time.Now().UTC().Add(-48 * time.Hour)
+
+	got, err := s.GetPostByID(ctx, old.ID.String())
+	require.NoError(t, err)
+	assert.False(t, got.Commentable)
+	require.NotNil(t, got.LockedAt)
+	assert.Equal(t, s.posts[1].CreatedAt.Add(24*time.Hour), *got.LockedAt)
+	assert.Equal(t, AutoLockReason, *got.LockReason)
+	assert.True(t, s.posts[1].Commentable, "stored post must not change")
+
+	oldID, freshID := old.ID, fresh.ID
+	_, err = s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Comment", PostID: &oldID})
+	assert.ErrorIs(t, err, ErrNotCommentable)
+	_, err = s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Comment", PostID: &freshID})
+	assert.NoError(t, err)
+
+	t.Run("explicit unlock overrides auto-lock", func(t *testing.T) {
+		unlocked, err := s.SetCommentable(ctx, old.ID, true, nil)
+		require.NoError(t, err)
+		assert.True(t, unlocked.Commentable)
+		assert.Nil(t, unlocked.LockedAt)
+		_, err = s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Comment", PostID: &oldID})
+		assert.NoError(t, err)
+
+		reason := "off-topic"
+		_, err = s.SetCommentable(ctx, old.ID, false, &reason)
+		require.NoError(t, err)
+		_, err = s.SetCommentable(ctx, old.ID, true, nil)
+		require.NoError(t, err)
+		got, err := s.GetPostByID(ctx, old.ID.String())
+		require.NoError(t, err)
+		assert.True(t, got.Commentable, "a lock and unlock again keeps the post open")
+	})
+
+	t.Run("updatePost unlock overrides auto-lock", func(t *testing.T) {
+		stale, err := s.CreatePost(ctx, model.NewPost{AuthorID: author, Title: "Stale", Commentable: true})
+		require.NoError(t, err)
+		s.posts[len(s.posts)-1].CreatedAt = time.Now().UTC().Add(-48 * time.Hour)
+
+		open := true
+		updated, err := s.UpdatePost(ctx, stale.ID, model.UpdatePost{Commentable: &open})
+		require.NoError(t, err)
+		assert.True(t, updated.Commentable)
+		assert.Nil(t, updated.LockedAt)
+	})
+}
+
+func TestVotes(t *testing.T) {
+	s := NewInMemStorage()
+	ctx := context.Background()
+	author := createUser(t, s, "author").ID
+
+	post, err := s.CreatePost(ctx, model.NewPost{AuthorID: author, Title: "Post", Commentable: true})
+	require.NoError(t, err)
+	postID := post.ID
+	comment, err := s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Comment", PostID: &postID})
+	require.NoError(t, err)
+	alice, bob := createUser(t, s, "alice").ID, createUser(t, s, "bob").ID
+
+	t.Run("one vote per user", func(t *testing.T) {
+		_, err := s.VotePost(ctx, post.ID, alice, model.VoteUp)
+		require.NoError(t, err)
+		voted, err := s.VotePost(ctx, post.ID, alice, model.VoteUp)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, voted.Upvotes)
+
+		voted, err = s.VotePost(ctx, post.ID, bob, model.VoteDown)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, voted.Downvotes)
+		assert.EqualValues(t, 0, voted.Score())
+
+		voted, err = s.VotePost(ctx, post.ID, alice, model.VoteDown)
+		require.NoError(t, err)
+		assert.EqualValues(t, 0, voted.Upvotes)
+		assert.EqualValues(t, 2, voted.Downvotes)
+
+		got, err := s.GetPostByID(ctx, postID.String())
+		require.NoError(t, err)
+		assert.EqualValues(t, -2, got.Score())
+	})
+
+	t.Run("comment", func(t *testing.T) {
+		voted, err := s.VoteComment(ctx, comment.ID, alice, model.VoteUp)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, voted.Score())
+
+		pages, err := s.GetCommentsByPostIDs(ctx, []uuid.UUID{post.ID}, model.PageArgs{})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, pages[post.ID].Comments[0].Upvotes)
+	})
+
+	t.Run("unknown target", func(t *testing.T) {
+		_, err := s.VotePost(ctx, uuid.New(), alice, model.VoteUp)
+		assert.ErrorIs(t, err, ErrNotFound)
+		_, err = s.VoteComment(ctx, uuid.New(), alice, model.VoteUp)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		_, err := s.VotePost(ctx, post.ID, uuid.New(), model.VoteUp)
+		assert.ErrorIs(t, err, ErrNotFound)
+		_, err = s.VoteComment(ctx, comment.ID, uuid.New(), model.VoteUp)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("hidden comment", func(t *testing.T) {
+		hidden, err := s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Hidden", PostID: &postID})
+		require.NoError(t, err)
+		_, err = s.SetCommentHidden(ctx, hidden.ID, true, author, nil)
+		require.NoError(t, err)
+		_, err = s.VoteComment(ctx, hidden.ID, alice, model.VoteUp)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("pending comment", func(t *testing.T) {
+		premoderated, err := s.CreatePost(ctx, model.NewPost{AuthorID: author, Title: "Board", Commentable: true, ModerationMode: model.ModerationModePre})
+		require.NoError(t, err)
+		pending, err := s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Pending", PostID: &premoderated.ID})
+		require.NoError(t, err)
+		_, err = s.VoteComment(ctx, pending.ID, alice, model.VoteUp)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("comment under a deleted post", func(t *testing.T) {
+		require.NoError(t, s.DeletePost(ctx, post.ID))
+		_, err := s.VoteComment(ctx, comment.ID, bob, model.VoteUp)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestCommentSort(t *testing.T) {
+	s := NewInMemStorage()
+	ctx := context.Background()
+	author := createUser(t, s, "author").ID
+
+	post, err := s.CreatePost(ctx, model.NewPost{AuthorID: author, Title: "Post", Commentable: true})
+	require.NoError(t, err)
+	postID := post.ID
+
+	var comments []*model.Comment
+	for i := 0; i < 4; i++ {
+		c, err := s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Comment", PostID: &postID})
+		require.NoError(t, err)
+		comments = append(comments, c)
+	}
+	voters := make([]uuid.UUID, 8)
+	for i := range voters {
+		voters[i] = createUser(t, s, fmt.Sprintf("voter-%d", i)).ID
+	}
+	vote := func(c *model.Comment, up, down int) {
+		for i := 0; i < up; i++ {
+			_, err := s.VoteComment(ctx, c.ID, voters[i], model.VoteUp)
+			require.NoError(t, err)
+		}
+		for i := 0; i < down; i++ {
+			_, err := s.VoteComment(ctx, c.ID, voters[up+i], model.VoteDown)
+			require.NoError(t, err)
+		}
+	}
+	vote(comments[1], 5, 0)
+	vote(comments[2], 3, 3)
+	vote(comments[3], 1, 2)
+
+	ids := func(page *model.CommentPage) []uuid.UUID {
+		var ids []uuid.UUID
+		for _, c := range page.Comments {
+			ids = append(ids, c.ID)
+		}
+		return ids
+	}
+	list := func(sort model.CommentSort, page model.PageArgs) *model.CommentPage {
+		page.Sort = sort
+		pages, err := s.GetCommentsByPostIDs(ctx, []uuid.UUID{post.ID}, page)
+		require.NoError(t, err)
+		return pages[post.ID]
+	}
+
+	assert.Equal(t, []uuid.UUID{comments[0].ID, comments[1].ID, comments[2].ID, comments[3].ID}, ids(list(model.CommentSortOld, model.PageArgs{})))
+	assert.Equal(t, []uuid.UUID{comments[3].ID, comments[2].ID, comments[1].ID, comments[0].ID}, ids(list(model.CommentSortNew, model.PageArgs{})))
+	assert.Equal(t, []uuid.UUID{comments[1].ID, comments[2].ID, comments[0].ID, comments[3].ID}, ids(list(model.CommentSortTop, model.PageArgs{})))
+	assert.Equal(t, []uuid.UUID{comments[2].ID, comments[3].ID, comments[1].ID, comments[0].ID}, ids(list(model.CommentSortControversial, model.PageArgs{})))
+
+	t.Run("cursor pagination keeps sort", func(t *testing.T) {
+		first := 2
+		page := list(model.CommentSortTop, model.PageArgs{First: &first})
+		require.Len(t, page.Comments, 2)
+		assert.True(t, page.HasNextPage)
+
+		after := page.Comments[1].Cursor(model.CommentSortTop)
+		page = list(model.CommentSortTop, model.PageArgs{First: &first, After: &after})
+		assert.Equal(t, []uuid.UUID{comments[0].ID, comments[3].ID}, ids(page))
+		assert.False(t, page.HasNextPage)
+
+		before := page.Comments[0].Cursor(model.CommentSortTop)
+		page = list(model.CommentSortTop, model.PageArgs{Last: &first, Before: &before})
+		assert.Equal(t, []uuid.UUID{comments[1].ID, comments[2].ID}, ids(page))
+	})
+}
+
+func TestSearch(t *testing.T) {
+	s := NewInMemStorage()
+	ctx := context.Background()
+	author := createUser(t, s, "author").ID
+
+	post, err := s.CreatePost(ctx, model.NewPost{AuthorID: author, Title: "GraphQL subscriptions", Content: "Websocket transport", Commentable: true})
+	require.NoError(t, err)
+	other, err := s.CreatePost(ctx, model.NewPost{AuthorID: author, Title: "Other", Content: "Nothing about graphql here", Commentable: true})
+	require.NoError(t, err)
+	postID := post.ID
+	comment, err := s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Subscriptions need a GraphQL websocket", PostID: &postID})
+	require.NoError(t, err)
+
+	t.Run("ranked hits with snippets", func(t *testing.T) {
+		page, err := s.Search(ctx, "graphql", 10, 0)
+		require.NoError(t, err)
+		require.Len(t, page.Hits, 3)
+		assert.Equal(t, 3, page.TotalCount)
+		assert.Equal(t, post.ID, page.Hits[0].Node.(*model.Post).ID, "title match ranks first")
+		assert.Contains(t, page.Hits[0].Snippet, "<mark>GraphQL</mark>")
+	})
+
+	t.Run("all terms must match", func(t *testing.T) {
+		page, err := s.Search(ctx, "graphql websocket", 10, 0)
+		require.NoError(t, err)
+		require.Len(t, page.Hits, 2)
+		assert.Equal(t, comment.ID, page.Hits[1].Node.(*model.Comment).ID)
+	})
+
+	t.Run("offset", func(t *testing.T) {
+		page, err := s.Search(ctx, "graphql", 1, 2)
+		require.NoError(t, err)
+		require.Len(t, page.Hits, 1)
+		assert.Equal(t, 3, page.TotalCount)
+	})
+
+	t.Run("index follows edits and deletes", func(t *testing.T) {
+		_, err := s.EditComment(ctx, comment.ID, "Rewritten", nil)
+		require.NoError(t, err)
+		require.NoError(t, s.DeletePost(ctx, other.ID))
+
+		page, err := s.Search(ctx, "graphql", 10, 0)
+		require.NoError(t, err)
+		require.Len(t, page.Hits, 1)
+		assert.Equal(t, post.ID, page.Hits[0].Node.(*model.Post).ID)
+
+		page, err = s.Search(ctx, "rewritten", 10, 0)
+		require.NoError(t, err)
+		assert.Len(t, page.Hits, 1)
+	})
+}
+
+func TestHighlight(t *testing.T) {
+	snippet := highlight(`<script>alert("graphql")</script> & GraphQL <b>rocks</b>`, []string{"graphql"})
+	assert.Equal(t, `<mark>&lt;script&gt;alert(&#34;graphql&#34;)&lt;/script&gt;</mark> &amp; <mark>GraphQL</mark> &lt;b&gt;rocks&lt;/b&gt;`, snippet)
+}
+
+func TestModeration(t *testing.T) {
+	s := NewInMemStorage()
+	ctx := context.Background()
+	author := createUser(t, s, "author").ID
+	moderator := createUser(t, s, "moderator").ID
+
+	post, err := s.CreatePost(ctx, model.NewPost{AuthorID: author, Title: "Post", Commentable: true})
+	require.NoError(t, err)
+	postID := post.ID
+	comment, err := s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Buy cheap pills", PostID: &postID})
+	require.NoError(t, err)
+
+	t.Run("hide and restore comment", func(t *testing.T) {
+		reason := "spam"
+		hidden, err := s.SetCommentHidden(ctx, comment.ID, true, moderator, &reason)
+		require.NoError(t, err)
+		assert.NotNil(t, hidden.HiddenAt)
+		assert.Nil(t, comment.HiddenAt, "previously returned comment is not modified")
+
+		pages, err := s.GetCommentsByPostIDs(ctx, []uuid.UUID{post.ID}, model.PageArgs{})
+		require.NoError(t, err)
+		require.Len(t, pages[post.ID].Comments, 1, "hidden comment stays in the thread")
+		page, err := s.Search(ctx, "pills", 10, 0)
+		require.NoError(t, err)
+		assert.Empty(t, page.Hits, "hidden comment is not searchable")
+
+		_, err = s.SetCommentHidden(ctx, comment.ID, true, moderator, &reason)
+		require.NoError(t, err)
+
+		restored, err := s.SetCommentHidden(ctx, comment.ID, false, moderator, nil)
+		require.NoError(t, err)
+		assert.Nil(t, restored.HiddenAt)
+		page, err = s.Search(ctx, "pills", 10, 0)
+		require.NoError(t, err)
+		assert.Len(t, page.Hits, 1)
+	})
+
+	t.Run("banned author cannot post", func(t *testing.T) {
+		banned, err := s.SetUserBanned(ctx, author, true, moderator, nil)
+		require.NoError(t, err)
+		assert.NotNil(t, banned.BannedAt)
+
+		_, err = s.CreatePost(ctx, model.NewPost{AuthorID: author, Title: "Again"})
+		assert.ErrorIs(t, err, ErrBanned)
+		_, err = s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Again", PostID: &postID})
+		assert.ErrorIs(t, err, ErrBanned)
+
+		_, err = s.SetUserBanned(ctx, author, false, moderator, nil)
+		require.NoError(t, err)
+		_, err = s.CreatePost(ctx, model.NewPost{AuthorID: author, Title: "Again"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("unknown target", func(t *testing.T) {
+		_, err := s.SetCommentHidden(ctx, uuid.New(), true, moderator, nil)
+		assert.ErrorIs(t, err, ErrNotFound)
+		_, err = s.SetUserBanned(ctx, uuid.New(), true, moderator, nil)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("log", func(t *testing.T) {
+		first := 3
+		page, err := s.GetModerationLog(ctx, model.PageArgs{First: &first})
+		require.NoError(t, err)
+		assert.Equal(t, 4, page.TotalCount, "repeated hide is not logged")
+		require.Len(t, page.Actions, 3)
+		assert.True(t, page.HasNextPage)
+
+		actions := make([]model.ModerationActionType, len(page.Actions))
+		for i, action := range page.Actions {
+			actions[i] = action.Action
+			assert.Equal(t, moderator, action.ModeratorID)
+		}
+		assert.Equal(t, []model.ModerationActionType{
+			model.ModerationActionTypeUnbanAuthor,
+			model.ModerationActionTypeBanAuthor,
+			model.ModerationActionTypeRestoreComment,
+		}, actions, "newest first")
+
+		after := page.Actions[2].Cursor()
+		page, err = s.GetModerationLog(ctx, model.PageArgs{First: &first, After: &after})
+		require.NoError(t, err)
+		require.Len(t, page.Actions, 1)
+		assert.Equal(t, model.ModerationActionTypeHideComment, page.Actions[0].Action)
+		assert.Equal(t, "spam", *page.Actions[0].Reason)
+	})
+}
+
+func TestGetPostComments(t *testing.T) {
+	s := NewInMemStorage()
+	ctx := context.Background()
+	author := createUser(t, s, "author").ID
+
+	post, err := s.CreatePost(ctx, model.NewPost{AuthorID: author, Title: "Post", Commentable: true})
+	require.NoError(t, err)
+	other, err := s.CreatePost(ctx, model.NewPost{AuthorID: author, Title: "Other", Commentable: true})
+	require.NoError(t, err)
+	postID, otherID := post.ID, other.ID
+
+	root, err := s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Root", PostID: &postID})
+	require.NoError(t, err)
+	reply, err := s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Reply", CommentID: &root.ID})
+	require.NoError(t, err)
+	deleted, err := s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Deleted", PostID: &postID})
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteComment(ctx, deleted.ID))
+	last, err := s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Last", PostID: &postID})
+	require.NoError(t, err)
+	_, err = s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Elsewhere", PostID: &otherID})
+	require.NoError(t, err)
+
+	after := root.Cursor(model.CommentSortOld)
+	page, err := s.GetPostComments(ctx, post.ID, model.PageArgs{After: &after, Sort: model.CommentSortOld})
+	require.NoError(t, err)
+	require.Len(t, page.Comments, 2, "replies are included, deleted comments and other posts are not")
+	assert.Equal(t, reply.ID, page.Comments[0].ID)
+	assert.Equal(t, last.ID, page.Comments[1].ID)
+}
+
+func TestGetCommentAncestors(t *testing.T) {
+	s := NewInMemStorage()
+	ctx := context.Background()
+	author := createUser(t, s, "author").ID
+
+	post, err := s.CreatePost(ctx, model.NewPost{AuthorID: author, Title: "Post", Commentable: true})
+	require.NoError(t, err)
+	postID := post.ID
+	root, err := s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Root", PostID: &postID})
+	require.NoError(t, err)
+	reply, err := s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Reply", CommentID: &root.ID})
+	require.NoError(t, err)
+	nested, err := s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Nested", CommentID: &reply.ID})
+	require.NoError(t, err)
+
+	ancestors, err := s.GetCommentAncestors(ctx, nested.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{reply.ID, root.ID}, ancestors)
+
+	ancestors, err = s.GetCommentAncestors(ctx, root.ID)
+	require.NoError(t, err)
+	assert.Empty(t, ancestors)
+
+	_, err = s.GetCommentAncestors(ctx, uuid.New())
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestPreModeration(t *testing.T) {
+	s := NewInMemStorage()
+	ctx := context.Background()
+	author := createUser(t, s, "author").ID
+	moderator := createUser(t, s, "moderator").ID
+
+	post, err := s.CreatePost(ctx, model.NewPost{AuthorID: author, Title: "Board", Commentable: true, ModerationMode: model.ModerationModePre})
+	require.NoError(t, err)
+	assert.Equal(t, model.ModerationModePre, post.ModerationMode)
+	other, err := s.CreatePost(ctx, model.NewPost{AuthorID: author, Title: "Open", Commentable: true})
+	require.NoError(t, err)
+	assert.Equal(t, model.ModerationModeNone, other.ModerationMode)
+
+	postID, otherID := post.ID, other.ID
+	pending, err := s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Awaiting approval", PostID: &postID})
+	require.NoError(t, err)
+	assert.Equal(t, model.CommentStatusPending, pending.Status)
+	published, err := s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Published", PostID: &otherID})
+	require.NoError(t, err)
+	assert.Equal(t, model.CommentStatusPublished, published.Status)
+
+	t.Run("pending comment is hidden", func(t *testing.T) {
+		pages, err := s.GetCommentsByPostIDs(ctx, []uuid.UUID{post.ID}, model.PageArgs{})
+		require.NoError(t, err)
+		assert.Empty(t, pages[post.ID].Comments)
+		assert.Zero(t, pages[post.ID].TotalCount)
+
+		page, err := s.Search(ctx, "approval", 10, 0)
+		require.NoError(t, err)
+		assert.Empty(t, page.Hits)
+
+		pendingID := pending.ID
+		_, err = s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Reply", CommentID: &pendingID})
+		assert.ErrorIs(t, err, ErrNotFound, "cannot reply to a pending comment")
+	})
+
+	t.Run("pending queue", func(t *testing.T) {
+		page, err := s.GetPendingComments(ctx, nil, model.PageArgs{})
+		require.NoError(t, err)
+		require.Len(t, page.Comments, 1)
+		assert.Equal(t, pending.ID, page.Comments[0].ID)
+
+		page, err = s.GetPendingComments(ctx, &other.ID, model.PageArgs{})
+		require.NoError(t, err)
+		assert.Empty(t, page.Comments)
+	})
+
+	t.Run("approve", func(t *testing.T) {
+		approved, err := s.ApproveComment(ctx, pending.ID, moderator)
+		require.NoError(t, err)
+		assert.Equal(t, model.CommentStatusPublished, approved.Status)
+		assert.Equal(t, model.CommentStatusPending, pending.Status, "previously returned comment is not modified")
+
+		pages, err := s.GetCommentsByPostIDs(ctx, []uuid.UUID{post.ID}, model.PageArgs{})
+		require.NoError(t, err)
+		require.Len(t, pages[post.ID].Comments, 1)
+		search, err := s.Search(ctx, "approval", 10, 0)
+		require.NoError(t, err)
+		assert.Len(t, search.Hits, 1)
+		queue, err := s.GetPendingComments(ctx, nil, model.PageArgs{})
+		require.NoError(t, err)
+		assert.Empty(t, queue.Comments)
+
+		_, err = s.ApproveComment(ctx, pending.ID, moderator)
+		require.NoError(t, err)
+		log, err := s.GetModerationLog(ctx, model.PageArgs{})
+		require.NoError(t, err)
+		require.Len(t, log.Actions, 1, "repeated approval is not logged")
+		assert.Equal(t, model.ModerationActionTypeApproveComment, log.Actions[0].Action)
+
+		_, err = s.ApproveComment(ctx, uuid.New(), moderator)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("switching mode off keeps pending comments", func(t *testing.T) {
+		late, err := s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Late", PostID: &postID})
+		require.NoError(t, err)
+		none := model.ModerationModeNone
+		_, err = s.UpdatePost(ctx, post.ID, model.UpdatePost{ModerationMode: &none})
+		require.NoError(t, err)
+
+		fresh, err := s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Fresh", PostID: &postID})
+		require.NoError(t, err)
+		assert.Equal(t, model.CommentStatusPublished, fresh.Status)
+		queue, err := s.GetPendingComments(ctx, &post.ID, model.PageArgs{})
+		require.NoError(t, err)
+		require.Len(t, queue.Comments, 1)
+		assert.Equal(t, late.ID, queue.Comments[0].ID)
+	})
+}
+
+func TestReports(t *testing.T) {
+	s := NewInMemStorage()
+	ctx := context.Background()
+	author := createUser(t, s, "author").ID
+	readers := []uuid.UUID{createUser(t, s, "alice").ID, createUser(t, s, "bob").ID}
+	moderator := createUser(t, s, "moderator").ID
+
+	post, err := s.CreatePost(ctx, model.NewPost{AuthorID: author, Title: "Post", Commentable: true})
+	require.NoError(t, err)
+	postID := post.ID
+	comment, err := s.CreateComment(ctx, model.NewComment{AuthorID: author, Content: "Spam", PostID: &postID})
+	require.NoError(t, err)
+
+	report := func(reporter uuid.UUID, targetType model.ReportTargetType, targetID uuid.UUID) error {
+		_, err := s.CreateReport(ctx, model.NewReport{TargetType: targetType, TargetID: targetID, Reason: "spam", ReporterID: reporter})
+		return err
+	}
+	require.NoError(t, report(readers[0], model.ReportTargetTypePost, post.ID))
+	for _, reader := range readers {
+		require.NoError(t, report(reader, model.ReportTargetTypeComment, comment.ID))
+	}
+
+	t.Run("invalid reports", func(t *testing.T) {
+		assert.ErrorIs(t, report(readers[0], model.ReportTargetTypeComment, comment.ID), ErrAlreadyExists)
+		assert.ErrorIs(t, report(readers[0], model.ReportTargetTypeComment, post.ID), ErrNotFound, "target type must match")
+		assert.ErrorIs(t, report(uuid.New(), model.ReportTargetTypePost, post.ID), ErrNotFound)
+	})
+
+	t.Run("queue groups by target", func(t *testing.T) {
+		queue, err := s.GetReportQueue(ctx, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, queue, 2)
+		assert.Equal(t, comment.ID, queue[0].TargetID, "most reported first")
+		assert.EqualValues(t, 2, queue[0].ReportCount)
+		assert.Len(t, queue[0].Reports, 2)
+		assert.Equal(t, model.ReportTargetTypePost, queue[1].TargetType)
+
+		queue, err = s.GetReportQueue(ctx, 10, 1)
+		require.NoError(t, err)
+		require.Len(t, queue, 1)
+		assert.Equal(t, post.ID, queue[0].TargetID)
+	})
+
+	t.Run("resolve", func(t *testing.T) {
+		resolved, err := s.ResolveReports(ctx, model.ReportTargetTypeComment, comment.ID, moderator, model.ReportActionHide)
+		require.NoError(t, err)
+		require.Len(t, resolved, 2)
+		assert.Equal(t, moderator, *resolved[0].ResolverID)
+		assert.Equal(t, model.ReportActionHide, *resolved[0].Resolution)
+
+		_, err = s.ResolveReports(ctx, model.ReportTargetTypeComment, comment.ID, moderator, model.ReportActionDismiss)
+		assert.ErrorIs(t, err, ErrNotFound, "no open reports left")
+
+		queue, err := s.GetReportQueue(ctx, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, queue, 1)
+		assert.Equal(t, post.ID, queue[0].TargetID)
+
+		require.NoError(t, report(readers[0], model.ReportTargetTypeComment, comment.ID), "resolved report does not block a new one")
+	})
 }
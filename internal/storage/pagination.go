@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"fmt"
+	"graphql_project/internal/graph/model"
+	"slices"
+	"strings"
+)
+
+// finishPage отрезает лишний элемент, запрошенный для определения наличия следующей
+// страницы, и возвращает элементы в порядке возрастания курсора.
+// items должны быть упорядочены в направлении обхода.
+func finishPage[T any](items []T, page model.PageArgs) ([]T, bool, bool) {
+	var hasNext, hasPrev bool
+	if page.Backward() {
+		if len(items) > *page.Last {
+			hasPrev = true
+			items = items[:*page.Last]
+		}
+		slices.Reverse(items)
+		hasNext = page.Before != nil
+		return items, hasNext, hasPrev
+	}
+
+	if page.First != nil && len(items) > *page.First {
+		hasNext = true
+		items = items[:*page.First]
+	}
+	hasPrev = page.After != nil
+	return items, hasNext, hasPrev
+}
+
+// selectPage выбирает из отсортированных по курсору элементов те, что попадают в окно
+// After/Before, в порядке обхода страницы.
+func selectPage[T any](items []T, cursor func(T) model.Cursor, page model.PageArgs) []T {
+	window := make([]T, 0)
+	for _, item := range items {
+		c := cursor(item)
+		if page.After != nil && compareInOrder(c, *page.After, page.Sort) <= 0 {
+			continue
+		}
+		if page.Before != nil && compareInOrder(c, *page.Before, page.Sort) >= 0 {
+			continue
+		}
+		window = append(window, item)
+	}
+	if page.Backward() {
+		slices.Reverse(window)
+	}
+	return window
+}
+
+// compareInOrder сравнивает курсоры с учётом направления сортировки.
+func compareInOrder(a, b model.Cursor, sort model.CommentSort) int {
+	if sort.Descending() {
+		return b.Compare(a)
+	}
+	return a.Compare(b)
+}
+
+// keysetQuery дополняет запрос условиями курсора, сортировкой по ключу page.Sort и лимитом.
+// conds и args — уже имеющиеся условия WHERE и их параметры.
+func keysetQuery(query string, conds []string, args []interface{}, page model.PageArgs) (string, []interface{}) {
+	keys := "(" + strings.Join(sortKeys(page.Sort, ""), ", ") + ")"
+	after, before := ">", "<"
+	if page.Sort.Descending() {
+		after, before = before, after
+	}
+
+	if page.After != nil {
+		var cond string
+		cond, args = cursorCond(keys, after, *page.After, page.Sort, args)
+		conds = append(conds, cond)
+	}
+	if page.Before != nil {
+		var cond string
+		cond, args = cursorCond(keys, before, *page.Before, page.Sort, args)
+		conds = append(conds, cond)
+	}
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	query += " ORDER BY " + keysetOrder(page, "")
+
+	size := page.First
+	if page.Backward() {
+		size = page.Last
+	}
+	if size != nil {
+		query += fmt.Sprintf(" LIMIT $%d", len(args)+1)
+		args = append(args, *size+1)
+	}
+
+	return query, args
+}
+
+func cursorCond(keys, op string, cursor model.Cursor, sort model.CommentSort, args []interface{}) (string, []interface{}) {
+	values := []interface{}{cursor.CreatedAt, cursor.ID}
+	if len(sortKeys(sort, "")) == 3 {
+		values = append([]interface{}{cursor.Rank}, values...)
+	}
+
+	params := make([]string, len(values))
+	for i := range values {
+		params[i] = fmt.Sprintf("$%d", len(args)+i+1)
+	}
+	return keys + " " + op + " (" + strings.Join(params, ", ") + ")", append(args, values...)
+}
+
+// sortKeys возвращает выражения ключа сортировки, совпадающие с model.Cursor;
+// prefix — необязательный псевдоним таблицы вида "c.".
+func sortKeys(sort model.CommentSort, prefix string) []string {
+	keys := []string{prefix + "created_at", prefix + "id"}
+	switch sort {
+	case model.CommentSortTop:
+		return append([]string{prefix + "upvotes - " + prefix + "downvotes"}, keys...)
+	case model.CommentSortControversial:
+		return append([]string{"LEAST(" + prefix + "upvotes, " + prefix + "downvotes)"}, keys...)
+	default:
+		return keys
+	}
+}
+
+// keysetOrder возвращает порядок сортировки в направлении обхода страницы;
+// prefix — необязательный псевдоним таблицы вида "c.".
+func keysetOrder(page model.PageArgs, prefix string) string {
+	keys := sortKeys(page.Sort, prefix)
+	if page.Sort.Descending() != page.Backward() {
+		for i := range keys {
+			keys[i] += " DESC"
+		}
+	}
+	return strings.Join(keys, ", ")
+}
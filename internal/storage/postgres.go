@@ -3,174 +3,262 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"graphql_project/internal/graph/model"
+	"html"
 	"strings"
+	"time"
 )
 
+// userColumns, postColumns, commentColumns и actionColumns — порядок колонок, который
+// ожидают scanUser, scanPost, scanComment и scanAction.
+const (
+	userColumns    = "id, handle, display_name, created_at, banned_at"
+	postColumns    = "id, title, author_id, content, commentable, created_at, updated_at, locked_at, lock_reason, upvotes, downvotes, moderation_mode, unlocked_at"
+	commentColumns = "id, post_id, parent_comment_id, author_id, content, created_at, edited_at, upvotes, downvotes, deleted_at, hidden_at, status"
+	actionColumns  = "id, moderator_id, action, target_id, reason, created_at"
+	reportColumns  = "id, reporter_id, post_id, comment_id, reason, created_at, resolved_at, resolver_id, resolution"
+)
+
+// reportTargets описывает, как жалоба ссылается на объект каждого типа: column — колонка
+// reports, live — условие существования неудалённого объекта с ID $1.
+var reportTargets = map[model.ReportTargetType]struct{ column, live string }{
+	model.ReportTargetTypePost: {
+		column: "post_id",
+		live:   "SELECT 1 FROM posts WHERE id = $1 AND deleted_at IS NULL",
+	},
+	model.ReportTargetTypeComment: {
+		column: "comment_id",
+		live: "SELECT 1 FROM comments c JOIN posts p ON p.id = c.post_id " +
+			"WHERE c.id = $1 AND c.deleted_at IS NULL AND p.deleted_at IS NULL",
+	},
+}
+
+// Коды ошибок PostgreSQL, которые хранилище переводит в собственные ошибки.
+const (
+	pgForeignKeyViolation = "23503"
+	pgUniqueViolation     = "23505"
+)
+
+// visibleComment отбирает опубликованные неудалённые комментарии и надгробия, под которыми
+// на любой глубине остался опубликованный неудалённый ответ. Ссылается на внешнюю таблицу
+// comments без псевдонима.
+const visibleComment = "status = 'PUBLISHED' AND (deleted_at IS NULL OR EXISTS (" +
+	"WITH RECURSIVE d AS (" +
+	"SELECT r.id, r.deleted_at, r.status FROM comments r WHERE r.parent_comment_id = comments.id " +
+	"UNION ALL SELECT r.id, r.deleted_at, r.status FROM comments r JOIN d ON r.parent_comment_id = d.id" +
+	") SELECT 1 FROM d WHERE d.deleted_at IS NULL AND d.status = 'PUBLISHED'))"
+
 type PostgresStorage struct {
-	db *sql.DB
+	db   *sql.DB
+	opts options
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
 }
 
-func NewPostgresStorage(dsn string) (*PostgresStorage, error) {
+func NewPostgresStorage(dsn string, opts ...Option) (*PostgresStorage, error) {
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		return nil, err
 	}
-	return &PostgresStorage{db: db}, nil
+	return &PostgresStorage{db: db, opts: newOptions(opts)}, nil
 }
 
-func (s *PostgresStorage) CreatePost(ctx context.Context, newPost model.NewPost) (*model.Post, error) {
-	post := &model.Post{
+func (s *PostgresStorage) CreateUser(ctx context.Context, newUser model.NewUser) (*model.User, error) {
+	user := &model.User{
 		ID:          uuid.New(),
-		Title:       newPost.Title,
-		Author:      newPost.Author,
-		Content:     newPost.Content,
-		Commentable: newPost.Commentable,
-		Comments:    []*model.Comment{},
+		Handle:      newUser.Handle,
+		DisplayName: displayName(newUser),
+		CreatedAt:   time.Now().UTC().Truncate(time.Microsecond),
 	}
 
 	_, err := s.db.ExecContext(ctx,
-		"INSERT INTO posts(id, title, author, content, commentable) VALUES($1, $2, $3, $4, $5)",
-		post.ID, post.Title, post.Author, post.Content, post.Commentable,
+		"INSERT INTO users (id, handle, display_name, created_at) VALUES ($1, $2, $3, $4)",
+		user.ID, user.Handle, user.DisplayName, user.CreatedAt,
 	)
-
 	if err != nil {
-		return nil, fmt.Errorf("failed to create post: %v", err)
+		return nil, constraintError(err, "failed to create user")
 	}
+	return user, nil
+}
 
-	return post, nil
+func (s *PostgresStorage) GetUserByID(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	return s.getUser(ctx, "id", id)
 }
 
-func (s *PostgresStorage) GetAllPosts(ctx context.Context, offset *int, limit *int) ([]*model.Post, error) {
-	query := "SELECT id, title, author, content, commentable FROM posts"
-	var args []interface{}
+func (s *PostgresStorage) GetUserByHandle(ctx context.Context, handle string) (*model.User, error) {
+	return s.getUser(ctx, "handle", handle)
+}
 
-	if limit != nil {
-		query += " LIMIT $1"
-		args = append(args, *limit)
-		if offset != nil {
-			query += " OFFSET $2"
-			args = append(args, *offset)
-		}
-	} else if offset != nil {
-		query += " OFFSET $1"
-		args = append(args, *offset)
+func (s *PostgresStorage) getUser(ctx context.Context, column string, value interface{}) (*model.User, error) {
+	user, err := scanUser(s.db.QueryRowContext(ctx,
+		"SELECT "+userColumns+" FROM users WHERE "+column+" = $1",
+		value,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user: %v", err)
+	}
+	return user, nil
+}
+
+func (s *PostgresStorage) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]*model.User, error) {
+	if len(ids) == 0 {
+		return []*model.User{}, nil
 	}
 
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(
+		"SELECT %s FROM users WHERE id IN (%s)",
+		userColumns, placeholders(len(ids)),
+	), args...)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to fetch users: %v", err)
 	}
 	defer rows.Close()
 
-	var posts []*model.Post
+	users := []*model.User{}
 	for rows.Next() {
-		var post model.Post
-		if err := rows.Scan(
-			&post.ID,
-			&post.Title,
-			&post.Author,
-			&post.Content,
-			&post.Commentable,
-		); err != nil {
-			return nil, err
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning user: %v", err)
 		}
-		post.Comments = []*model.Comment{}
-		posts = append(posts, &post)
+		users = append(users, user)
 	}
-
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+	return users, nil
+}
+
+func (s *PostgresStorage) CreatePost(ctx context.Context, newPost model.NewPost) (*model.Post, error) {
+	post := &model.Post{
+		ID:             uuid.New(),
+		Title:          newPost.Title,
+		AuthorID:       newPost.AuthorID,
+		Content:        newPost.Content,
+		Commentable:    newPost.Commentable,
+		ModerationMode: moderationMode(newPost),
+		CreatedAt:      time.Now().UTC().Truncate(time.Microsecond),
+	}
 
-	if len(posts) == 0 {
-		return posts, nil
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
 	}
+	defer tx.Rollback()
 
-	postIDs := make([]uuid.UUID, len(posts))
-	for i, post := range posts {
-		postIDs[i] = post.ID
+	if err := checkAuthor(ctx, tx, post.AuthorID); err != nil {
+		return nil, err
 	}
 
-	commentsQuery := fmt.Sprintf(
-		"SELECT id, post_id, parent_comment_id, author, content FROM comments WHERE post_id IN (%s)",
-		placeholders(len(postIDs)),
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO posts(id, title, author_id, content, commentable, moderation_mode, created_at) VALUES($1, $2, $3, $4, $5, $6, $7)",
+		post.ID, post.Title, post.AuthorID, post.Content, post.Commentable, post.ModerationMode, post.CreatedAt,
 	)
-	args = make([]interface{}, len(postIDs))
-	for i, id := range postIDs {
-		args[i] = id
+
+	if err != nil {
+		return nil, constraintError(err, "failed to create post")
 	}
+	if err := insertFilterResults(ctx, tx, "post_filter_results", "post_id", post.ID, 0, newPost.FilterResults); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
 
-	rows, err = s.db.QueryContext(ctx, commentsQuery, args...)
+// checkAuthor проверяет, что автор существует и не заблокирован. Строка пользователя
+// блокируется на чтение до конца транзакции, чтобы параллельная блокировка автора не
+// разминулась с публикацией.
+func checkAuthor(ctx context.Context, tx *sql.Tx, authorID uuid.UUID) error {
+	var bannedAt *time.Time
+	err := tx.QueryRowContext(ctx,
+		"SELECT banned_at FROM users WHERE id = $1 FOR SHARE",
+		authorID,
+	).Scan(&bannedAt)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch comments: %v", err)
+		return fmt.Errorf("failed to fetch author: %v", err)
 	}
-	defer rows.Close()
+	if bannedAt != nil {
+		return ErrBanned
+	}
+	return nil
+}
 
-	var tempComments []model.TempComment
-	for rows.Next() {
-		var c model.TempComment
-		if err := rows.Scan(&c.ID, &c.PostID, &c.ParentCommentID, &c.Author, &c.Content); err != nil {
-			return nil, fmt.Errorf("scanning comment: %v", err)
+func (s *PostgresStorage) GetAllPosts(ctx context.Context, offset *int, limit *int) ([]*model.Post, error) {
+	query := "SELECT " + postColumns + " FROM posts WHERE deleted_at IS NULL ORDER BY created_at, id"
+	var args []interface{}
+
+	if limit != nil {
+		query += " LIMIT $1"
+		args = append(args, *limit)
+		if offset != nil {
+			query += " OFFSET $2"
+			args = append(args, *offset)
 		}
-		tempComments = append(tempComments, c)
+	} else if offset != nil {
+		query += " OFFSET $1"
+		args = append(args, *offset)
 	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("after scanning comments: %v", err)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	commentMap := make(map[uuid.UUID]*model.Comment)
-	commentsByPostID := make(map[uuid.UUID][]*model.Comment)
+	return s.scanPosts(rows)
+}
 
-	for _, tc := range tempComments {
-		comment := &model.Comment{
-			ID:       tc.ID,
-			Author:   tc.Author,
-			Content:  tc.Content,
-			PostID:   &tc.PostID,
-			Comments: []*model.Comment{},
-		}
-		commentMap[tc.ID] = comment
+func (s *PostgresStorage) GetPostsPage(ctx context.Context, page model.PageArgs) (*model.PostPage, error) {
+	var total int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM posts WHERE deleted_at IS NULL").Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count posts: %v", err)
 	}
 
-	for _, tc := range tempComments {
-		comment := commentMap[tc.ID]
-		if tc.ParentCommentID != nil {
-			parent, exists := commentMap[*tc.ParentCommentID]
-			if exists {
-				parent.Comments = append(parent.Comments, comment)
-			}
-		} else {
-			commentsByPostID[tc.PostID] = append(commentsByPostID[tc.PostID], comment)
-		}
+	query, args := keysetQuery("SELECT "+postColumns+" FROM posts", []string{"deleted_at IS NULL"}, nil, page)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch posts: %v", err)
 	}
+	defer rows.Close()
 
-	for _, post := range posts {
-		if comments, ok := commentsByPostID[post.ID]; ok {
-			post.Comments = comments
-		} else {
-			post.Comments = []*model.Comment{}
-		}
+	posts, err := s.scanPosts(rows)
+	if err != nil {
+		return nil, err
 	}
 
-	return posts, nil
+	posts, hasNext, hasPrev := finishPage(posts, page)
+	return &model.PostPage{
+		Posts:           posts,
+		HasNextPage:     hasNext,
+		HasPreviousPage: hasPrev,
+		TotalCount:      total,
+	}, nil
 }
 
 func (s *PostgresStorage) GetPostByID(ctx context.Context, id string) (*model.Post, error) {
-	var post model.Post
-	err := s.db.QueryRowContext(ctx,
-		"SELECT id, title, author, content, commentable FROM posts WHERE id = $1",
+	post, err := scanPost(s.db.QueryRowContext(ctx,
+		"SELECT "+postColumns+" FROM posts WHERE id = $1 AND deleted_at IS NULL",
 		id,
-	).Scan(
-		&post.ID,
-		&post.Title,
-		&post.Author,
-		&post.Content,
-		&post.Commentable,
-	)
+	))
 
 	if err == sql.ErrNoRows {
 		return nil, ErrNotFound
@@ -179,60 +267,84 @@ func (s *PostgresStorage) GetPostByID(ctx context.Context, id string) (*model.Po
 		return nil, err
 	}
 
-	rows, err := s.db.QueryContext(ctx,
-		"SELECT id, post_id, parent_comment_id, author, content FROM comments WHERE post_id = $1",
-		post.ID,
-	)
+	return s.opts.withAutoLock(post), nil
+}
+
+func (s *PostgresStorage) UpdatePost(ctx context.Context, id uuid.UUID, input model.UpdatePost) (*model.Post, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch comments: %v", err)
+		return nil, err
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	var tempComments []model.TempComment
-	for rows.Next() {
-		var c model.TempComment
-		if err := rows.Scan(&c.ID, &c.PostID, &c.ParentCommentID, &c.Author, &c.Content); err != nil {
-			return nil, fmt.Errorf("scanning comment: %v", err)
-		}
-		tempComments = append(tempComments, c)
+	post, err := scanPost(tx.QueryRowContext(ctx,
+		"SELECT "+postColumns+" FROM posts WHERE id = $1 AND deleted_at IS NULL FOR UPDATE",
+		id,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
 	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("after scanning comments: %v", err)
+	if err != nil {
+		return nil, err
 	}
 
-	commentMap := make(map[uuid.UUID]*model.Comment)
-	commentsByPostID := make(map[uuid.UUID][]*model.Comment)
+	written := post.CreatedAt
+	if post.UpdatedAt != nil {
+		written = *post.UpdatedAt
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO post_revisions (post_id, version, title, content, commentable, created_at) "+
+			"SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4, $5 FROM post_revisions WHERE post_id = $1",
+		id, post.Title, post.Content, post.Commentable, written,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save post revision: %v", err)
+	}
 
-	for _, tc := range tempComments {
-		comment := &model.Comment{
-			ID:       tc.ID,
-			Author:   tc.Author,
-			Content:  tc.Content,
-			PostID:   &tc.PostID,
-			Comments: []*model.Comment{},
-		}
-		commentMap[tc.ID] = comment
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	s.opts.applyPostUpdate(post, input, now)
+	post.UpdatedAt = &now
+	_, err = tx.ExecContext(ctx,
+		"UPDATE posts SET title = $1, content = $2, commentable = $3, locked_at = $4, lock_reason = $5, unlocked_at = $6, "+
+			"moderation_mode = $7, updated_at = $8 WHERE id = $9",
+		post.Title, post.Content, post.Commentable, post.LockedAt, post.LockReason, post.UnlockedAt,
+		post.ModerationMode, post.UpdatedAt, id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update post: %v", err)
+	}
+	if err := appendFilterResults(ctx, tx, "post_filter_results", "post_id", id, input.FilterResults); err != nil {
+		return nil, err
 	}
 
-	for _, tc := range tempComments {
-		comment := commentMap[tc.ID]
-		if tc.ParentCommentID != nil {
-			parent, exists := commentMap[*tc.ParentCommentID]
-			if exists {
-				parent.Comments = append(parent.Comments, comment)
-			}
-		} else {
-			commentsByPostID[tc.PostID] = append(commentsByPostID[tc.PostID], comment)
-		}
+	if err := tx.Commit(); err != nil {
+		return nil, err
 	}
+	return s.opts.withAutoLock(post), nil
+}
 
-	if comments, ok := commentsByPostID[post.ID]; ok {
-		post.Comments = comments
-	} else {
-		post.Comments = []*model.Comment{}
+func (s *PostgresStorage) GetPostRevisions(ctx context.Context, postID uuid.UUID) ([]*model.PostRevision, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT post_id, version, title, content, commentable, created_at FROM post_revisions WHERE post_id = $1 ORDER BY version",
+		postID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch post revisions: %v", err)
 	}
+	defer rows.Close()
 
-	return &post, nil
+	revisions := []*model.PostRevision{}
+	for rows.Next() {
+		var r model.PostRevision
+		if err := rows.Scan(&r.PostID, &r.Version, &r.Title, &r.Content, &r.Commentable, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning post revision: %v", err)
+		}
+		revisions = append(revisions, &r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return revisions, nil
 }
 
 func (s *PostgresStorage) CreateComment(ctx context.Context, newComment model.NewComment) (*model.Comment, error) {
@@ -243,53 +355,39 @@ func (s *PostgresStorage) CreateComment(ctx context.Context, newComment model.Ne
 	defer tx.Rollback()
 
 	comment := &model.Comment{
-		ID:      uuid.New(),
-		Author:  newComment.Author,
-		Content: newComment.Content,
+		ID:        uuid.New(),
+		AuthorID:  newComment.AuthorID,
+		Content:   newComment.Content,
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+
+	if err := checkAuthor(ctx, tx, comment.AuthorID); err != nil {
+		return nil, err
 	}
 
 	switch {
 	case newComment.PostID != nil:
-		postID, err := uuid.Parse(*newComment.PostID)
-		if err != nil {
-			return nil, ErrBadRequest
-		}
-
-		var commentable bool
-		err = tx.QueryRowContext(ctx,
-			"SELECT commentable FROM posts WHERE id = $1",
-			postID,
-		).Scan(&commentable)
-
-		if err == sql.ErrNoRows {
-			return nil, ErrNotFound
-		}
+		postID := *newComment.PostID
+		mode, err := s.checkCommentable(ctx, tx, postID)
 		if err != nil {
 			return nil, err
 		}
-		if !commentable {
-			return nil, ErrNotCommentable
-		}
+		comment.Status = commentStatus(mode)
 
 		_, err = tx.ExecContext(ctx,
-			"INSERT INTO comments (id, post_id, author, content) VALUES ($1, $2, $3, $4)",
-			comment.ID, postID, comment.Author, comment.Content,
+			"INSERT INTO comments (id, post_id, author_id, content, created_at, status) VALUES ($1, $2, $3, $4, $5, $6)",
+			comment.ID, postID, comment.AuthorID, comment.Content, comment.CreatedAt, comment.Status,
 		)
 		if err != nil {
-			return nil, err
+			return nil, constraintError(err, "failed to create comment")
 		}
-		parsed, _ := uuid.Parse(*newComment.PostID)
-		comment.PostID = &(parsed)
+		comment.PostID = &postID
 
 	case newComment.CommentID != nil:
-		parentID, err := uuid.Parse(*newComment.CommentID)
-		if err != nil {
-			return nil, ErrBadRequest
-		}
-
+		parentID := *newComment.CommentID
 		var postID uuid.UUID
-		err = tx.QueryRowContext(ctx,
-			"SELECT post_id FROM comments WHERE id = $1",
+		err := tx.QueryRowContext(ctx,
+			"SELECT post_id FROM comments WHERE id = $1 AND deleted_at IS NULL AND status = 'PUBLISHED'",
 			parentID,
 		).Scan(&postID)
 		comment.PostID = &postID
@@ -301,31 +399,29 @@ func (s *PostgresStorage) CreateComment(ctx context.Context, newComment model.Ne
 			return nil, err
 		}
 
-		var commentable bool
-		err = tx.QueryRowContext(ctx,
-			"SELECT commentable FROM posts WHERE id = $1",
-			postID,
-		).Scan(&commentable)
-
+		mode, err := s.checkCommentable(ctx, tx, postID)
 		if err != nil {
 			return nil, err
 		}
-		if !commentable {
-			return nil, ErrNotCommentable
-		}
+		comment.Status = commentStatus(mode)
 
 		_, err = tx.ExecContext(ctx,
-			"INSERT INTO comments (id, post_id, parent_comment_id, author, content) VALUES ($1, $2, $3, $4, $5)",
-			comment.ID, postID, parentID, comment.Author, comment.Content,
+			"INSERT INTO comments (id, post_id, parent_comment_id, author_id, content, created_at, status) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			comment.ID, postID, parentID, comment.AuthorID, comment.Content, comment.CreatedAt, comment.Status,
 		)
 		if err != nil {
-			return nil, err
+			return nil, constraintError(err, "failed to create comment")
 		}
+		comment.ParentID = &parentID
 
 	default:
 		return nil, ErrBadRequest
 	}
 
+	if err := insertFilterResults(ctx, tx, "comment_filter_results", "comment_id", comment.ID, 0, newComment.FilterResults); err != nil {
+		return nil, err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
@@ -333,6 +429,1066 @@ func (s *PostgresStorage) CreateComment(ctx context.Context, newComment model.Ne
 	return comment, nil
 }
 
+// checkCommentable проверяет, что пост существует и открыт для комментариев с учётом
+// автозакрытия, и возвращает его режим модерации.
+func (s *PostgresStorage) checkCommentable(ctx context.Context, tx *sql.Tx, postID uuid.UUID) (model.ModerationMode, error) {
+	post := model.Post{ID: postID}
+	err := tx.QueryRowContext(ctx,
+		"SELECT commentable, created_at, unlocked_at, moderation_mode FROM posts WHERE id = $1 AND deleted_at IS NULL",
+		postID,
+	).Scan(&post.Commentable, &post.CreatedAt, &post.UnlockedAt, &post.ModerationMode)
+
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	if !s.opts.withAutoLock(&post).Commentable {
+		return "", ErrNotCommentable
+	}
+	return post.ModerationMode, nil
+}
+
+func (s *PostgresStorage) GetCommentByID(ctx context.Context, id uuid.UUID) (*model.Comment, error) {
+	comment, err := scanComment(s.db.QueryRowContext(ctx,
+		"SELECT "+commentColumns+" FROM comments WHERE id = $1 AND deleted_at IS NULL "+
+			"AND EXISTS (SELECT 1 FROM posts p WHERE p.id = comments.post_id AND p.deleted_at IS NULL)",
+		id,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+func (s *PostgresStorage) EditComment(ctx context.Context, id uuid.UUID, content string, results []*model.FilterResult) (*model.Comment, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	comment, err := scanComment(tx.QueryRowContext(ctx,
+		"SELECT "+commentColumns+" FROM comments WHERE id = $1 AND deleted_at IS NULL FOR UPDATE",
+		id,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if comment.HiddenAt != nil {
+		return nil, ErrHidden
+	}
+
+	written := comment.CreatedAt
+	if comment.EditedAt != nil {
+		written = *comment.EditedAt
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO comment_revisions (comment_id, version, content, created_at) "+
+			"SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3 FROM comment_revisions WHERE comment_id = $1",
+		id, comment.Content, written,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save comment revision: %v", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	comment.Content, comment.EditedAt = content, &now
+	_, err = tx.ExecContext(ctx,
+		"UPDATE comments SET content = $1, edited_at = $2 WHERE id = $3",
+		comment.Content, comment.EditedAt, id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to edit comment: %v", err)
+	}
+	if err := appendFilterResults(ctx, tx, "comment_filter_results", "comment_id", id, results); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+func (s *PostgresStorage) GetCommentHistory(ctx context.Context, commentID uuid.UUID) ([]*model.CommentRevision, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT comment_id, version, content, created_at FROM comment_revisions WHERE comment_id = $1 ORDER BY version",
+		commentID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch comment history: %v", err)
+	}
+	defer rows.Close()
+
+	revisions := []*model.CommentRevision{}
+	for rows.Next() {
+		var r model.CommentRevision
+		if err := rows.Scan(&r.CommentID, &r.Version, &r.Content, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning comment revision: %v", err)
+		}
+		revisions = append(revisions, &r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// insertFilterResults сохраняет отметки фильтров поста или комментария id в table,
+// нумеруя их с позиции from.
+func insertFilterResults(ctx context.Context, tx *sql.Tx, table, column string, id uuid.UUID, from int, results []*model.FilterResult) error {
+	for i, r := range results {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO "+table+" ("+column+", position, filter, action, reason) VALUES ($1, $2, $3, $4, $5)",
+			id, from+i, r.Filter, r.Action, r.Reason,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save filter result: %v", err)
+		}
+	}
+	return nil
+}
+
+// appendFilterResults дописывает отметки фильтров после уже сохранённых. Строку поста или
+// комментария вызывающий держит под FOR UPDATE, так что позиции не пересекутся.
+func appendFilterResults(ctx context.Context, tx *sql.Tx, table, column string, id uuid.UUID, results []*model.FilterResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	var from int
+	err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table+" WHERE "+column+" = $1", id).Scan(&from)
+	if err != nil {
+		return fmt.Errorf("failed to count filter results: %v", err)
+	}
+	return insertFilterResults(ctx, tx, table, column, id, from, results)
+}
+
+func (s *PostgresStorage) GetPostFilterResults(ctx context.Context, postID uuid.UUID) ([]*model.FilterResult, error) {
+	return s.getFilterResults(ctx, "post_filter_results", "post_id", postID)
+}
+
+func (s *PostgresStorage) GetCommentFilterResults(ctx context.Context, commentID uuid.UUID) ([]*model.FilterResult, error) {
+	return s.getFilterResults(ctx, "comment_filter_results", "comment_id", commentID)
+}
+
+func (s *PostgresStorage) getFilterResults(ctx context.Context, table, column string, id uuid.UUID) ([]*model.FilterResult, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT filter, action, reason FROM "+table+" WHERE "+column+" = $1 ORDER BY position",
+		id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch filter results: %v", err)
+	}
+	defer rows.Close()
+
+	results := []*model.FilterResult{}
+	for rows.Next() {
+		var r model.FilterResult
+		if err := rows.Scan(&r.Filter, &r.Action, &r.Reason); err != nil {
+			return nil, fmt.Errorf("scanning filter result: %v", err)
+		}
+		results = append(results, &r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// SetCommentable открывает или закрывает пост для комментариев. Закрытие запоминает
+// время и причину, открытие их сбрасывает и отменяет автозакрытие поста.
+func (s *PostgresStorage) SetCommentable(ctx context.Context, postID uuid.UUID, value bool, reason *string) (*model.Post, error) {
+	var lock model.Post
+	setCommentable(&lock, value, reason, time.Now().UTC().Truncate(time.Microsecond))
+
+	post, err := scanPost(s.db.QueryRowContext(ctx,
+		"UPDATE posts SET commentable = $1, locked_at = $2, lock_reason = $3, unlocked_at = $4 "+
+			"WHERE id = $5 AND deleted_at IS NULL RETURNING "+postColumns,
+		lock.Commentable, lock.LockedAt, lock.LockReason, lock.UnlockedAt, postID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to set commentable: %v", err)
+	}
+	return s.opts.withAutoLock(post), nil
+}
+
+func (s *PostgresStorage) VotePost(ctx context.Context, postID uuid.UUID, userID uuid.UUID, value int) (*model.Post, error) {
+	var post *model.Post
+	err := s.vote(ctx, "posts", "post_id", postColumns, "deleted_at IS NULL", postID, userID, value, func(row rowScanner) (err error) {
+		post, err = scanPost(row)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.opts.withAutoLock(post), nil
+}
+
+func (s *PostgresStorage) VoteComment(ctx context.Context, commentID uuid.UUID, userID uuid.UUID, value int) (*model.Comment, error) {
+	var comment *model.Comment
+	err := s.vote(ctx, "comments", "comment_id", commentColumns, votableComment, commentID, userID, value, func(row rowScanner) (err error) {
+		comment, err = scanComment(row)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// votableComment отбирает комментарии, за которые можно голосовать: опубликованные,
+// неудалённые и не скрытые, под неудалённым постом.
+const votableComment = "deleted_at IS NULL AND hidden_at IS NULL AND status = 'PUBLISHED' AND " +
+	"EXISTS (SELECT 1 FROM posts WHERE posts.id = comments.post_id AND posts.deleted_at IS NULL)"
+
+// vote сохраняет голос пользователя за строку table и пересчитывает её счётчики, после чего
+// передаёт обновлённую строку (колонки columns) в scan. column — ссылка из votes на table,
+// votable — условие, которому должна удовлетворять цель, иначе возвращается ErrNotFound.
+// Строка цели блокируется, чтобы параллельные голоса одного пользователя не учлись дважды.
+func (s *PostgresStorage) vote(ctx context.Context, table, column, columns, votable string, id uuid.UUID, userID uuid.UUID, value int, scan func(rowScanner) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT TRUE FROM "+table+" WHERE id = $1 AND "+votable+" FOR UPDATE",
+		id,
+	).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	var prev int
+	err = tx.QueryRowContext(ctx,
+		"SELECT value FROM votes WHERE "+column+" = $1 AND user_id = $2",
+		id, userID,
+	).Scan(&prev)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to fetch vote: %v", err)
+	}
+
+	if prev != value {
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO votes ("+column+", user_id, value, created_at) VALUES ($1, $2, $3, $4) "+
+				"ON CONFLICT ("+column+", user_id) WHERE "+column+" IS NOT NULL DO UPDATE SET value = EXCLUDED.value",
+			id, userID, value, time.Now().UTC().Truncate(time.Microsecond),
+		)
+		if err != nil {
+			return constraintError(err, "failed to save vote")
+		}
+	}
+
+	up, down := voteDelta(prev, value)
+	err = scan(tx.QueryRowContext(ctx,
+		"UPDATE "+table+" SET upvotes = upvotes + $1, downvotes = downvotes + $2 WHERE id = $3 RETURNING "+columns,
+		up, down, id,
+	))
+	if err != nil {
+		return fmt.Errorf("failed to update score: %v", err)
+	}
+
+	return tx.Commit()
+}
+
+func (s *PostgresStorage) DeletePost(ctx context.Context, id uuid.UUID) error {
+	return s.softDelete(ctx, "posts", id)
+}
+
+// DeleteComment помечает комментарий удалённым. Пока под ним есть видимые ответы, он
+// остаётся в дереве надгробием (см. visibleComment), иначе исчезает из выдачи.
+func (s *PostgresStorage) DeleteComment(ctx context.Context, id uuid.UUID) error {
+	return s.softDelete(ctx, "comments", id)
+}
+
+func (s *PostgresStorage) softDelete(ctx context.Context, table string, id uuid.UUID) error {
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE "+table+" SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL",
+		time.Now().UTC().Truncate(time.Microsecond), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete from %s: %v", table, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *PostgresStorage) GetPostsByIDs(ctx context.Context, ids []uuid.UUID) ([]*model.Post, error) {
+	if len(ids) == 0 {
+		return []*model.Post{}, nil
+	}
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(
+		"SELECT %s FROM posts WHERE id IN (%s) AND deleted_at IS NULL",
+		postColumns, placeholders(len(ids)),
+	), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch posts: %v", err)
+	}
+	defer rows.Close()
+
+	return s.scanPosts(rows)
+}
+
+func (s *PostgresStorage) GetCommentsByPostIDs(ctx context.Context, postIDs []uuid.UUID, page model.PageArgs) (map[uuid.UUID]*model.CommentPage, error) {
+	return s.commentPages(ctx, "post_id = p.id AND parent_comment_id IS NULL", postIDs, page)
+}
+
+func (s *PostgresStorage) GetRepliesByCommentIDs(ctx context.Context, commentIDs []uuid.UUID, page model.PageArgs) (map[uuid.UUID]*model.CommentPage, error) {
+	return s.commentPages(ctx, "parent_comment_id = p.id", commentIDs, page)
+}
+
+// commentPages загружает по странице дочерних комментариев для каждого родителя одним
+// запросом: LATERAL-подзапросы считают общее число потомков и выбирают keyset-страницу.
+// cond связывает строку comments с родителем p.id.
+func (s *PostgresStorage) commentPages(ctx context.Context, cond string, parentIDs []uuid.UUID, page model.PageArgs) (map[uuid.UUID]*model.CommentPage, error) {
+	pages := make(map[uuid.UUID]*model.CommentPage, len(parentIDs))
+	if len(parentIDs) == 0 {
+		return pages, nil
+	}
+
+	args := make([]interface{}, len(parentIDs))
+	for i, id := range parentIDs {
+		args[i] = id
+	}
+	cond += " AND " + visibleComment
+	inner, args := keysetQuery("SELECT "+commentColumns+" FROM comments", []string{cond}, args, page)
+	query := fmt.Sprintf(
+		"SELECT p.id, cnt.total, %s "+
+			"FROM unnest(ARRAY[%s]::uuid[]) AS p(id) "+
+			"CROSS JOIN LATERAL (SELECT COUNT(*) AS total FROM comments WHERE %s) AS cnt "+
+			"LEFT JOIN LATERAL (%s) AS c ON TRUE "+
+			"ORDER BY p.id, %s",
+		qualify(commentColumns, "c"), placeholders(len(parentIDs)), cond, inner, keysetOrder(page, "c."),
+	)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch comments: %v", err)
+	}
+	defer rows.Close()
+
+	fetched := make(map[uuid.UUID][]*model.Comment, len(parentIDs))
+	for rows.Next() {
+		var (
+			parentID uuid.UUID
+			total    int
+		)
+		comment, err := scanComment(rows, &parentID, &total)
+		if err != nil {
+			return nil, fmt.Errorf("scanning comment: %v", err)
+		}
+		if _, ok := pages[parentID]; !ok {
+			pages[parentID] = &model.CommentPage{TotalCount: total}
+		}
+		if comment != nil {
+			fetched[parentID] = append(fetched[parentID], comment)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("after scanning comments: %v", err)
+	}
+
+	for parentID, p := range pages {
+		p.Comments, p.HasNextPage, p.HasPreviousPage = finishPage(fetched[parentID], page)
+		if p.Comments == nil {
+			p.Comments = []*model.Comment{}
+		}
+	}
+	return pages, nil
+}
+
+// SetCommentHidden скрывает комментарий или возвращает его в выдачу и записывает действие
+// в журнал модерации в той же транзакции. Скрытый комментарий остаётся в дереве без текста
+// и не ищется. Повторная установка того же состояния ничего не меняет и в журнал не попадает.
+func (s *PostgresStorage) SetCommentHidden(ctx context.Context, id uuid.UUID, hidden bool, moderatorID uuid.UUID, reason *string) (*model.Comment, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	comment, err := scanComment(tx.QueryRowContext(ctx,
+		"SELECT "+commentColumns+" FROM comments WHERE id = $1 AND deleted_at IS NULL FOR UPDATE",
+		id,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if (comment.HiddenAt != nil) == hidden {
+		return comment, nil
+	}
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	comment.HiddenAt = nil
+	if hidden {
+		comment.HiddenAt = &now
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE comments SET hidden_at = $1 WHERE id = $2", comment.HiddenAt, id); err != nil {
+		return nil, fmt.Errorf("failed to hide comment: %v", err)
+	}
+	if err := logAction(ctx, tx, hideAction(hidden), id, moderatorID, reason, now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// SetUserBanned блокирует пользователя или снимает блокировку и записывает действие в
+// журнал модерации. Заблокированный пользователь не может создавать посты и комментарии
+// (см. checkAuthor).
+func (s *PostgresStorage) SetUserBanned(ctx context.Context, userID uuid.UUID, banned bool, moderatorID uuid.UUID, reason *string) (*model.User, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	user, err := scanUser(tx.QueryRowContext(ctx,
+		"SELECT "+userColumns+" FROM users WHERE id = $1 FOR UPDATE",
+		userID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if (user.BannedAt != nil) == banned {
+		return user, nil
+	}
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	user.BannedAt = nil
+	if banned {
+		user.BannedAt = &now
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET banned_at = $1 WHERE id = $2", user.BannedAt, userID); err != nil {
+		return nil, fmt.Errorf("failed to ban user: %v", err)
+	}
+	if err := logAction(ctx, tx, banAction(banned), userID, moderatorID, reason, now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ApproveComment публикует комментарий, ожидающий премодерации, и записывает действие в
+// журнал модерации в той же транзакции. Одобрение опубликованного комментария ничего не меняет.
+func (s *PostgresStorage) ApproveComment(ctx context.Context, id uuid.UUID, moderatorID uuid.UUID) (*model.Comment, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	comment, err := scanComment(tx.QueryRowContext(ctx,
+		"SELECT "+commentColumns+" FROM comments WHERE id = $1 AND deleted_at IS NULL "+
+			"AND EXISTS (SELECT 1 FROM posts p WHERE p.id = comments.post_id AND p.deleted_at IS NULL) FOR UPDATE",
+		id,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if comment.Status == model.CommentStatusPublished {
+		return comment, nil
+	}
+
+	comment.Status = model.CommentStatusPublished
+	if _, err := tx.ExecContext(ctx, "UPDATE comments SET status = $1 WHERE id = $2", comment.Status, id); err != nil {
+		return nil, fmt.Errorf("failed to approve comment: %v", err)
+	}
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	if err := logAction(ctx, tx, model.ModerationActionTypeApproveComment, id, moderatorID, nil, now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// GetPendingComments возвращает страницу комментариев, ожидающих премодерации, по всем
+// постам или только по postID.
+func (s *PostgresStorage) GetPendingComments(ctx context.Context, postID *uuid.UUID, page model.PageArgs) (*model.CommentPage, error) {
+	conds := []string{
+		"status = 'PENDING' AND deleted_at IS NULL",
+		"EXISTS (SELECT 1 FROM posts p WHERE p.id = comments.post_id AND p.deleted_at IS NULL)",
+	}
+	var args []interface{}
+	if postID != nil {
+		conds = append(conds, "post_id = $1")
+		args = append(args, *postID)
+	}
+
+	var total int
+	err := s.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM comments WHERE "+strings.Join(conds, " AND "),
+		args...,
+	).Scan(&total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count pending comments: %v", err)
+	}
+
+	query, args := keysetQuery("SELECT "+commentColumns+" FROM comments", conds, args, page)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pending comments: %v", err)
+	}
+	defer rows.Close()
+
+	var comments []*model.Comment
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning comment: %v", err)
+		}
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	comments, hasNext, hasPrev := finishPage(comments, page)
+	if comments == nil {
+		comments = []*model.Comment{}
+	}
+	return &model.CommentPage{
+		Comments:        comments,
+		HasNextPage:     hasNext,
+		HasPreviousPage: hasPrev,
+		TotalCount:      total,
+	}, nil
+}
+
+// GetPostComments возвращает страницу опубликованных комментариев поста вместе с ответами
+// любой глубины; удалённые и скрытые комментарии пропускаются. TotalCount не заполняется.
+func (s *PostgresStorage) GetPostComments(ctx context.Context, postID uuid.UUID, page model.PageArgs) (*model.CommentPage, error) {
+	conds := []string{
+		"post_id = $1",
+		"status = 'PUBLISHED' AND deleted_at IS NULL AND hidden_at IS NULL",
+		"EXISTS (SELECT 1 FROM posts p WHERE p.id = comments.post_id AND p.deleted_at IS NULL)",
+	}
+	query, args := keysetQuery("SELECT "+commentColumns+" FROM comments", conds, []interface{}{postID}, page)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch post comments: %v", err)
+	}
+	defer rows.Close()
+
+	var comments []*model.Comment
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning comment: %v", err)
+		}
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	comments, hasNext, hasPrev := finishPage(comments, page)
+	if comments == nil {
+		comments = []*model.Comment{}
+	}
+	return &model.CommentPage{
+		Comments:        comments,
+		HasNextPage:     hasNext,
+		HasPreviousPage: hasPrev,
+	}, nil
+}
+
+// GetCommentAncestors возвращает идентификаторы предков комментария: от родителя до
+// корневого комментария ветки.
+func (s *PostgresStorage) GetCommentAncestors(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"WITH RECURSIVE a AS ("+
+			"SELECT parent_comment_id AS id, 1 AS depth FROM comments WHERE id = $1 "+
+			"UNION ALL SELECT c.parent_comment_id, a.depth + 1 FROM comments c JOIN a ON c.id = a.id"+
+			") SELECT id FROM a WHERE id IS NOT NULL ORDER BY depth",
+		id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch comment ancestors: %v", err)
+	}
+	defer rows.Close()
+
+	var ancestors []uuid.UUID
+	for rows.Next() {
+		var ancestor uuid.UUID
+		if err := rows.Scan(&ancestor); err != nil {
+			return nil, fmt.Errorf("scanning comment ancestor: %v", err)
+		}
+		ancestors = append(ancestors, ancestor)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ancestors, nil
+}
+
+func logAction(ctx context.Context, tx *sql.Tx, action model.ModerationActionType, targetID, moderatorID uuid.UUID, reason *string, at time.Time) error {
+	_, err := tx.ExecContext(ctx,
+		"INSERT INTO moderation_log ("+actionColumns+") VALUES ($1, $2, $3, $4, $5, $6)",
+		uuid.New(), moderatorID, action, targetID, reason, at,
+	)
+	if err != nil {
+		return constraintError(err, "failed to log moderation action")
+	}
+	return nil
+}
+
+func (s *PostgresStorage) GetModerationLog(ctx context.Context, page model.PageArgs) (*model.ModerationLogPage, error) {
+	page = logPage(page)
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM moderation_log").Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count moderation actions: %v", err)
+	}
+
+	query, args := keysetQuery("SELECT "+actionColumns+" FROM moderation_log", nil, nil, page)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch moderation log: %v", err)
+	}
+	defer rows.Close()
+
+	var actions []*model.ModerationAction
+	for rows.Next() {
+		action, err := scanAction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning moderation action: %v", err)
+		}
+		actions = append(actions, action)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	actions, hasNext, hasPrev := finishPage(actions, page)
+	return &model.ModerationLogPage{
+		Actions:         actions,
+		HasNextPage:     hasNext,
+		HasPreviousPage: hasPrev,
+		TotalCount:      total,
+	}, nil
+}
+
+// CreateReport сохраняет жалобу на неудалённый пост или комментарий. Пока жалоба
+// пользователя открыта, повторная на тот же объект нарушает частичный уникальный индекс
+// и отклоняется с ErrAlreadyExists.
+func (s *PostgresStorage) CreateReport(ctx context.Context, newReport model.NewReport) (*model.Report, error) {
+	target, ok := reportTargets[newReport.TargetType]
+	if !ok {
+		return nil, ErrBadRequest
+	}
+	report := &model.Report{
+		ID:         uuid.New(),
+		ReporterID: newReport.ReporterID,
+		TargetType: newReport.TargetType,
+		TargetID:   newReport.TargetID,
+		Reason:     newReport.Reason,
+		CreatedAt:  time.Now().UTC().Truncate(time.Microsecond),
+	}
+
+	res, err := s.db.ExecContext(ctx,
+		"INSERT INTO reports (id, "+target.column+", reporter_id, reason, created_at) "+
+			"SELECT $2, $1, $3, $4, $5 WHERE EXISTS ("+target.live+")",
+		report.TargetID, report.ID, report.ReporterID, report.Reason, report.CreatedAt,
+	)
+	if err != nil {
+		return nil, constraintError(err, "failed to create report")
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, ErrNotFound
+	}
+	return report, nil
+}
+
+// reportQueueQuery выбирает страницу объектов с открытыми жалобами в порядке
+// compareQueueItems.
+const reportQueueQuery = "SELECT post_id, comment_id, COUNT(*), MIN(created_at), MAX(created_at) " +
+	"FROM reports WHERE resolved_at IS NULL GROUP BY post_id, comment_id " +
+	"ORDER BY COUNT(*) DESC, MAX(created_at) DESC, COALESCE(post_id, comment_id) LIMIT $1 OFFSET $2"
+
+// GetReportQueue группирует открытые жалобы по объекту; первыми идут объекты с
+// наибольшим числом жалоб, при равенстве — с самой свежей жалобой.
+func (s *PostgresStorage) GetReportQueue(ctx context.Context, limit, offset int) ([]*model.ReportQueueItem, error) {
+	rows, err := s.db.QueryContext(ctx, reportQueueQuery, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch report queue: %v", err)
+	}
+	defer rows.Close()
+
+	items := []*model.ReportQueueItem{}
+	byTarget := make(map[uuid.UUID]*model.ReportQueueItem)
+	for rows.Next() {
+		var (
+			item              model.ReportQueueItem
+			postID, commentID *uuid.UUID
+		)
+		if err := rows.Scan(&postID, &commentID, &item.ReportCount, &item.FirstReportedAt, &item.LastReportedAt); err != nil {
+			return nil, fmt.Errorf("scanning report queue: %v", err)
+		}
+		item.TargetType, item.TargetID = reportTarget(postID, commentID)
+		items = append(items, &item)
+		byTarget[item.TargetID] = &item
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return items, nil
+	}
+
+	args := make([]interface{}, len(items))
+	for i, item := range items {
+		args[i] = item.TargetID
+	}
+	reports, err := s.queryReports(ctx, fmt.Sprintf(
+		"SELECT %s FROM reports WHERE resolved_at IS NULL AND COALESCE(post_id, comment_id) IN (%s) ORDER BY created_at, id",
+		reportColumns, placeholders(len(items)),
+	), args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, report := range reports {
+		if item, ok := byTarget[report.TargetID]; ok {
+			item.Reports = append(item.Reports, report)
+		}
+	}
+	return items, nil
+}
+
+// ResolveReports закрывает все открытые жалобы на объект решением модератора и
+// возвращает их; ErrNotFound — открытых жалоб нет.
+func (s *PostgresStorage) ResolveReports(ctx context.Context, targetType model.ReportTargetType, targetID uuid.UUID, moderatorID uuid.UUID, resolution model.ReportAction) ([]*model.Report, error) {
+	target, ok := reportTargets[targetType]
+	if !ok {
+		return nil, ErrBadRequest
+	}
+
+	reports, err := s.queryReports(ctx,
+		"UPDATE reports SET resolved_at = $1, resolver_id = $2, resolution = $3 "+
+			"WHERE "+target.column+" = $4 AND resolved_at IS NULL RETURNING "+reportColumns,
+		time.Now().UTC().Truncate(time.Microsecond), moderatorID, resolution, targetID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(reports) == 0 {
+		return nil, ErrNotFound
+	}
+	return reports, nil
+}
+
+func (s *PostgresStorage) queryReports(ctx context.Context, query string, args ...interface{}) ([]*model.Report, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch reports: %v", err)
+	}
+	defer rows.Close()
+
+	var reports []*model.Report
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning report: %v", err)
+		}
+		reports = append(reports, report)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// searchQuery ранжирует совпадения в постах и комментариях (колонки search — tsvector с
+// GIN-индексами) и строит фрагменты с подсветкой только для выбранной страницы. Из текста
+// удаляются символы маркеров headlineStart и headlineStop, чтобы их ставил только ts_headline.
+const searchQuery = "SELECT kind, id, rank, ts_headline('simple', translate(body, chr(2) || chr(3), ''), query, $4), total FROM (" +
+	"SELECT kind, id, rank, body, query, created_at, COUNT(*) OVER () AS total FROM (" +
+	"SELECT 'post' AS kind, id, ts_rank(search, query) AS rank, title || ' ' || content AS body, query, created_at " +
+	"FROM posts, websearch_to_tsquery('simple', $1) AS query " +
+	"WHERE search @@ query AND deleted_at IS NULL " +
+	"UNION ALL " +
+	"SELECT 'comment', c.id, ts_rank(c.search, query), c.content, query, c.created_at " +
+	"FROM comments c JOIN posts p ON p.id = c.post_id, websearch_to_tsquery('simple', $1) AS query " +
+	"WHERE c.search @@ query AND c.deleted_at IS NULL AND c.hidden_at IS NULL AND c.status = 'PUBLISHED' AND p.deleted_at IS NULL" +
+	") AS matches ORDER BY rank DESC, created_at DESC, id DESC LIMIT $2 OFFSET $3" +
+	") AS hits ORDER BY rank DESC, created_at DESC, id DESC"
+
+// Search ищет посты и комментарии полнотекстовым поиском PostgreSQL.
+func (s *PostgresStorage) Search(ctx context.Context, query string, limit, offset int) (*model.SearchPage, error) {
+	rows, err := s.db.QueryContext(ctx, searchQuery,
+		query, limit, offset,
+		fmt.Sprintf(`StartSel="%s", StopSel="%s", MaxWords=%d, MinWords=%d`, headlineStart, headlineStop, snippetWords, snippetWords/4),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search: %v", err)
+	}
+	defer rows.Close()
+
+	type match struct {
+		kind string
+		id   uuid.UUID
+		hit  *model.SearchHit
+	}
+	var (
+		matches    []match
+		postIDs    []uuid.UUID
+		commentIDs []uuid.UUID
+		page       = &model.SearchPage{Hits: []*model.SearchHit{}}
+	)
+	for rows.Next() {
+		m := match{hit: &model.SearchHit{}}
+		if err := rows.Scan(&m.kind, &m.id, &m.hit.Rank, &m.hit.Snippet, &page.TotalCount); err != nil {
+			return nil, fmt.Errorf("scanning search hit: %v", err)
+		}
+		m.hit.Snippet = headlineMarks.Replace(html.EscapeString(m.hit.Snippet))
+		if m.kind == "post" {
+			postIDs = append(postIDs, m.id)
+		} else {
+			commentIDs = append(commentIDs, m.id)
+		}
+		matches = append(matches, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	posts, err := s.GetPostsByIDs(ctx, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := s.getCommentsByIDs(ctx, commentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[uuid.UUID]model.SearchResult, len(matches))
+	for _, post := range posts {
+		nodes[post.ID] = post
+	}
+	for _, comment := range comments {
+		nodes[comment.ID] = comment
+	}
+	for _, m := range matches {
+		// Строка могла быть удалена между запросами.
+		if node, ok := nodes[m.id]; ok {
+			m.hit.Node = node
+			page.Hits = append(page.Hits, m.hit)
+		}
+	}
+	return page, nil
+}
+
+func (s *PostgresStorage) getCommentsByIDs(ctx context.Context, ids []uuid.UUID) ([]*model.Comment, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(
+		"SELECT %s FROM comments WHERE id IN (%s) AND deleted_at IS NULL",
+		commentColumns, placeholders(len(ids)),
+	), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch comments: %v", err)
+	}
+	defer rows.Close()
+
+	var comments []*model.Comment
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning comment: %v", err)
+		}
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func scanUser(row rowScanner) (*model.User, error) {
+	var user model.User
+	if err := row.Scan(&user.ID, &user.Handle, &user.DisplayName, &user.CreatedAt, &user.BannedAt); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func scanPost(row rowScanner) (*model.Post, error) {
+	var post model.Post
+	if err := row.Scan(
+		&post.ID,
+		&post.Title,
+		&post.AuthorID,
+		&post.Content,
+		&post.Commentable,
+		&post.CreatedAt,
+		&post.UpdatedAt,
+		&post.LockedAt,
+		&post.LockReason,
+		&post.Upvotes,
+		&post.Downvotes,
+		&post.ModerationMode,
+		&post.UnlockedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+func (s *PostgresStorage) scanPosts(rows *sql.Rows) ([]*model.Post, error) {
+	var posts []*model.Post
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, s.opts.withAutoLock(post))
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// scanComment читает колонки commentColumns, предварительно заполнив dest. Для строки
+// LEFT JOIN без комментария возвращает nil.
+func scanComment(row rowScanner, dest ...interface{}) (*model.Comment, error) {
+	var (
+		comment   model.Comment
+		id        *uuid.UUID
+		author    *uuid.UUID
+		content   *string
+		createdAt *time.Time
+		upvotes   *int32
+		downvotes *int32
+		status    *model.CommentStatus
+	)
+	dest = append(dest, &id, &comment.PostID, &comment.ParentID, &author, &content, &createdAt,
+		&comment.EditedAt, &upvotes, &downvotes, &comment.DeletedAt, &comment.HiddenAt, &status)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if id == nil {
+		return nil, nil
+	}
+
+	comment.ID, comment.AuthorID, comment.Content, comment.CreatedAt = *id, *author, *content, *createdAt
+	comment.Upvotes, comment.Downvotes, comment.Status = *upvotes, *downvotes, *status
+	return &comment, nil
+}
+
+func scanAction(row rowScanner) (*model.ModerationAction, error) {
+	var action model.ModerationAction
+	if err := row.Scan(&action.ID, &action.ModeratorID, &action.Action, &action.TargetID, &action.Reason, &action.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &action, nil
+}
+
+func scanReport(row rowScanner) (*model.Report, error) {
+	var (
+		report            model.Report
+		postID, commentID *uuid.UUID
+	)
+	if err := row.Scan(&report.ID, &report.ReporterID, &postID, &commentID, &report.Reason, &report.CreatedAt,
+		&report.ResolvedAt, &report.ResolverID, &report.Resolution); err != nil {
+		return nil, err
+	}
+	report.TargetType, report.TargetID = reportTarget(postID, commentID)
+	return &report, nil
+}
+
+// reportTarget определяет объект жалобы по заполненной из колонок post_id и comment_id.
+func reportTarget(postID, commentID *uuid.UUID) (model.ReportTargetType, uuid.UUID) {
+	if postID != nil {
+		return model.ReportTargetTypePost, *postID
+	}
+	return model.ReportTargetTypeComment, *commentID
+}
+
+// constraintError переводит нарушение внешнего ключа в ErrNotFound (нет пользователя
+// или поста, на которые ссылается строка), а уникального индекса — в ErrAlreadyExists.
+// Остальные ошибки оборачиваются с сообщением msg.
+func constraintError(err error, msg string) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case pgForeignKeyViolation:
+			return ErrNotFound
+		case pgUniqueViolation:
+			return ErrAlreadyExists
+		}
+	}
+	return fmt.Errorf("%s: %v", msg, err)
+}
+
+// qualify добавляет псевдоним таблицы к каждой колонке из списка.
+func qualify(columns, alias string) string {
+	parts := strings.Split(columns, ", ")
+	for i, col := range parts {
+		parts[i] = alias + "." + col
+	}
+	return strings.Join(parts, ", ")
+}
+
 func placeholders(n int) string {
 	parts := make([]string, n)
 	for i := 0; i < n; i++ {
@@ -4,11 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"graphql_project/internal/graph/model"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -24,14 +27,17 @@ func TestPostgresStorage_CreatePost(t *testing.T) {
 	t.Run("successful creation", func(t *testing.T) {
 		newPost := model.NewPost{
 			Title:       "Test Post",
-			Author:      "Author",
+			AuthorID:    uuid.New(),
 			Content:     "Content",
 			Commentable: true,
 		}
 
+		mock.ExpectBegin()
+		expectAuthor(mock, newPost.AuthorID, nil)
 		mock.ExpectExec("INSERT INTO posts").
-			WithArgs(sqlmock.AnyArg(), newPost.Title, newPost.Author, newPost.Content, newPost.Commentable).
+			WithArgs(sqlmock.AnyArg(), newPost.Title, newPost.AuthorID, newPost.Content, newPost.Commentable, model.ModerationModeNone, sqlmock.AnyArg()).
 			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
 
 		post, err := storage.CreatePost(ctx, newPost)
 		require.NoError(t, err)
@@ -40,10 +46,38 @@ func TestPostgresStorage_CreatePost(t *testing.T) {
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
+	t.Run("unknown author", func(t *testing.T) {
+		authorID := uuid.New()
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT banned_at FROM users WHERE id = \\$1 FOR SHARE").
+			WithArgs(authorID).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		_, err := storage.CreatePost(ctx, model.NewPost{Title: "Post", AuthorID: authorID})
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("banned author", func(t *testing.T) {
+		authorID := uuid.New()
+		bannedAt := time.Now()
+		mock.ExpectBegin()
+		expectAuthor(mock, authorID, &bannedAt)
+		mock.ExpectRollback()
+
+		_, err := storage.CreatePost(ctx, model.NewPost{Title: "Post", AuthorID: authorID})
+		assert.ErrorIs(t, err, ErrBanned)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
 	t.Run("database error", func(t *testing.T) {
 		newPost := model.NewPost{Title: "Error Test"}
+		mock.ExpectBegin()
+		expectAuthor(mock, newPost.AuthorID, nil)
 		mock.ExpectExec("INSERT INTO posts").
 			WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
 
 		_, err := storage.CreatePost(ctx, newPost)
 		assert.Error(t, err)
@@ -51,7 +85,7 @@ func TestPostgresStorage_CreatePost(t *testing.T) {
 	})
 }
 
-func TestPostgresStorage_GetAllPosts(t *testing.T) {
+func TestPostgresStorage_Users(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
 	defer db.Close()
@@ -59,18 +93,77 @@ func TestPostgresStorage_GetAllPosts(t *testing.T) {
 	storage := &PostgresStorage{db: db}
 	ctx := context.Background()
 
-	postRows := sqlmock.NewRows([]string{"id", "title", "author", "content", "commentable"}).
-		AddRow(uuid.New(), "Post 1", "Author", "Content", true).
-		AddRow(uuid.New(), "Post 2", "Author", "Content", false)
+	t.Run("create", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO users \\(id, handle, display_name, created_at\\)").
+			WithArgs(sqlmock.AnyArg(), "alice", "alice", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
 
-	commentRows := sqlmock.NewRows([]string{"id", "post_id", "parent_comment_id", "author", "content"})
+		user, err := storage.CreateUser(ctx, model.NewUser{Handle: "alice"})
+		require.NoError(t, err)
+		assert.Equal(t, "alice", user.DisplayName)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 
-	t.Run("get all posts", func(t *testing.T) {
-		mock.ExpectQuery("SELECT id, title, author, content, commentable FROM posts").
-			WillReturnRows(postRows)
+	t.Run("duplicate handle", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO users").
+			WillReturnError(&pq.Error{Code: pgUniqueViolation})
+
+		_, err := storage.CreateUser(ctx, model.NewUser{Handle: "alice"})
+		assert.ErrorIs(t, err, ErrAlreadyExists)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("by handle", func(t *testing.T) {
+		userID := uuid.New()
+		mock.ExpectQuery("SELECT " + userColumns + " FROM users WHERE handle = \\$1").
+			WithArgs("alice").
+			WillReturnRows(sqlmock.NewRows(strings.Split(userColumns, ", ")).AddRow(userID, "alice", "Alice", time.Now(), nil))
+
+		user, err := storage.GetUserByHandle(ctx, "alice")
+		require.NoError(t, err)
+		assert.Equal(t, userID, user.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 
-		mock.ExpectQuery("SELECT id, post_id, parent_comment_id, author, content FROM comments").
-			WillReturnRows(commentRows)
+	t.Run("not found", func(t *testing.T) {
+		mock.ExpectQuery("SELECT " + userColumns + " FROM users WHERE id = \\$1").
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := storage.GetUserByID(ctx, uuid.New())
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("by ids", func(t *testing.T) {
+		ids := []uuid.UUID{uuid.New(), uuid.New()}
+		mock.ExpectQuery("SELECT "+userColumns+" FROM users WHERE id IN \\(\\$1, \\$2\\)").
+			WithArgs(ids[0], ids[1]).
+			WillReturnRows(sqlmock.NewRows(strings.Split(userColumns, ", ")).AddRow(ids[1], "bob", "Bob", time.Now(), nil))
+
+		users, err := storage.GetUsersByIDs(ctx, ids)
+		require.NoError(t, err)
+		require.Len(t, users, 1)
+		assert.Equal(t, "bob", users[0].Handle)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresStorage_GetAllPosts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+	ctx := context.Background()
+
+	rows := postRows(
+		&model.Post{ID: uuid.New(), Title: "Post 1", AuthorID: uuid.New(), Content: "Content", Commentable: true, CreatedAt: time.Now()},
+		&model.Post{ID: uuid.New(), Title: "Post 2", AuthorID: uuid.New(), Content: "Content", CreatedAt: time.Now()},
+	)
+
+	t.Run("get all posts", func(t *testing.T) {
+		mock.ExpectQuery("SELECT " + postColumns + " FROM posts WHERE deleted_at IS NULL ORDER BY created_at, id").
+			WillReturnRows(rows)
 
 		posts, err := storage.GetAllPosts(ctx, nil, nil)
 		require.NoError(t, err)
@@ -79,6 +172,64 @@ func TestPostgresStorage_GetAllPosts(t *testing.T) {
 	})
 }
 
+func TestPostgresStorage_GetPostsPage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+
+	t.Run("first after cursor", func(t *testing.T) {
+		first := 2
+		after := model.Cursor{CreatedAt: now, ID: uuid.New()}
+
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM posts WHERE deleted_at IS NULL").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(10))
+		mock.ExpectQuery("FROM posts WHERE deleted_at IS NULL AND \\(created_at, id\\) > \\(\\$1, \\$2\\) ORDER BY created_at, id LIMIT \\$3").
+			WithArgs(after.CreatedAt, after.ID, first+1).
+			WillReturnRows(postRows(
+				&model.Post{ID: ids[0], Title: "Post 1", CreatedAt: now.Add(time.Second)},
+				&model.Post{ID: ids[1], Title: "Post 2", CreatedAt: now.Add(2 * time.Second)},
+				&model.Post{ID: ids[2], Title: "Post 3", CreatedAt: now.Add(3 * time.Second)},
+			))
+
+		page, err := storage.GetPostsPage(ctx, model.PageArgs{First: &first, After: &after})
+		require.NoError(t, err)
+		require.Len(t, page.Posts, 2)
+		assert.Equal(t, ids[0], page.Posts[0].ID)
+		assert.True(t, page.HasNextPage)
+		assert.True(t, page.HasPreviousPage)
+		assert.Equal(t, 10, page.TotalCount)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("last page", func(t *testing.T) {
+		last := 2
+
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM posts WHERE deleted_at IS NULL").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectQuery("FROM posts WHERE deleted_at IS NULL ORDER BY created_at DESC, id DESC LIMIT \\$1").
+			WithArgs(last + 1).
+			WillReturnRows(postRows(
+				&model.Post{ID: ids[1], Title: "Post 2", CreatedAt: now.Add(2 * time.Second)},
+				&model.Post{ID: ids[0], Title: "Post 1", CreatedAt: now.Add(time.Second)},
+			))
+
+		page, err := storage.GetPostsPage(ctx, model.PageArgs{Last: &last})
+		require.NoError(t, err)
+		require.Len(t, page.Posts, 2)
+		assert.Equal(t, ids[0], page.Posts[0].ID)
+		assert.Equal(t, ids[1], page.Posts[1].ID)
+		assert.False(t, page.HasNextPage)
+		assert.False(t, page.HasPreviousPage)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
 func TestPostgresStorage_GetPostByID(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
@@ -88,29 +239,21 @@ func TestPostgresStorage_GetPostByID(t *testing.T) {
 	ctx := context.Background()
 
 	postID := uuid.New()
-	commentID := uuid.New()
 	nonExistentID := uuid.New().String()
 
 	t.Run("existing post", func(t *testing.T) {
-		mock.ExpectQuery("SELECT id, title, author, content, commentable FROM posts WHERE id = \\$1").
+		mock.ExpectQuery("SELECT " + postColumns + " FROM posts WHERE id = \\$1 AND deleted_at IS NULL").
 			WithArgs(postID.String()).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "author", "content", "commentable"}).
-				AddRow(postID, "Test Post", "Author", "Content", true))
-
-		mock.ExpectQuery("SELECT id, post_id, parent_comment_id, author, content FROM comments WHERE post_id = \\$1").
-			WithArgs(postID).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "parent_comment_id", "author", "content"}).
-				AddRow(commentID, postID, nil, "User", "Comment"))
+			WillReturnRows(postRows(&model.Post{ID: postID, Title: "Test Post", AuthorID: uuid.New(), Content: "Content", Commentable: true, CreatedAt: time.Now()}))
 
 		post, err := storage.GetPostByID(ctx, postID.String())
 		require.NoError(t, err)
 		assert.Equal(t, postID, post.ID)
-		assert.Len(t, post.Comments, 1)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
 	t.Run("not found", func(t *testing.T) {
-		mock.ExpectQuery("SELECT id, title, author, content, commentable FROM posts WHERE id = \\$1").
+		mock.ExpectQuery("SELECT " + postColumns + " FROM posts WHERE id = \\$1 AND deleted_at IS NULL").
 			WithArgs(nonExistentID).
 			WillReturnError(sql.ErrNoRows)
 
@@ -131,43 +274,97 @@ func TestPostgresStorage_CreateComment(t *testing.T) {
 
 	postID := uuid.New()
 	commentID := uuid.New()
+	authorID := uuid.New()
 
 	t.Run("comment to post", func(t *testing.T) {
 		mock.ExpectBegin()
-		mock.ExpectQuery("SELECT commentable FROM posts WHERE id = ?").
+		expectAuthor(mock, authorID, nil)
+		mock.ExpectQuery("SELECT commentable, created_at, unlocked_at, moderation_mode FROM posts WHERE id = ?").
+			WithArgs(postID).
+			WillReturnRows(sqlmock.NewRows([]string{"commentable", "created_at", "unlocked_at", "moderation_mode"}).AddRow(true, time.Now(), nil, "NONE"))
+		mock.ExpectExec("INSERT INTO comments").
+			WithArgs(sqlmock.AnyArg(), postID, authorID, "Content", sqlmock.AnyArg(), model.CommentStatusPublished).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		_, err := storage.CreateComment(ctx, model.NewComment{
+			AuthorID: authorID,
+			Content:  "Content",
+			PostID:   &postID,
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("comment with filter results", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectAuthor(mock, authorID, nil)
+		mock.ExpectQuery("SELECT commentable, created_at, unlocked_at, moderation_mode FROM posts WHERE id = ?").
 			WithArgs(postID).
-			WillReturnRows(sqlmock.NewRows([]string{"commentable"}).AddRow(true))
+			WillReturnRows(sqlmock.NewRows([]string{"commentable", "created_at", "unlocked_at", "moderation_mode"}).AddRow(true, time.Now(), nil, "NONE"))
 		mock.ExpectExec("INSERT INTO comments").
-			WithArgs(sqlmock.AnyArg(), postID, "Author", "Content").
+			WithArgs(sqlmock.AnyArg(), postID, authorID, "well ****", sqlmock.AnyArg(), model.CommentStatusPublished).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO comment_filter_results").
+			WithArgs(sqlmock.AnyArg(), 0, "blocklist", model.FilterActionRewrite, "blocked words: darn").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO comment_filter_results").
+			WithArgs(sqlmock.AnyArg(), 1, "repeat", model.FilterActionFlag, "repeated").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 
 		_, err := storage.CreateComment(ctx, model.NewComment{
-			Author:  "Author",
-			Content: "Content",
-			PostID:  ptr(postID.String()),
+			AuthorID: authorID,
+			Content:  "well ****",
+			PostID:   &postID,
+			FilterResults: []*model.FilterResult{
+				{Filter: "blocklist", Action: model.FilterActionRewrite, Reason: "blocked words: darn"},
+				{Filter: "repeat", Action: model.FilterActionFlag, Reason: "repeated"},
+			},
 		})
 		assert.NoError(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
+	t.Run("comment to pre-moderated post", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectAuthor(mock, authorID, nil)
+		mock.ExpectQuery("SELECT commentable, created_at, unlocked_at, moderation_mode FROM posts WHERE id = ?").
+			WithArgs(postID).
+			WillReturnRows(sqlmock.NewRows([]string{"commentable", "created_at", "unlocked_at", "moderation_mode"}).AddRow(true, time.Now(), nil, "PRE"))
+		mock.ExpectExec("INSERT INTO comments").
+			WithArgs(sqlmock.AnyArg(), postID, authorID, "Content", sqlmock.AnyArg(), model.CommentStatusPending).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		comment, err := storage.CreateComment(ctx, model.NewComment{
+			AuthorID: authorID,
+			Content:  "Content",
+			PostID:   &postID,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, model.CommentStatusPending, comment.Status)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
 	t.Run("comment to comment", func(t *testing.T) {
 		mock.ExpectBegin()
-		mock.ExpectQuery("SELECT post_id FROM comments WHERE id = ?").
+		expectAuthor(mock, authorID, nil)
+		mock.ExpectQuery("SELECT post_id FROM comments WHERE id = \\$1 AND deleted_at IS NULL AND status = 'PUBLISHED'").
 			WithArgs(commentID).
 			WillReturnRows(sqlmock.NewRows([]string{"post_id"}).AddRow(postID))
-		mock.ExpectQuery("SELECT commentable FROM posts WHERE id = ?").
+		mock.ExpectQuery("SELECT commentable, created_at, unlocked_at, moderation_mode FROM posts WHERE id = ?").
 			WithArgs(postID).
-			WillReturnRows(sqlmock.NewRows([]string{"commentable"}).AddRow(true))
+			WillReturnRows(sqlmock.NewRows([]string{"commentable", "created_at", "unlocked_at", "moderation_mode"}).AddRow(true, time.Now(), nil, "NONE"))
 		mock.ExpectExec("INSERT INTO comments").
-			WithArgs(sqlmock.AnyArg(), postID, commentID, "Author", "Content").
+			WithArgs(sqlmock.AnyArg(), postID, commentID, authorID, "Content", sqlmock.AnyArg(), model.CommentStatusPublished).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 
 		_, err := storage.CreateComment(ctx, model.NewComment{
-			Author:    "Author",
+			AuthorID:  authorID,
 			Content:   "Content",
-			CommentID: ptr(commentID.String()),
+			CommentID: &commentID,
 		})
 		assert.NoError(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
@@ -175,19 +372,961 @@ func TestPostgresStorage_CreateComment(t *testing.T) {
 
 	t.Run("post not commentable", func(t *testing.T) {
 		mock.ExpectBegin()
-		mock.ExpectQuery("SELECT commentable FROM posts WHERE id = ?").
+		expectAuthor(mock, authorID, nil)
+		mock.ExpectQuery("SELECT commentable, created_at, unlocked_at, moderation_mode FROM posts WHERE id = ?").
+			WithArgs(postID).
+			WillReturnRows(sqlmock.NewRows([]string{"commentable", "created_at", "unlocked_at", "moderation_mode"}).AddRow(false, time.Now(), nil, "NONE"))
+		mock.ExpectRollback()
+
+		_, err := storage.CreateComment(ctx, model.NewComment{
+			AuthorID: authorID,
+			Content:  "Content",
+			PostID:   &postID,
+		})
+		assert.ErrorIs(t, err, ErrNotCommentable)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("post auto-locked", func(t *testing.T) {
+		storage := &PostgresStorage{db: db, opts: newOptions([]Option{WithAutoLock(24 * time.Hour)})}
+
+		mock.ExpectBegin()
+		expectAuthor(mock, authorID, nil)
+		mock.ExpectQuery("SELECT commentable, created_at, unlocked_at, moderation_mode FROM posts WHERE id = ?").
 			WithArgs(postID).
-			WillReturnRows(sqlmock.NewRows([]string{"commentable"}).AddRow(false))
+			WillReturnRows(sqlmock.NewRows([]string{"commentable", "created_at", "unlocked_at", "moderation_mode"}).AddRow(true, time.Now().Add(-48*time.Hour), nil, "NONE"))
 		mock.ExpectRollback()
 
 		_, err := storage.CreateComment(ctx, model.NewComment{
-			Author:  "Author",
-			Content: "Content",
-			PostID:  ptr(postID.String()),
+			AuthorID: authorID,
+			Content:  "Content",
+			PostID:   &postID,
 		})
 		assert.ErrorIs(t, err, ErrNotCommentable)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
+
+	t.Run("banned author", func(t *testing.T) {
+		bannedAt := time.Now()
+		mock.ExpectBegin()
+		expectAuthor(mock, authorID, &bannedAt)
+		mock.ExpectRollback()
+
+		_, err := storage.CreateComment(ctx, model.NewComment{
+			AuthorID: authorID,
+			Content:  "Content",
+			PostID:   &postID,
+		})
+		assert.ErrorIs(t, err, ErrBanned)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresStorage_GetPostsByIDs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+	ctx := context.Background()
+
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+	mock.ExpectQuery("SELECT "+postColumns+" FROM posts WHERE id IN \\(\\$1, \\$2\\) AND deleted_at IS NULL").
+		WithArgs(ids[0], ids[1]).
+		WillReturnRows(postRows(&model.Post{ID: ids[1], Title: "Post 2", CreatedAt: time.Now()}))
+
+	posts, err := storage.GetPostsByIDs(ctx, ids)
+	require.NoError(t, err)
+	require.Len(t, posts, 1)
+	assert.Equal(t, ids[1], posts[0].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_GetCommentsByPostIDs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+	ctx := context.Background()
+
+	postID := uuid.New()
+	emptyPostID := uuid.New()
+	commentID := uuid.New()
+	first := 1
+	columns := append([]string{"id", "total"}, strings.Split(commentColumns, ", ")...)
+
+	mock.ExpectQuery("FROM unnest\\(ARRAY\\[\\$1, \\$2\\]::uuid\\[\\]\\) AS p\\(id\\) "+
+		"CROSS JOIN LATERAL \\(SELECT COUNT\\(\\*\\) AS total FROM comments WHERE post_id = p.id AND parent_comment_id IS NULL AND "+
+		regexp.QuoteMeta(visibleComment)+"\\) AS cnt "+
+		"LEFT JOIN LATERAL \\(.+ ORDER BY created_at, id LIMIT \\$3\\) AS c ON TRUE").
+		WithArgs(postID, emptyPostID, first+1).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(postID, 2, commentID, postID, nil, uuid.New(), "Comment", time.Now(), nil, 3, 1, nil, nil, "PUBLISHED").
+			AddRow(postID, 2, uuid.New(), postID, nil, uuid.New(), "Comment", time.Now(), nil, 0, 0, nil, nil, "PUBLISHED").
+			AddRow(emptyPostID, 0, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil))
+
+	pages, err := storage.GetCommentsByPostIDs(ctx, []uuid.UUID{postID, emptyPostID}, model.PageArgs{First: &first})
+	require.NoError(t, err)
+	require.Len(t, pages[postID].Comments, 1)
+	assert.Equal(t, commentID, pages[postID].Comments[0].ID)
+	assert.Nil(t, pages[postID].Comments[0].ParentID)
+	assert.EqualValues(t, 2, pages[postID].Comments[0].Score())
+	assert.True(t, pages[postID].HasNextPage)
+	assert.Equal(t, 2, pages[postID].TotalCount)
+	assert.Empty(t, pages[emptyPostID].Comments)
+	assert.False(t, pages[emptyPostID].HasNextPage)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_GetRepliesByCommentIDs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+	ctx := context.Background()
+
+	postID := uuid.New()
+	parentID := uuid.New()
+	after := model.Cursor{CreatedAt: time.Now().UTC(), ID: uuid.New()}
+	first := 5
+
+	mock.ExpectQuery("WHERE parent_comment_id = p.id AND status = 'PUBLISHED' AND \\(deleted_at IS NULL OR EXISTS .+\\) AND \\(created_at, id\\) > \\(\\$2, \\$3\\) ORDER BY created_at, id LIMIT \\$4\\) AS c ON TRUE ORDER BY p.id, c.created_at, c.id").
+		WithArgs(parentID, after.CreatedAt, after.ID, first+1).
+		WillReturnRows(sqlmock.NewRows(append([]string{"id", "total"}, strings.Split(commentColumns, ", ")...)).
+			AddRow(parentID, 1, uuid.New(), postID, parentID, uuid.New(), "Reply", time.Now(), time.Now(), 0, 0, nil, nil, "PUBLISHED"))
+
+	pages, err := storage.GetRepliesByCommentIDs(ctx, []uuid.UUID{parentID}, model.PageArgs{First: &first, After: &after})
+	require.NoError(t, err)
+	require.Len(t, pages[parentID].Comments, 1)
+	assert.Equal(t, parentID, *pages[parentID].Comments[0].ParentID)
+	assert.NotNil(t, pages[parentID].Comments[0].EditedAt)
+	assert.False(t, pages[parentID].HasNextPage)
+	assert.True(t, pages[parentID].HasPreviousPage)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_GetCommentsByPostIDs_Sort(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+	ctx := context.Background()
+
+	postID := uuid.New()
+	after := model.Cursor{Rank: 3, CreatedAt: time.Now().UTC(), ID: uuid.New()}
+	first := 1
+
+	mock.ExpectQuery(regexp.QuoteMeta("AND (upvotes - downvotes, created_at, id) < ($2, $3, $4) "+
+		"ORDER BY upvotes - downvotes DESC, created_at DESC, id DESC LIMIT $5) AS c ON TRUE "+
+		"ORDER BY p.id, c.upvotes - c.downvotes DESC, c.created_at DESC, c.id DESC")).
+		WithArgs(postID, after.Rank, after.CreatedAt, after.ID, first+1).
+		WillReturnRows(sqlmock.NewRows(append([]string{"id", "total"}, strings.Split(commentColumns, ", ")...)).
+			AddRow(postID, 2, uuid.New(), postID, nil, uuid.New(), "Comment", time.Now(), nil, 1, 0, nil, nil, "PUBLISHED"))
+
+	pages, err := storage.GetCommentsByPostIDs(ctx, []uuid.UUID{postID}, model.PageArgs{First: &first, After: &after, Sort: model.CommentSortTop})
+	require.NoError(t, err)
+	require.Len(t, pages[postID].Comments, 1)
+	assert.True(t, pages[postID].HasPreviousPage)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_UpdatePost(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+	ctx := context.Background()
+
+	postID := uuid.New()
+	createdAt := time.Now().UTC().Add(-time.Hour)
+	title := "New title"
+
+	t.Run("saves revision", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT " + postColumns + " FROM posts WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+			WithArgs(postID).
+			WillReturnRows(postRows(&model.Post{ID: postID, Title: "Old title", Content: "Content", Commentable: true, CreatedAt: createdAt}))
+		mock.ExpectExec("INSERT INTO post_revisions").
+			WithArgs(postID, "Old title", "Content", true, createdAt).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE posts SET title = \\$1, content = \\$2, commentable = \\$3, locked_at = \\$4, lock_reason = \\$5, unlocked_at = \\$6, moderation_mode = \\$7, updated_at = \\$8 WHERE id = \\$9").
+			WithArgs(title, "Content", true, nil, nil, nil, model.ModerationModeNone, sqlmock.AnyArg(), postID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		post, err := storage.UpdatePost(ctx, postID, model.UpdatePost{Title: &title})
+		require.NoError(t, err)
+		assert.Equal(t, title, post.Title)
+		assert.NotNil(t, post.UpdatedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("commentable unlocks", func(t *testing.T) {
+		lockedAt, reason, open := createdAt, "off-topic", true
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT " + postColumns + " FROM posts WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+			WithArgs(postID).
+			WillReturnRows(postRows(&model.Post{ID: postID, Title: "Title", CreatedAt: createdAt, LockedAt: &lockedAt, LockReason: &reason}))
+		mock.ExpectExec("INSERT INTO post_revisions").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE posts SET").
+			WithArgs("Title", "", true, nil, nil, sqlmock.AnyArg(), model.ModerationModeNone, sqlmock.AnyArg(), postID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		post, err := storage.UpdatePost(ctx, postID, model.UpdatePost{Commentable: &open})
+		require.NoError(t, err)
+		assert.True(t, post.Commentable)
+		assert.Nil(t, post.LockReason)
+		assert.NotNil(t, post.UnlockedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("appends filter results", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT " + postColumns + " FROM posts WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+			WithArgs(postID).
+			WillReturnRows(postRows(&model.Post{ID: postID, Title: "Old title", Content: "Content", Commentable: true, CreatedAt: createdAt}))
+		mock.ExpectExec("INSERT INTO post_revisions").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE posts SET").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM post_filter_results WHERE post_id = \\$1").
+			WithArgs(postID).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectExec("INSERT INTO post_filter_results \\(post_id, position, filter, action, reason\\)").
+			WithArgs(postID, 2, "repeat", model.FilterActionFlag, "repeated").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		results := []*model.FilterResult{{Filter: "repeat", Action: model.FilterActionFlag, Reason: "repeated"}}
+		_, err := storage.UpdatePost(ctx, postID, model.UpdatePost{Title: &title, FilterResults: results})
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT " + postColumns + " FROM posts WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+			WithArgs(postID).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		_, err := storage.UpdatePost(ctx, postID, model.UpdatePost{Title: &title})
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresStorage_GetPostRevisions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+	ctx := context.Background()
+
+	postID := uuid.New()
+	mock.ExpectQuery("SELECT post_id, version, title, content, commentable, created_at FROM post_revisions WHERE post_id = \\$1 ORDER BY version").
+		WithArgs(postID).
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "version", "title", "content", "commentable", "created_at"}).
+			AddRow(postID, 1, "v1", "Content", true, time.Now()).
+			AddRow(postID, 2, "v2", "Content", false, time.Now()))
+
+	revisions, err := storage.GetPostRevisions(ctx, postID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.EqualValues(t, 1, revisions[0].Version)
+	assert.Equal(t, "v2", revisions[1].Title)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-func ptr(s string) *string { return &s }
+func TestPostgresStorage_EditComment(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+	ctx := context.Background()
+
+	commentID := uuid.New()
+	postID := uuid.New()
+	createdAt := time.Now().UTC().Add(-time.Hour)
+
+	t.Run("saves revision", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT " + commentColumns + " FROM comments WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+			WithArgs(commentID).
+			WillReturnRows(sqlmock.NewRows(strings.Split(commentColumns, ", ")).
+				AddRow(commentID, postID, nil, uuid.New(), "Old", createdAt, nil, 0, 0, nil, nil, "PUBLISHED"))
+		mock.ExpectExec("INSERT INTO comment_revisions").
+			WithArgs(commentID, "Old", createdAt).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE comments SET content = \\$1, edited_at = \\$2 WHERE id = \\$3").
+			WithArgs("New", sqlmock.AnyArg(), commentID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		comment, err := storage.EditComment(ctx, commentID, "New", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "New", comment.Content)
+		assert.Equal(t, postID, *comment.PostID)
+		assert.NotNil(t, comment.EditedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT " + commentColumns + " FROM comments WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+			WithArgs(commentID).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		_, err := storage.EditComment(ctx, commentID, "New", nil)
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("hidden", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT " + commentColumns + " FROM comments WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+			WithArgs(commentID).
+			WillReturnRows(sqlmock.NewRows(strings.Split(commentColumns, ", ")).
+				AddRow(commentID, postID, nil, uuid.New(), "Old", createdAt, nil, 0, 0, nil, createdAt, "PUBLISHED"))
+		mock.ExpectRollback()
+
+		_, err := storage.EditComment(ctx, commentID, "New", nil)
+		assert.ErrorIs(t, err, ErrHidden)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresStorage_GetCommentHistory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+	ctx := context.Background()
+
+	commentID := uuid.New()
+	mock.ExpectQuery("SELECT comment_id, version, content, created_at FROM comment_revisions WHERE comment_id = \\$1 ORDER BY version").
+		WithArgs(commentID).
+		WillReturnRows(sqlmock.NewRows([]string{"comment_id", "version", "content", "created_at"}).
+			AddRow(commentID, 1, "v1", time.Now()))
+
+	history, err := storage.GetCommentHistory(ctx, commentID)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "v1", history[0].Content)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_GetCommentFilterResults(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+	ctx := context.Background()
+
+	commentID := uuid.New()
+	mock.ExpectQuery("SELECT filter, action, reason FROM comment_filter_results WHERE comment_id = \\$1 ORDER BY position").
+		WithArgs(commentID).
+		WillReturnRows(sqlmock.NewRows([]string{"filter", "action", "reason"}).
+			AddRow("repeat", "FLAG", "repeated"))
+
+	results, err := storage.GetCommentFilterResults(ctx, commentID)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, model.FilterActionFlag, results[0].Action)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_SetCommentable(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+	ctx := context.Background()
+
+	postID := uuid.New()
+	reason := "off-topic"
+
+	t.Run("lock", func(t *testing.T) {
+		lockedAt := time.Now().UTC()
+		mock.ExpectQuery("UPDATE posts SET commentable = \\$1, locked_at = \\$2, lock_reason = \\$3, unlocked_at = \\$4 WHERE id = \\$5 AND deleted_at IS NULL RETURNING "+postColumns).
+			WithArgs(false, sqlmock.AnyArg(), &reason, nil, postID).
+			WillReturnRows(postRows(&model.Post{ID: postID, CreatedAt: time.Now(), LockedAt: &lockedAt, LockReason: &reason}))
+
+		post, err := storage.SetCommentable(ctx, postID, false, &reason)
+		require.NoError(t, err)
+		assert.False(t, post.Commentable)
+		assert.Equal(t, reason, *post.LockReason)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unlock clears reason", func(t *testing.T) {
+		mock.ExpectQuery("UPDATE posts SET commentable").
+			WithArgs(true, nil, nil, sqlmock.AnyArg(), postID).
+			WillReturnRows(postRows(&model.Post{ID: postID, Commentable: true, CreatedAt: time.Now()}))
+
+		post, err := storage.SetCommentable(ctx, postID, true, &reason)
+		require.NoError(t, err)
+		assert.True(t, post.Commentable)
+		assert.Nil(t, post.LockReason)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unlock overrides auto-lock", func(t *testing.T) {
+		storage := &PostgresStorage{db: db, opts: newOptions([]Option{WithAutoLock(24 * time.Hour)})}
+		unlockedAt := time.Now().UTC()
+		mock.ExpectQuery("UPDATE posts SET commentable").
+			WithArgs(true, nil, nil, sqlmock.AnyArg(), postID).
+			WillReturnRows(postRows(&model.Post{ID: postID, Commentable: true, CreatedAt: time.Now().Add(-48 * time.Hour), UnlockedAt: &unlockedAt}))
+
+		post, err := storage.SetCommentable(ctx, postID, true, nil)
+		require.NoError(t, err)
+		assert.True(t, post.Commentable)
+		assert.Nil(t, post.LockedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mock.ExpectQuery("UPDATE posts SET commentable").
+			WithArgs(false, sqlmock.AnyArg(), nil, nil, postID).
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := storage.SetCommentable(ctx, postID, false, nil)
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresStorage_VotePost(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+	ctx := context.Background()
+
+	postID, userID := uuid.New(), uuid.New()
+
+	t.Run("changes previous vote", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT TRUE FROM posts WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+			WithArgs(postID).
+			WillReturnRows(sqlmock.NewRows([]string{"bool"}).AddRow(true))
+		mock.ExpectQuery("SELECT value FROM votes WHERE post_id = \\$1 AND user_id = \\$2").
+			WithArgs(postID, userID).
+			WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow(model.VoteDown))
+		mock.ExpectExec("INSERT INTO votes \\(post_id, user_id, value, created_at\\) .+ ON CONFLICT \\(post_id, user_id\\) WHERE post_id IS NOT NULL DO UPDATE").
+			WithArgs(postID, userID, model.VoteUp, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("UPDATE posts SET upvotes = upvotes \\+ \\$1, downvotes = downvotes \\+ \\$2 WHERE id = \\$3 RETURNING "+postColumns).
+			WithArgs(1, -1, postID).
+			WillReturnRows(postRows(&model.Post{ID: postID, CreatedAt: time.Now(), Upvotes: 1}))
+		mock.ExpectCommit()
+
+		post, err := storage.VotePost(ctx, postID, userID, model.VoteUp)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, post.Score())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("repeated vote is ignored", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT TRUE FROM posts").
+			WithArgs(postID).
+			WillReturnRows(sqlmock.NewRows([]string{"bool"}).AddRow(true))
+		mock.ExpectQuery("SELECT value FROM votes").
+			WithArgs(postID, userID).
+			WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow(model.VoteUp))
+		mock.ExpectQuery("UPDATE posts SET upvotes").
+			WithArgs(0, 0, postID).
+			WillReturnRows(postRows(&model.Post{ID: postID, CreatedAt: time.Now(), Upvotes: 1}))
+		mock.ExpectCommit()
+
+		_, err := storage.VotePost(ctx, postID, userID, model.VoteUp)
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT TRUE FROM posts").
+			WithArgs(postID).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		_, err := storage.VotePost(ctx, postID, userID, model.VoteUp)
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresStorage_VoteComment(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+	ctx := context.Background()
+
+	commentID, userID := uuid.New(), uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT TRUE FROM comments WHERE id = \\$1 AND deleted_at IS NULL AND hidden_at IS NULL AND status = 'PUBLISHED' AND " +
+		"EXISTS \\(SELECT 1 FROM posts WHERE posts.id = comments.post_id AND posts.deleted_at IS NULL\\) FOR UPDATE").
+		WithArgs(commentID).
+		WillReturnRows(sqlmock.NewRows([]string{"bool"}).AddRow(true))
+	mock.ExpectQuery("SELECT value FROM votes WHERE comment_id = \\$1 AND user_id = \\$2").
+		WithArgs(commentID, userID).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectExec("INSERT INTO votes \\(comment_id, user_id, value, created_at\\)").
+		WithArgs(commentID, userID, model.VoteDown, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("UPDATE comments SET upvotes = upvotes \\+ \\$1, downvotes = downvotes \\+ \\$2 WHERE id = \\$3 RETURNING "+commentColumns).
+		WithArgs(0, 1, commentID).
+		WillReturnRows(sqlmock.NewRows(strings.Split(commentColumns, ", ")).
+			AddRow(commentID, uuid.New(), nil, uuid.New(), "Comment", time.Now(), nil, 0, 1, nil, nil, "PUBLISHED"))
+	mock.ExpectCommit()
+
+	comment, err := storage.VoteComment(ctx, commentID, userID, model.VoteDown)
+	require.NoError(t, err)
+	assert.EqualValues(t, -1, comment.Score())
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT TRUE FROM comments WHERE id = \\$1 AND deleted_at IS NULL AND hidden_at IS NULL").
+		WithArgs(commentID).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	_, err = storage.VoteComment(ctx, commentID, userID, model.VoteUp)
+	assert.ErrorIs(t, err, ErrNotFound, "hidden, pending and orphaned comments are not votable")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_DeleteComment(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+	ctx := context.Background()
+
+	commentID := uuid.New()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectExec("UPDATE comments SET deleted_at = \\$1 WHERE id = \\$2 AND deleted_at IS NULL").
+			WithArgs(sqlmock.AnyArg(), commentID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, storage.DeleteComment(ctx, commentID))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("already deleted", func(t *testing.T) {
+		mock.ExpectExec("UPDATE comments SET deleted_at").
+			WithArgs(sqlmock.AnyArg(), commentID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.ErrorIs(t, storage.DeleteComment(ctx, commentID), ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresStorage_Moderation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+	ctx := context.Background()
+
+	commentID, userID, moderatorID := uuid.New(), uuid.New(), uuid.New()
+	reason := "spam"
+	commentRow := func(hiddenAt *time.Time) *sqlmock.Rows {
+		return sqlmock.NewRows(strings.Split(commentColumns, ", ")).
+			AddRow(commentID, uuid.New(), nil, userID, "Spam", time.Now(), nil, 0, 0, nil, hiddenAt, "PUBLISHED")
+	}
+
+	t.Run("hide comment", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT " + commentColumns + " FROM comments WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+			WithArgs(commentID).
+			WillReturnRows(commentRow(nil))
+		mock.ExpectExec("UPDATE comments SET hidden_at = \\$1 WHERE id = \\$2").
+			WithArgs(sqlmock.AnyArg(), commentID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO moderation_log").
+			WithArgs(sqlmock.AnyArg(), moderatorID, model.ModerationActionTypeHideComment, commentID, &reason, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		comment, err := storage.SetCommentHidden(ctx, commentID, true, moderatorID, &reason)
+		require.NoError(t, err)
+		assert.NotNil(t, comment.HiddenAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("hide already hidden comment", func(t *testing.T) {
+		hiddenAt := time.Now()
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT " + commentColumns + " FROM comments WHERE id = \\$1").
+			WithArgs(commentID).
+			WillReturnRows(commentRow(&hiddenAt))
+		mock.ExpectRollback()
+
+		_, err := storage.SetCommentHidden(ctx, commentID, true, moderatorID, &reason)
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet(), "nothing is updated or logged")
+	})
+
+	t.Run("unban user", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT " + userColumns + " FROM users WHERE id = \\$1 FOR UPDATE").
+			WithArgs(userID).
+			WillReturnRows(sqlmock.NewRows(strings.Split(userColumns, ", ")).AddRow(userID, "spammer", "Spammer", time.Now(), time.Now()))
+		mock.ExpectExec("UPDATE users SET banned_at = \\$1 WHERE id = \\$2").
+			WithArgs(nil, userID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO moderation_log").
+			WithArgs(sqlmock.AnyArg(), moderatorID, model.ModerationActionTypeUnbanAuthor, userID, nil, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		user, err := storage.SetUserBanned(ctx, userID, false, moderatorID, nil)
+		require.NoError(t, err)
+		assert.Nil(t, user.BannedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT " + userColumns + " FROM users").
+			WithArgs(userID).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		_, err := storage.SetUserBanned(ctx, userID, true, moderatorID, nil)
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("log", func(t *testing.T) {
+		first := 1
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM moderation_log").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectQuery("SELECT " + actionColumns + " FROM moderation_log ORDER BY created_at DESC, id DESC LIMIT \\$1").
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows(strings.Split(actionColumns, ", ")).
+				AddRow(uuid.New(), moderatorID, "BAN_AUTHOR", userID, reason, time.Now()).
+				AddRow(uuid.New(), moderatorID, "HIDE_COMMENT", commentID, nil, time.Now().Add(-time.Minute)))
+
+		page, err := storage.GetModerationLog(ctx, model.PageArgs{First: &first})
+		require.NoError(t, err)
+		require.Len(t, page.Actions, 1)
+		assert.Equal(t, model.ModerationActionTypeBanAuthor, page.Actions[0].Action)
+		assert.True(t, page.HasNextPage)
+		assert.Equal(t, 2, page.TotalCount)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresStorage_PreModeration(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+	ctx := context.Background()
+
+	commentID, postID, moderatorID := uuid.New(), uuid.New(), uuid.New()
+	commentRow := func(status model.CommentStatus) *sqlmock.Rows {
+		return sqlmock.NewRows(strings.Split(commentColumns, ", ")).
+			AddRow(commentID, postID, nil, uuid.New(), "Awaiting", time.Now(), nil, 0, 0, nil, nil, status)
+	}
+
+	t.Run("approve pending comment", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT " + commentColumns + " FROM comments WHERE id = \\$1 AND deleted_at IS NULL .+ FOR UPDATE").
+			WithArgs(commentID).
+			WillReturnRows(commentRow(model.CommentStatusPending))
+		mock.ExpectExec("UPDATE comments SET status = \\$1 WHERE id = \\$2").
+			WithArgs(model.CommentStatusPublished, commentID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO moderation_log").
+			WithArgs(sqlmock.AnyArg(), moderatorID, model.ModerationActionTypeApproveComment, commentID, nil, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		comment, err := storage.ApproveComment(ctx, commentID, moderatorID)
+		require.NoError(t, err)
+		assert.Equal(t, model.CommentStatusPublished, comment.Status)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("approve published comment", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT " + commentColumns + " FROM comments").
+			WithArgs(commentID).
+			WillReturnRows(commentRow(model.CommentStatusPublished))
+		mock.ExpectRollback()
+
+		_, err := storage.ApproveComment(ctx, commentID, moderatorID)
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet(), "nothing is updated or logged")
+	})
+
+	t.Run("approve unknown comment", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT " + commentColumns + " FROM comments").
+			WithArgs(commentID).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		_, err := storage.ApproveComment(ctx, commentID, moderatorID)
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("pending comments of post", func(t *testing.T) {
+		first := 10
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM comments WHERE status = 'PENDING' AND deleted_at IS NULL AND .+ AND post_id = \\$1").
+			WithArgs(postID).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery("SELECT "+commentColumns+" FROM comments WHERE status = 'PENDING' .+ AND post_id = \\$1 ORDER BY created_at, id LIMIT \\$2").
+			WithArgs(postID, first+1).
+			WillReturnRows(commentRow(model.CommentStatusPending))
+
+		page, err := storage.GetPendingComments(ctx, &postID, model.PageArgs{First: &first, Sort: model.CommentSortOld})
+		require.NoError(t, err)
+		assert.Equal(t, 1, page.TotalCount)
+		require.Len(t, page.Comments, 1)
+		assert.Equal(t, model.CommentStatusPending, page.Comments[0].Status)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresStorage_GetPostComments(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+	ctx := context.Background()
+	postID, replyID := uuid.New(), uuid.New()
+	after := model.Cursor{CreatedAt: time.Now().Add(-time.Minute), ID: uuid.New()}
+	first := 1
+
+	mock.ExpectQuery("SELECT "+commentColumns+" FROM comments WHERE post_id = \\$1 AND status = 'PUBLISHED' AND deleted_at IS NULL AND hidden_at IS NULL AND .+ "+
+		"AND \\(created_at, id\\) > \\(\\$2, \\$3\\) ORDER BY created_at, id LIMIT \\$4").
+		WithArgs(postID, after.CreatedAt, after.ID, first+1).
+		WillReturnRows(sqlmock.NewRows(strings.Split(commentColumns, ", ")).
+			AddRow(replyID, postID, uuid.New(), uuid.New(), "Reply", time.Now(), nil, 0, 0, nil, nil, "PUBLISHED").
+			AddRow(uuid.New(), postID, nil, uuid.New(), "Later", time.Now(), nil, 0, 0, nil, nil, "PUBLISHED"))
+
+	page, err := storage.GetPostComments(ctx, postID, model.PageArgs{First: &first, After: &after, Sort: model.CommentSortOld})
+	require.NoError(t, err)
+	require.Len(t, page.Comments, 1)
+	assert.Equal(t, replyID, page.Comments[0].ID)
+	assert.True(t, page.HasNextPage)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_GetCommentAncestors(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+	commentID, parentID, rootID := uuid.New(), uuid.New(), uuid.New()
+
+	mock.ExpectQuery("WITH RECURSIVE a AS \\(SELECT parent_comment_id AS id, 1 AS depth FROM comments WHERE id = \\$1 .+\\) " +
+		"SELECT id FROM a WHERE id IS NOT NULL ORDER BY depth").
+		WithArgs(commentID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(parentID).AddRow(rootID))
+
+	ancestors, err := storage.GetCommentAncestors(context.Background(), commentID)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{parentID, rootID}, ancestors)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_Reports(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+	ctx := context.Background()
+
+	commentID, reporterID, moderatorID := uuid.New(), uuid.New(), uuid.New()
+	newReport := model.NewReport{TargetType: model.ReportTargetTypeComment, TargetID: commentID, Reason: "spam", ReporterID: reporterID}
+
+	t.Run("create", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO reports \\(id, comment_id, reporter_id, reason, created_at\\) SELECT .* WHERE EXISTS").
+			WithArgs(commentID, sqlmock.AnyArg(), reporterID, "spam", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		report, err := storage.CreateReport(ctx, newReport)
+		require.NoError(t, err)
+		assert.Equal(t, commentID, report.TargetID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("deleted target", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO reports").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		_, err := storage.CreateReport(ctx, newReport)
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("duplicate open report", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO reports").
+			WillReturnError(&pq.Error{Code: pgUniqueViolation})
+
+		_, err := storage.CreateReport(ctx, newReport)
+		assert.ErrorIs(t, err, ErrAlreadyExists)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("queue", func(t *testing.T) {
+		postID := uuid.New()
+		mock.ExpectQuery(regexp.QuoteMeta(reportQueueQuery)).
+			WithArgs(10, 0).
+			WillReturnRows(sqlmock.NewRows([]string{"post_id", "comment_id", "count", "min", "max"}).
+				AddRow(nil, commentID, 2, time.Now(), time.Now()).
+				AddRow(postID, nil, 1, time.Now(), time.Now()))
+		mock.ExpectQuery("SELECT "+reportColumns+" FROM reports WHERE resolved_at IS NULL AND COALESCE\\(post_id, comment_id\\) IN \\(\\$1, \\$2\\)").
+			WithArgs(commentID, postID).
+			WillReturnRows(sqlmock.NewRows(strings.Split(reportColumns, ", ")).
+				AddRow(uuid.New(), reporterID, nil, commentID, "spam", time.Now(), nil, nil, nil).
+				AddRow(uuid.New(), uuid.New(), postID, nil, "offtopic", time.Now(), nil, nil, nil).
+				AddRow(uuid.New(), uuid.New(), nil, commentID, "spam", time.Now(), nil, nil, nil))
+
+		queue, err := storage.GetReportQueue(ctx, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, queue, 2)
+		assert.Equal(t, model.ReportTargetTypeComment, queue[0].TargetType)
+		assert.Len(t, queue[0].Reports, 2)
+		assert.Equal(t, model.ReportTargetTypePost, queue[1].TargetType)
+		assert.Equal(t, "offtopic", queue[1].Reports[0].Reason)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("resolve", func(t *testing.T) {
+		mock.ExpectQuery("UPDATE reports SET resolved_at = \\$1, resolver_id = \\$2, resolution = \\$3 WHERE comment_id = \\$4 AND resolved_at IS NULL").
+			WithArgs(sqlmock.AnyArg(), moderatorID, model.ReportActionDismiss, commentID).
+			WillReturnRows(sqlmock.NewRows(strings.Split(reportColumns, ", ")).
+				AddRow(uuid.New(), reporterID, nil, commentID, "spam", time.Now(), time.Now(), moderatorID, "DISMISS"))
+
+		reports, err := storage.ResolveReports(ctx, model.ReportTargetTypeComment, commentID, moderatorID, model.ReportActionDismiss)
+		require.NoError(t, err)
+		require.Len(t, reports, 1)
+		assert.Equal(t, model.ReportActionDismiss, *reports[0].Resolution)
+		assert.Equal(t, moderatorID, *reports[0].ResolverID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("resolve without open reports", func(t *testing.T) {
+		mock.ExpectQuery("UPDATE reports").
+			WillReturnRows(sqlmock.NewRows(strings.Split(reportColumns, ", ")))
+
+		_, err := storage.ResolveReports(ctx, model.ReportTargetTypeComment, commentID, moderatorID, model.ReportActionDismiss)
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresStorage_DeletePost(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+	ctx := context.Background()
+
+	postID := uuid.New()
+	mock.ExpectExec("UPDATE posts SET deleted_at = \\$1 WHERE id = \\$2 AND deleted_at IS NULL").
+		WithArgs(sqlmock.AnyArg(), postID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, storage.DeletePost(ctx, postID))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_Search(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storage := &PostgresStorage{db: db}
+	ctx := context.Background()
+
+	postID := uuid.New()
+	commentID := uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta(searchQuery)).
+		WithArgs("graphql", 10, 0, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"kind", "id", "rank", "ts_headline", "total"}).
+			AddRow("post", postID, 0.6, headlineStart+"GraphQL"+headlineStop+` post <img src=x onerror="alert(1)">`, 2).
+			AddRow("comment", commentID, 0.1, "about "+headlineStart+"graphql"+headlineStop, 2))
+	mock.ExpectQuery("SELECT " + postColumns + " FROM posts WHERE id IN \\(\\$1\\)").
+		WithArgs(postID).
+		WillReturnRows(postRows(&model.Post{ID: postID, Title: "GraphQL post", CreatedAt: time.Now()}))
+	mock.ExpectQuery("SELECT " + commentColumns + " FROM comments WHERE id IN \\(\\$1\\) AND deleted_at IS NULL").
+		WithArgs(commentID).
+		WillReturnRows(sqlmock.NewRows(strings.Split(commentColumns, ", ")).
+			AddRow(commentID, postID, nil, uuid.New(), "about graphql", time.Now(), nil, 0, 0, nil, nil, "PUBLISHED"))
+
+	page, err := storage.Search(ctx, "graphql", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, page.Hits, 2)
+	assert.Equal(t, 2, page.TotalCount)
+	assert.Equal(t, postID, page.Hits[0].Node.(*model.Post).ID)
+	assert.Equal(t, `<mark>GraphQL</mark> post &lt;img src=x onerror=&#34;alert(1)&#34;&gt;`, page.Hits[0].Snippet, "markup in the text is escaped")
+	assert.Equal(t, "about <mark>graphql</mark>", page.Hits[1].Snippet)
+	assert.Equal(t, commentID, page.Hits[1].Node.(*model.Comment).ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func postRows(posts ...*model.Post) *sqlmock.Rows {
+	rows := sqlmock.NewRows(strings.Split(postColumns, ", "))
+	for _, p := range posts {
+		var updatedAt, lockedAt, lockReason, unlockedAt interface{}
+		if p.UpdatedAt != nil {
+			updatedAt = *p.UpdatedAt
+		}
+		if p.LockedAt != nil {
+			lockedAt = *p.LockedAt
+		}
+		if p.LockReason != nil {
+			lockReason = *p.LockReason
+		}
+		if p.UnlockedAt != nil {
+			unlockedAt = *p.UnlockedAt
+		}
+		mode := p.ModerationMode
+		if mode == "" {
+			mode = model.ModerationModeNone
+		}
+		rows.AddRow(p.ID, p.Title, p.AuthorID, p.Content, p.Commentable, p.CreatedAt, updatedAt, lockedAt, lockReason, p.Upvotes, p.Downvotes, mode, unlockedAt)
+	}
+	return rows
+}
+
+// expectAuthor ожидает проверку автора в начале транзакции создания поста или комментария.
+func expectAuthor(mock sqlmock.Sqlmock, authorID uuid.UUID, bannedAt *time.Time) {
+	mock.ExpectQuery("SELECT banned_at FROM users WHERE id = \\$1 FOR SHARE").
+		WithArgs(authorID).
+		WillReturnRows(sqlmock.NewRows([]string{"banned_at"}).AddRow(bannedAt))
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"html"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// titleWeight — вес совпадения в заголовке поста относительно совпадения в тексте.
+	titleWeight = 2
+	// snippetWords — длина фрагмента с подсветкой в словах.
+	snippetWords = 20
+	// Теги подсветки совпавших слов, одинаковые для обоих хранилищ. Сам текст фрагмента
+	// экранируется для HTML.
+	highlightStart = "<mark>"
+	highlightStop  = "</mark>"
+	// Маркеры, которые ts_headline ставит вместо тегов: фрагмент экранируется после
+	// подсветки, а сами маркеры из документа заранее удаляются.
+	headlineStart = "\x02"
+	headlineStop  = "\x03"
+)
+
+// headlineMarks заменяет маркеры ts_headline тегами подсветки.
+var headlineMarks = strings.NewReplacer(headlineStart, highlightStart, headlineStop, highlightStop)
+
+// searchIndex — обратный индекс для inmemStorage: для каждого слова хранит взвешенное
+// число его вхождений в документы (посты и комментарии).
+type searchIndex struct {
+	postings map[string]map[uuid.UUID]int
+	// terms — слова, под которыми документ записан в postings, для переиндексации.
+	terms map[uuid.UUID][]string
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings: make(map[string]map[uuid.UUID]int),
+		terms:    make(map[uuid.UUID][]string),
+	}
+}
+
+// add индексирует документ заново; title может быть пустым.
+func (idx *searchIndex) add(id uuid.UUID, title, content string) {
+	idx.remove(id)
+
+	weights := make(map[string]int)
+	for _, term := range tokenize(title) {
+		weights[term] += titleWeight
+	}
+	for _, term := range tokenize(content) {
+		weights[term]++
+	}
+
+	for term, weight := range weights {
+		if idx.postings[term] == nil {
+			idx.postings[term] = make(map[uuid.UUID]int)
+		}
+		idx.postings[term][id] = weight
+		idx.terms[id] = append(idx.terms[id], term)
+	}
+}
+
+func (idx *searchIndex) remove(id uuid.UUID) {
+	for _, term := range idx.terms[id] {
+		delete(idx.postings[term], id)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	delete(idx.terms, id)
+}
+
+// match возвращает документы, содержащие все слова запроса, с их рангом.
+func (idx *searchIndex) match(terms []string) map[uuid.UUID]float64 {
+	if len(terms) == 0 {
+		return nil
+	}
+
+	ranks := make(map[uuid.UUID]float64)
+	for id, weight := range idx.postings[terms[0]] {
+		ranks[id] = float64(weight)
+	}
+	for _, term := range terms[1:] {
+		for id := range ranks {
+			weight, ok := idx.postings[term][id]
+			if !ok {
+				delete(ranks, id)
+				continue
+			}
+			ranks[id] += float64(weight)
+		}
+	}
+	return ranks
+}
+
+// tokenize разбивает текст на слова в нижнем регистре.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// highlight возвращает экранированный для HTML фрагмент текста вокруг первого совпадения,
+// обернув совпавшие слова в теги подсветки.
+func highlight(text string, terms []string) string {
+	words := strings.Fields(text)
+	matched := func(word string) bool {
+		return slices.ContainsFunc(tokenize(word), func(t string) bool {
+			return slices.Contains(terms, t)
+		})
+	}
+
+	start := max(slices.IndexFunc(words, matched)-snippetWords/4, 0)
+	end := min(start+snippetWords, len(words))
+
+	snippet := make([]string, 0, end-start)
+	for _, word := range words[start:end] {
+		escaped := html.EscapeString(word)
+		if matched(word) {
+			escaped = highlightStart + escaped + highlightStop
+		}
+		snippet = append(snippet, escaped)
+	}
+	return strings.Join(snippet, " ")
+}
@@ -1,13 +1,190 @@
 package storage
 
 import (
+	"bytes"
 	"context"
 	"graphql_project/internal/graph/model"
+	"time"
+
+	"github.com/google/uuid"
 )
 
+// AutoLockReason — причина закрытия комментариев у поста, старше срока из WithAutoLock.
+const AutoLockReason = "comments are closed automatically"
+
 type Storage interface {
+	CreateUser(ctx context.Context, newUser model.NewUser) (*model.User, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (*model.User, error)
+	GetUserByHandle(ctx context.Context, handle string) (*model.User, error)
+	GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]*model.User, error)
 	CreatePost(ctx context.Context, newPost model.NewPost) (*model.Post, error)
 	GetAllPosts(ctx context.Context, offset *int, limit *int) ([]*model.Post, error)
+	GetPostsPage(ctx context.Context, page model.PageArgs) (*model.PostPage, error)
 	GetPostByID(ctx context.Context, id string) (*model.Post, error)
+	UpdatePost(ctx context.Context, id uuid.UUID, input model.UpdatePost) (*model.Post, error)
+	GetPostRevisions(ctx context.Context, postID uuid.UUID) ([]*model.PostRevision, error)
+	GetPostFilterResults(ctx context.Context, postID uuid.UUID) ([]*model.FilterResult, error)
+	SetCommentable(ctx context.Context, postID uuid.UUID, value bool, reason *string) (*model.Post, error)
+	DeletePost(ctx context.Context, id uuid.UUID) error
+	VotePost(ctx context.Context, postID uuid.UUID, userID uuid.UUID, value int) (*model.Post, error)
 	CreateComment(ctx context.Context, newComment model.NewComment) (*model.Comment, error)
+	GetCommentByID(ctx context.Context, id uuid.UUID) (*model.Comment, error)
+	EditComment(ctx context.Context, id uuid.UUID, content string, results []*model.FilterResult) (*model.Comment, error)
+	GetCommentHistory(ctx context.Context, commentID uuid.UUID) ([]*model.CommentRevision, error)
+	GetCommentFilterResults(ctx context.Context, commentID uuid.UUID) ([]*model.FilterResult, error)
+	DeleteComment(ctx context.Context, id uuid.UUID) error
+	VoteComment(ctx context.Context, commentID uuid.UUID, userID uuid.UUID, value int) (*model.Comment, error)
+	GetPostsByIDs(ctx context.Context, ids []uuid.UUID) ([]*model.Post, error)
+	GetCommentsByPostIDs(ctx context.Context, postIDs []uuid.UUID, page model.PageArgs) (map[uuid.UUID]*model.CommentPage, error)
+	GetRepliesByCommentIDs(ctx context.Context, commentIDs []uuid.UUID, page model.PageArgs) (map[uuid.UUID]*model.CommentPage, error)
+	Search(ctx context.Context, query string, limit, offset int) (*model.SearchPage, error)
+	SetCommentHidden(ctx context.Context, id uuid.UUID, hidden bool, moderatorID uuid.UUID, reason *string) (*model.Comment, error)
+	SetUserBanned(ctx context.Context, userID uuid.UUID, banned bool, moderatorID uuid.UUID, reason *string) (*model.User, error)
+	GetModerationLog(ctx context.Context, page model.PageArgs) (*model.ModerationLogPage, error)
+	CreateReport(ctx context.Context, newReport model.NewReport) (*model.Report, error)
+	GetReportQueue(ctx context.Context, limit, offset int) ([]*model.ReportQueueItem, error)
+	ResolveReports(ctx context.Context, targetType model.ReportTargetType, targetID uuid.UUID, moderatorID uuid.UUID, resolution model.ReportAction) ([]*model.Report, error)
+	ApproveComment(ctx context.Context, id uuid.UUID, moderatorID uuid.UUID) (*model.Comment, error)
+	GetPendingComments(ctx context.Context, postID *uuid.UUID, page model.PageArgs) (*model.CommentPage, error)
+	GetPostComments(ctx context.Context, postID uuid.UUID, page model.PageArgs) (*model.CommentPage, error)
+	GetCommentAncestors(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error)
+}
+
+// Option настраивает хранилище при создании.
+type Option func(*options)
+
+type options struct {
+	autoLock time.Duration
+}
+
+// WithAutoLock закрывает комментарии у постов через after после создания; 0 отключает автозакрытие.
+func WithAutoLock(after time.Duration) Option {
+	return func(o *options) {
+		o.autoLock = after
+	}
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// withAutoLock возвращает копию поста, закрытую для комментариев, если срок автозакрытия
+// истёк, иначе сам пост. Пост, явно открытый через SetCommentable, не закрывается.
+func (o options) withAutoLock(post *model.Post) *model.Post {
+	if o.autoLock <= 0 || !post.Commentable || post.UnlockedAt != nil {
+		return post
+	}
+	lockedAt := post.CreatedAt.Add(o.autoLock)
+	if time.Now().Before(lockedAt) {
+		return post
+	}
+
+	reason := AutoLockReason
+	locked := *post
+	locked.Commentable = false
+	locked.LockedAt, locked.LockReason = &lockedAt, &reason
+	return &locked
+}
+
+// voteDelta возвращает изменение счётчиков голосов «за» и «против» при замене голоса
+// prev на value; 0 означает отсутствие голоса.
+func voteDelta(prev, value int) (up, down int32) {
+	count := func(v, want int) int32 {
+		if v == want {
+			return 1
+		}
+		return 0
+	}
+	return count(value, model.VoteUp) - count(prev, model.VoteUp),
+		count(value, model.VoteDown) - count(prev, model.VoteDown)
+}
+
+// hideAction и banAction возвращают тип записи журнала модерации для нового состояния.
+func hideAction(hidden bool) model.ModerationActionType {
+	if hidden {
+		return model.ModerationActionTypeHideComment
+	}
+	return model.ModerationActionTypeRestoreComment
+}
+
+func banAction(banned bool) model.ModerationActionType {
+	if banned {
+		return model.ModerationActionTypeBanAuthor
+	}
+	return model.ModerationActionTypeUnbanAuthor
+}
+
+// logPage задаёт порядок обхода журнала модерации: от новых записей к старым.
+func logPage(page model.PageArgs) model.PageArgs {
+	page.Sort = model.CommentSortNew
+	return page
+}
+
+// compareQueueItems задаёт порядок очереди жалоб: по убыванию числа жалоб, затем по
+// убыванию времени последней жалобы, затем по ID объекта.
+func compareQueueItems(a, b *model.ReportQueueItem) int {
+	if a.ReportCount != b.ReportCount {
+		return int(b.ReportCount - a.ReportCount)
+	}
+	if cmp := b.LastReportedAt.Compare(a.LastReportedAt); cmp != 0 {
+		return cmp
+	}
+	return bytes.Compare(a.TargetID[:], b.TargetID[:])
+}
+
+// displayName возвращает отображаемое имя нового пользователя; по умолчанию это его handle.
+func displayName(newUser model.NewUser) string {
+	if newUser.DisplayName != nil && *newUser.DisplayName != "" {
+		return *newUser.DisplayName
+	}
+	return newUser.Handle
+}
+
+// moderationMode возвращает режим модерации нового поста; по умолчанию премодерации нет.
+func moderationMode(newPost model.NewPost) model.ModerationMode {
+	if newPost.ModerationMode == "" {
+		return model.ModerationModeNone
+	}
+	return newPost.ModerationMode
+}
+
+// commentStatus возвращает статус нового комментария к посту с режимом модерации mode.
+func commentStatus(mode model.ModerationMode) model.CommentStatus {
+	if mode == model.ModerationModePre {
+		return model.CommentStatusPending
+	}
+	return model.CommentStatusPublished
+}
+
+// applyPostUpdate применяет к посту поля input. Commentable открывает или закрывает пост так
+// же, как SetCommentable, но только если меняет видимое состояние с учётом автозакрытия:
+// повторная отправка того же значения не сбрасывает время и причину закрытия.
+func (o options) applyPostUpdate(post *model.Post, input model.UpdatePost, now time.Time) {
+	if input.Title != nil {
+		post.Title = *input.Title
+	}
+	if input.Content != nil {
+		post.Content = *input.Content
+	}
+	if input.Commentable != nil && *input.Commentable != o.withAutoLock(post).Commentable {
+		setCommentable(post, *input.Commentable, nil, now)
+	}
+	if input.ModerationMode != nil {
+		post.ModerationMode = *input.ModerationMode
+	}
+}
+
+// setCommentable открывает или закрывает пост. Закрытие запоминает время и причину,
+// открытие их сбрасывает и отменяет автозакрытие поста.
+func setCommentable(post *model.Post, value bool, reason *string, now time.Time) {
+	post.Commentable = value
+	if value {
+		post.LockedAt, post.LockReason, post.UnlockedAt = nil, nil, &now
+	} else {
+		post.LockedAt, post.LockReason, post.UnlockedAt = &now, reason, nil
+	}
 }
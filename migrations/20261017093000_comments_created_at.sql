@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE comments ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX idx_comments_post_root ON comments(post_id, created_at, id) WHERE parent_comment_id IS NULL;
+CREATE INDEX idx_comments_parent_created ON comments(parent_comment_id, created_at, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_comments_parent_created;
+DROP INDEX idx_comments_post_root;
+ALTER TABLE comments DROP COLUMN created_at;
+-- +goose StatementEnd
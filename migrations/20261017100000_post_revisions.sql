@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE posts ADD COLUMN updated_at TIMESTAMPTZ;
+
+CREATE TABLE post_revisions (
+    post_id UUID NOT NULL REFERENCES posts(id),
+    version INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    commentable BOOLEAN NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (post_id, version)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE post_revisions;
+ALTER TABLE posts DROP COLUMN updated_at;
+-- +goose StatementEnd
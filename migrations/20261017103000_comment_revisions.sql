@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE comments ADD COLUMN edited_at TIMESTAMPTZ;
+
+CREATE TABLE comment_revisions (
+    comment_id UUID NOT NULL REFERENCES comments(id),
+    version INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (comment_id, version)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE comment_revisions;
+ALTER TABLE comments DROP COLUMN edited_at;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE posts ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE comments ADD COLUMN deleted_at TIMESTAMPTZ;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE comments DROP COLUMN deleted_at;
+ALTER TABLE posts DROP COLUMN deleted_at;
+-- +goose StatementEnd
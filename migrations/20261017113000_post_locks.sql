@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE posts ADD COLUMN locked_at TIMESTAMPTZ;
+ALTER TABLE posts ADD COLUMN lock_reason TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE posts DROP COLUMN lock_reason;
+ALTER TABLE posts DROP COLUMN locked_at;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE posts ADD COLUMN upvotes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE posts ADD COLUMN downvotes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN upvotes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN downvotes INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE votes (
+    post_id UUID REFERENCES posts(id),
+    comment_id UUID REFERENCES comments(id),
+    author TEXT NOT NULL,
+    value SMALLINT NOT NULL CHECK (value IN (-1, 1)),
+    created_at TIMESTAMPTZ NOT NULL,
+    CHECK ((post_id IS NULL) <> (comment_id IS NULL))
+);
+
+CREATE UNIQUE INDEX idx_votes_post_author ON votes(post_id, author) WHERE post_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_votes_comment_author ON votes(comment_id, author) WHERE comment_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE votes;
+ALTER TABLE comments DROP COLUMN downvotes;
+ALTER TABLE comments DROP COLUMN upvotes;
+ALTER TABLE posts DROP COLUMN downvotes;
+ALTER TABLE posts DROP COLUMN upvotes;
+-- +goose StatementEnd
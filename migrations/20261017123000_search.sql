@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE posts ADD COLUMN search tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', title), 'A') || setweight(to_tsvector('simple', content), 'B')
+) STORED;
+ALTER TABLE comments ADD COLUMN search tsvector GENERATED ALWAYS AS (
+    to_tsvector('simple', content)
+) STORED;
+
+CREATE INDEX idx_posts_search ON posts USING GIN (search);
+CREATE INDEX idx_comments_search ON comments USING GIN (search);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_comments_search;
+DROP INDEX idx_posts_search;
+ALTER TABLE comments DROP COLUMN search;
+ALTER TABLE posts DROP COLUMN search;
+-- +goose StatementEnd
@@ -0,0 +1,96 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE users (
+    id UUID PRIMARY KEY,
+    handle TEXT NOT NULL UNIQUE,
+    display_name TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+-- Каждая различная строка автора становится пользователем. Строка сохраняется в
+-- display_name, а handle получается из неё приведением к формату ^[A-Za-z0-9_.-]{1,32}$:
+-- прочие символы заменяются на «_», длина обрезается до 32. Если такой handle уже занят,
+-- к нему добавляется суффикс «_2», «_3» и так далее; раньше появившиеся авторы получают
+-- handle без суффикса.
+CREATE TEMPORARY TABLE author_users (
+    author TEXT PRIMARY KEY,
+    user_id UUID NOT NULL
+) ON COMMIT DROP;
+
+DO $$
+DECLARE
+    a RECORD;
+    base TEXT;
+    candidate TEXT;
+    n INT;
+    uid UUID;
+BEGIN
+    FOR a IN
+        SELECT author, MIN(created_at) AS created_at
+        FROM (
+            SELECT author, created_at FROM posts
+            UNION ALL SELECT author, created_at FROM comments
+            UNION ALL SELECT author, created_at FROM votes
+        ) AS authors
+        GROUP BY author
+        ORDER BY MIN(created_at), author
+    LOOP
+        base := COALESCE(NULLIF(LEFT(regexp_replace(a.author, '[^A-Za-z0-9_.-]', '_', 'g'), 32), ''), 'user');
+        candidate := base;
+        n := 1;
+        WHILE EXISTS (SELECT 1 FROM users WHERE handle = candidate) LOOP
+            n := n + 1;
+            candidate := LEFT(base, 31 - length(n::TEXT)) || '_' || n;
+        END LOOP;
+
+        uid := gen_random_uuid();
+        INSERT INTO users (id, handle, display_name, created_at)
+        VALUES (uid, candidate, LEFT(a.author, 64), a.created_at);
+        INSERT INTO author_users (author, user_id) VALUES (a.author, uid);
+    END LOOP;
+END $$;
+
+ALTER TABLE posts ADD COLUMN author_id UUID REFERENCES users(id);
+UPDATE posts SET author_id = author_users.user_id FROM author_users WHERE author_users.author = posts.author;
+ALTER TABLE posts ALTER COLUMN author_id SET NOT NULL;
+ALTER TABLE posts DROP COLUMN author;
+
+ALTER TABLE comments ADD COLUMN author_id UUID REFERENCES users(id);
+UPDATE comments SET author_id = author_users.user_id FROM author_users WHERE author_users.author = comments.author;
+ALTER TABLE comments ALTER COLUMN author_id SET NOT NULL;
+ALTER TABLE comments DROP COLUMN author;
+
+ALTER TABLE votes ADD COLUMN user_id UUID REFERENCES users(id);
+UPDATE votes SET user_id = author_users.user_id FROM author_users WHERE author_users.author = votes.author;
+ALTER TABLE votes ALTER COLUMN user_id SET NOT NULL;
+DROP INDEX idx_votes_post_author;
+DROP INDEX idx_votes_comment_author;
+ALTER TABLE votes DROP COLUMN author;
+
+CREATE INDEX idx_posts_author ON posts(author_id);
+CREATE INDEX idx_comments_author ON comments(author_id);
+CREATE UNIQUE INDEX idx_votes_post_user ON votes(post_id, user_id) WHERE post_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_votes_comment_user ON votes(comment_id, user_id) WHERE comment_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE votes ADD COLUMN author TEXT;
+UPDATE votes SET author = users.handle FROM users WHERE users.id = votes.user_id;
+ALTER TABLE votes ALTER COLUMN author SET NOT NULL;
+ALTER TABLE votes DROP COLUMN user_id;
+CREATE UNIQUE INDEX idx_votes_post_author ON votes(post_id, author) WHERE post_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_votes_comment_author ON votes(comment_id, author) WHERE comment_id IS NOT NULL;
+
+ALTER TABLE comments ADD COLUMN author TEXT;
+UPDATE comments SET author = users.handle FROM users WHERE users.id = comments.author_id;
+ALTER TABLE comments ALTER COLUMN author SET NOT NULL;
+ALTER TABLE comments DROP COLUMN author_id;
+
+ALTER TABLE posts ADD COLUMN author TEXT;
+UPDATE posts SET author = users.handle FROM users WHERE users.id = posts.author_id;
+ALTER TABLE posts ALTER COLUMN author SET NOT NULL;
+ALTER TABLE posts DROP COLUMN author_id;
+
+DROP TABLE users;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN banned_at TIMESTAMPTZ;
+ALTER TABLE comments ADD COLUMN hidden_at TIMESTAMPTZ;
+
+CREATE TABLE moderation_log (
+    id UUID PRIMARY KEY,
+    moderator_id UUID NOT NULL REFERENCES users(id),
+    action TEXT NOT NULL,
+    target_id UUID NOT NULL,
+    reason TEXT,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_moderation_log_created_at ON moderation_log(created_at, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE moderation_log;
+ALTER TABLE comments DROP COLUMN hidden_at;
+ALTER TABLE users DROP COLUMN banned_at;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE reports (
+    id UUID PRIMARY KEY,
+    post_id UUID REFERENCES posts(id),
+    comment_id UUID REFERENCES comments(id),
+    reporter_id UUID NOT NULL REFERENCES users(id),
+    reason TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    resolved_at TIMESTAMPTZ,
+    resolver_id UUID REFERENCES users(id),
+    resolution TEXT,
+    CHECK ((post_id IS NULL) <> (comment_id IS NULL))
+);
+
+CREATE UNIQUE INDEX idx_reports_open_post_reporter ON reports(post_id, reporter_id) WHERE post_id IS NOT NULL AND resolved_at IS NULL;
+CREATE UNIQUE INDEX idx_reports_open_comment_reporter ON reports(comment_id, reporter_id) WHERE comment_id IS NOT NULL AND resolved_at IS NULL;
+CREATE INDEX idx_reports_open ON reports(created_at) WHERE resolved_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE reports;
+-- +goose StatementEnd
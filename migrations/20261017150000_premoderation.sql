@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE posts ADD COLUMN moderation_mode TEXT NOT NULL DEFAULT 'NONE';
+ALTER TABLE comments ADD COLUMN status TEXT NOT NULL DEFAULT 'PUBLISHED';
+
+CREATE INDEX idx_comments_pending ON comments(created_at, id) WHERE status = 'PENDING';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_comments_pending;
+ALTER TABLE comments DROP COLUMN status;
+ALTER TABLE posts DROP COLUMN moderation_mode;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE comment_filter_results (
+    comment_id UUID NOT NULL REFERENCES comments(id),
+    position INT NOT NULL,
+    filter TEXT NOT NULL,
+    action TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    PRIMARY KEY (comment_id, position)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE comment_filter_results;
+-- +goose StatementEnd
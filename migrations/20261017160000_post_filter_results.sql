@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE post_filter_results (
+    post_id UUID NOT NULL REFERENCES posts(id),
+    position INT NOT NULL,
+    filter TEXT NOT NULL,
+    action TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    PRIMARY KEY (post_id, position)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE post_filter_results;
+-- +goose StatementEnd